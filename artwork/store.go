@@ -0,0 +1,208 @@
+// Package artwork 按内容 SHA-256 保存封面图片。
+//
+// 文件路径为 <dir>/<hash 前两位>/<hash>，相同内容只保存一份，
+// 不同内容永远不会互相覆盖；图片的 MIME 和尺寸由调用方记录到数据库。
+package artwork
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotImage 数据不是支持的图片格式
+var ErrNotImage = errors.New("unsupported image format")
+
+// supportedMIME 可以保存的图片类型
+var supportedMIME = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Image 图片的内容哈希、类型与尺寸
+type Image struct {
+	Hash   string `json:"hash"`
+	MIME   string `json:"mime"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// Inspect 计算哈希并识别图片类型和尺寸，不支持的格式返回 ErrNotImage
+func Inspect(data []byte) (*Image, error) {
+	if len(data) == 0 {
+		return nil, ErrNotImage
+	}
+	mime := http.DetectContentType(data)
+	if !supportedMIME[mime] {
+		return nil, fmt.Errorf("%w: %s", ErrNotImage, mime)
+	}
+
+	img := &Image{MIME: mime, Size: int64(len(data))}
+	if mime == "image/webp" {
+		w, h, ok := webpSize(data)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid webp header", ErrNotImage)
+		}
+		img.Width, img.Height = w, h
+	} else {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+		}
+		img.Width, img.Height = cfg.Width, cfg.Height
+	}
+
+	sum := sha256.Sum256(data)
+	img.Hash = hex.EncodeToString(sum[:])
+	return img, nil
+}
+
+// webpSize 从 VP8 / VP8L / VP8X 块头读取宽高
+func webpSize(b []byte) (int, int, bool) {
+	if len(b) < 30 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+	le24 := func(p []byte) int { return int(p[0]) | int(p[1])<<8 | int(p[2])<<16 }
+	switch string(b[12:16]) {
+	case "VP8X":
+		return 1 + le24(b[24:27]), 1 + le24(b[27:30]), true
+	case "VP8 ":
+		if b[23] != 0x9d || b[24] != 0x01 || b[25] != 0x2a {
+			return 0, 0, false
+		}
+		return int(binary.LittleEndian.Uint16(b[26:28]) & 0x3fff), int(binary.LittleEndian.Uint16(b[28:30]) & 0x3fff), true
+	case "VP8L":
+		if b[20] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(b[21:25])
+		return 1 + int(bits&0x3fff), 1 + int(bits>>14&0x3fff), true
+	}
+	return 0, 0, false
+}
+
+// Store 以内容哈希命名的图片目录
+type Store struct {
+	dir string
+}
+
+// NewStore 创建图片存储，目录在第一次写入时创建
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir 存储根目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// validHash 只接受 64 位小写十六进制，避免路径穿越
+func validHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+// Path 哈希对应的文件路径
+func (s *Store) Path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// Put 保存图片并返回其信息，内容已存在时不重复写入
+func (s *Store) Put(data []byte) (*Image, error) {
+	img, err := Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+	path := s.Path(img.Hash)
+	if _, err := os.Stat(path); err == nil {
+		return img, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artwork directory: %w", err)
+	}
+	// 先写临时文件再改名，读取方不会看到写了一半的图片
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write artwork: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write artwork: %w", err)
+	}
+	return img, nil
+}
+
+// Read 读取图片内容
+func (s *Store) Read(hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, fmt.Errorf("invalid artwork hash: %s", hash)
+	}
+	return os.ReadFile(s.Path(hash))
+}
+
+// Remove 删除图片，文件不存在时不报错
+func (s *Store) Remove(hash string) error {
+	if !validHash(hash) {
+		return fmt.Errorf("invalid artwork hash: %s", hash)
+	}
+	if err := os.Remove(s.Path(hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// StoredFile 存储目录中的一个图片文件
+type StoredFile struct {
+	Hash    string
+	Size    int64
+	ModTime int64 // Unix 秒
+}
+
+// List 列出存储中的全部图片，根目录中的其他文件 (如旧的 md5 封面) 不在其中
+func (s *Store) List() ([]StoredFile, error) {
+	shards, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []StoredFile
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != 2 {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.dir, shard.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !validHash(e.Name()) || e.Name()[:2] != shard.Name() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, StoredFile{Hash: e.Name(), Size: info.Size(), ModTime: info.ModTime().Unix()})
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,254 @@
+// Package charset 识别被当作 ISO-8859-1 读取的 GBK/Big5/Shift-JIS 标签和歌词文本。
+//
+// 旧的中文、日文软件常把本地编码直接写进 ID3v1 或编码字节为 0 (ISO-8859-1) 的 ID3v2.3 帧，
+// 外部 .lrc 文件也多为 GBK。Detector 对每个候选编码解码后统计常用字的比例，
+// 得分最高者胜出；无法区分时使用配置的回退编码。
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// 编码名称
+const (
+	UTF8     = "utf-8"
+	UTF16    = "utf-16"
+	Latin1   = "iso-8859-1"
+	GBK      = "gbk"
+	GB18030  = "gb18030"
+	Big5     = "big5"
+	ShiftJIS = "shift_jis"
+)
+
+// DefaultCandidates 默认参与检测的编码
+var DefaultCandidates = []string{GBK, Big5, ShiftJIS}
+
+var encodings = map[string]encoding.Encoding{
+	GBK:      simplifiedchinese.GBK,
+	GB18030:  simplifiedchinese.GB18030,
+	Big5:     traditionalchinese.Big5,
+	ShiftJIS: japanese.ShiftJIS,
+}
+
+var aliases = map[string]string{
+	"gb2312":     GBK,
+	"cp936":      GBK,
+	"cp950":      Big5,
+	"big5-hkscs": Big5,
+	"sjis":       ShiftJIS,
+	"shift-jis":  ShiftJIS,
+	"cp932":      ShiftJIS,
+	"latin1":     Latin1,
+	"utf8":       UTF8,
+}
+
+// Normalize 返回编码的规范名称，不支持时 ok 为 false
+func Normalize(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	if _, ok := encodings[name]; ok || name == Latin1 || name == UTF8 {
+		return name, true
+	}
+	return "", false
+}
+
+// Supported 返回可用于候选和回退的编码名称
+func Supported() []string {
+	return []string{GBK, GB18030, Big5, ShiftJIS, Latin1}
+}
+
+// Result 一次检测的结果，Confidence 为候选编码解码后常用字所占的比例
+type Result struct {
+	Text       string  `json:"text"`
+	Encoding   string  `json:"encoding"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Detector 按候选编码检测文本，Fallback 为空表示无法判断时保留 ISO-8859-1
+type Detector struct {
+	candidates []string
+	fallback   string
+}
+
+// NewDetector 创建检测器，candidates 为空时使用 DefaultCandidates
+func NewDetector(candidates []string, fallback string) (*Detector, error) {
+	if len(candidates) == 0 {
+		candidates = DefaultCandidates
+	}
+	d := &Detector{}
+	for _, c := range candidates {
+		name, ok := Normalize(c)
+		if !ok || encodings[name] == nil {
+			return nil, fmt.Errorf("unsupported candidate encoding: %s", c)
+		}
+		d.candidates = append(d.candidates, name)
+	}
+	if fallback != "" {
+		name, ok := Normalize(fallback)
+		if !ok || name == UTF8 {
+			return nil, fmt.Errorf("unsupported fallback encoding: %s", fallback)
+		}
+		d.fallback = name
+	}
+	return d, nil
+}
+
+// Fallback 返回回退编码
+func (d *Detector) Fallback() string {
+	return d.fallback
+}
+
+// DecodeBytes 把任意编码的字节转为 UTF-8 文本，识别 BOM、UTF-8 与候选编码
+func (d *Detector) DecodeBytes(b []byte) Result {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xEF, 0xBB, 0xBF}):
+		return Result{Text: string(b[3:]), Encoding: UTF8, Confidence: 1}
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}), bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		return Result{Text: decodeUTF16(b), Encoding: UTF16, Confidence: 1}
+	case utf8.Valid(b):
+		return Result{Text: string(b), Encoding: UTF8, Confidence: 1}
+	}
+	return d.detectLegacy(b)
+}
+
+// Repair 修复以 ISO-8859-1 解码或未解码的本地编码字符串，返回修复结果以及是否有变化
+func (d *Detector) Repair(s string) (Result, bool) {
+	var raw []byte
+	switch {
+	case !utf8.ValidString(s):
+		// ID3v1 等未经解码直接转为字符串的原始字节
+		raw = []byte(s)
+	case isLatin1Mojibake(s):
+		raw = make([]byte, 0, len(s))
+		for _, r := range s {
+			raw = append(raw, byte(r))
+		}
+	default:
+		return Result{Text: s, Encoding: UTF8, Confidence: 1}, false
+	}
+
+	r := d.detectLegacy(raw)
+	return r, r.Text != s
+}
+
+// RepairString 只返回修复后的文本
+func (d *Detector) RepairString(s string) string {
+	r, _ := d.Repair(s)
+	return r.Text
+}
+
+// detectLegacy 在候选编码中选出常用字比例最高的一个，平局时优先回退编码
+func (d *Detector) detectLegacy(b []byte) Result {
+	best := Result{Encoding: "", Confidence: -1}
+	for _, name := range d.ordered() {
+		text, ok := decodeStrict(encodings[name], b)
+		if !ok {
+			continue
+		}
+		score := commonRatio(text)
+		if score > best.Confidence {
+			best = Result{Text: text, Encoding: name, Confidence: score}
+		}
+	}
+
+	// 没有候选命中常用字时，孤立的重音字母更像真正的 ISO-8859-1 文本 (如 Motörhead)
+	if best.Encoding == "" || (best.Confidence == 0 && (d.fallback == "" || d.fallback == Latin1 || latin1Plausible(b))) {
+		return Result{Text: decodeLatin1(b), Encoding: Latin1}
+	}
+	return best
+}
+
+// ordered 回退编码排在最前，得分相同时优先使用
+func (d *Detector) ordered() []string {
+	if d.fallback == "" || encodings[d.fallback] == nil {
+		return d.candidates
+	}
+	list := []string{d.fallback}
+	for _, c := range d.candidates {
+		if c != d.fallback {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// decodeStrict 解码失败 (出现替换字符或私用区字符) 时 ok 为 false
+func decodeStrict(enc encoding.Encoding, b []byte) (string, bool) {
+	out, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return "", false
+	}
+	for _, r := range string(out) {
+		if r == utf8.RuneError || (r >= 0xE000 && r <= 0xF8FF) || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			return "", false
+		}
+	}
+	return string(out), true
+}
+
+// isLatin1Mojibake 所有字符都在 U+00FF 以内且含有非 ASCII 字符
+func isLatin1Mojibake(s string) bool {
+	high := false
+	for _, r := range s {
+		if r > 0xFF {
+			return false
+		}
+		if r >= 0x80 {
+			high = true
+		}
+	}
+	return high
+}
+
+// latin1Plausible 非 ASCII 字节大多单独出现在 ASCII 之间，符合西文重音字母的分布；
+// 双字节编码的汉字和假名则总是成对出现
+func latin1Plausible(b []byte) bool {
+	high, paired := 0, 0
+	for i, c := range b {
+		if c < 0x80 {
+			continue
+		}
+		if c < 0xA0 {
+			return false // C1 控制字符
+		}
+		high++
+		if (i > 0 && b[i-1] >= 0x80) || (i+1 < len(b) && b[i+1] >= 0x80) {
+			paired++
+		}
+	}
+	return high > 0 && paired*2 < high
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func decodeUTF16(b []byte) string {
+	bigEndian := b[0] == 0xFE
+	b = b[2:]
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		} else {
+			units = append(units, uint16(b[i+1])<<8|uint16(b[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,163 @@
+package charset
+
+import "testing"
+
+// encode 把 UTF-8 文本编码为指定的本地编码字节
+func encode(t *testing.T, name, s string) []byte {
+	t.Helper()
+	b, err := encodings[name].NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encode %q: %v", s, err)
+	}
+	return b
+}
+
+// asLatin1 模拟把原始字节当作 ISO-8859-1 读取得到的字符串
+func asLatin1(b []byte) string {
+	return decodeLatin1(b)
+}
+
+func newDetector(t *testing.T, fallback string) *Detector {
+	t.Helper()
+	d, err := NewDetector(nil, fallback)
+	if err != nil {
+		t.Fatalf("NewDetector: %v", err)
+	}
+	return d
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"GBK", GBK, true},
+		{" gb2312 ", GBK, true},
+		{"CP936", GBK, true},
+		{"gb18030", GB18030, true},
+		{"Big5-HKSCS", Big5, true},
+		{"SJIS", ShiftJIS, true},
+		{"cp932", ShiftJIS, true},
+		{"latin1", Latin1, true},
+		{"UTF8", UTF8, true},
+		{"euc-kr", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Normalize(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewDetectorRejectsUnsupported(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		fallback   string
+	}{
+		{"unknown candidate", []string{"euc-kr"}, ""},
+		{"latin1 candidate", []string{Latin1}, ""},
+		{"utf-8 fallback", nil, UTF8},
+		{"unknown fallback", nil, "koi8-r"},
+	}
+	for _, tt := range tests {
+		if _, err := NewDetector(tt.candidates, tt.fallback); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	d, err := NewDetector([]string{"gb2312", "sjis"}, "cp950")
+	if err != nil {
+		t.Fatalf("NewDetector with aliases: %v", err)
+	}
+	if d.Fallback() != Big5 {
+		t.Errorf("Fallback = %q, want %q", d.Fallback(), Big5)
+	}
+	if got := d.ordered(); len(got) != 3 || got[0] != Big5 {
+		t.Errorf("ordered = %v, want fallback first", got)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	d := newDetector(t, "")
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		encoding string
+		changed  bool
+	}{
+		{"gbk mojibake", asLatin1(encode(t, GBK, "周杰伦 - 晴天")), "周杰伦 - 晴天", GBK, true},
+		{"gbk raw bytes", string(encode(t, GBK, "我们的歌")), "我们的歌", GBK, true},
+		{"big5 mojibake", asLatin1(encode(t, Big5, "周杰倫 - 晴天")), "周杰倫 - 晴天", Big5, true},
+		{"shift-jis mojibake", asLatin1(encode(t, ShiftJIS, "さくら 桜の歌")), "さくら 桜の歌", ShiftJIS, true},
+		{"real latin1", "Motörhead", "Motörhead", Latin1, false},
+		{"ascii", "Hello World", "Hello World", UTF8, false},
+		{"already utf-8", "晴天", "晴天", UTF8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, changed := d.Repair(tt.input)
+			if r.Text != tt.want || r.Encoding != tt.encoding || changed != tt.changed {
+				t.Errorf("Repair = %+v, changed %v; want %q (%s), changed %v", r, changed, tt.want, tt.encoding, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRepairFallback(t *testing.T) {
+	// 没有常用字时，GBK 回退优先于把成对的高位字节当作 ISO-8859-1
+	raw := asLatin1(encode(t, GBK, "龘"))
+	if got := newDetector(t, "").RepairString(raw); got != raw {
+		t.Errorf("without fallback got %q, want latin1 text kept", got)
+	}
+	if got := newDetector(t, GBK).RepairString(raw); got != "龘" {
+		t.Errorf("with gbk fallback got %q, want 龘", got)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	d := newDetector(t, "")
+	tests := []struct {
+		name     string
+		input    []byte
+		want     string
+		encoding string
+	}{
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "晴天"...), "晴天", UTF8},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 0x74, 0x66, 0x29, 0x59}, "晴天", UTF16},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0x66, 0x74, 0x59, 0x29}, "晴天", UTF16},
+		{"plain utf-8", []byte("[00:01.00]晴天"), "[00:01.00]晴天", UTF8},
+		{"gbk lyrics", encode(t, GBK, "[00:01.00]故事的小黄花"), "[00:01.00]故事的小黄花", GBK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := d.DecodeBytes(tt.input)
+			if r.Text != tt.want || r.Encoding != tt.encoding {
+				t.Errorf("DecodeBytes = %+v, want %q (%s)", r, tt.want, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestCommonRatio(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"我们", 1},
+		{"さくら", 1},
+		{"我龘", 0.5},
+		{"ｱｲ", 0},
+	}
+	for _, tt := range tests {
+		if got := commonRatio(tt.in); got != tt.want {
+			t.Errorf("commonRatio(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
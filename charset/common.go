@@ -0,0 +1,77 @@
+package charset
+
+import (
+	"go-music-tag/normalize"
+	"unicode"
+)
+
+// commonHan 现代汉语常用字 (按字频) 以及歌名、歌词中常见的字，繁体形式在 init 中补充。
+// 错误的编码解出的字大多是生僻字，所以常用字比例可以区分 GBK、Big5 与 Shift-JIS
+const commonHan = "" +
+	"的一是不了人我在有他这中大来上国个到说们为子和你地出道也时年得就那要下以生会自着去之过家学对可她里后" +
+	"小么心多天而能好都然没日于起还发成事只作当想看文无开手十用主行方又如前所本见经头面公同三已老从动两长" +
+	"知民样现分将外但身些与高意进把法此实回二理美点月明其种声全工己话儿者向情部正名定女问力机给等几很业最" +
+	"间新什打便位因重被走电四第门相次东政海口使教西再平真听世气信北少关并内加化由却代军产入先山五太水万市" +
+	"眼体别处总才场师书比住员九笑性通目华报立马命张活难神数件安表原车白应路期叫死常提感金何更反合放做系计" +
+	"或司利受光王果亲界及今京务制解各任至清物台象记边共风战干接它许八特觉望直服毛林题建南度统色字请交爱让" +
+	"认算论百吃义科怎元社术结六功指思非流每青管夫连远资队跟带花快条院变联言权往展该领传近留红治决周保达办" +
+	"运武半候七必城父强步完革深区即求品士转量空甚众技轻程告江语英基派满式李息写呢识极令黄德收脸钱党倒未持" +
+	"取设始版双历越史商千片容研像找友孩站广改议形委早房音火际则首单据导影失拿网香似斯专石若兵弟谁校读志飞" +
+	"观争究包组造落视济喜离虽坐集编宝谈府拉黑且随格尽剑讲布杀微怕母调局根曾准团段终乐切级克精哪官示冷域孔" +
+	"夜梦雨雪春秋冬夏星云月阳歌曲唱舞恋想念泪伤痛忘记回忆等待永远温柔寂寞孤单快乐幸福自由青春故乡梦想天空" +
+	"花开落叶海洋河流城市街灯雨夜晚安你好再见朋友爱人情人妈妈爸爸宝贝姑娘少年男孩女孩时光岁月未来过去今天" +
+	"明天昨天一生一世唯一最后第一约定承诺勇敢坚强流浪远方家园月亮太阳星星彩虹烟火晴天阴天微笑眼泪拥抱思念" +
+	"怀旧演奏版现场纯音伴奏原唱翻混音专辑精选集合辑单曲主题插尾片序号红颜蓝白紫灰粉绿橙银铃钟琴笛鼓弦吉他" +
+	"啊吧呀哦嗯哈啦嘛呵喔耶呜噢吗呢么着过给跟被让把从向对于关于因为所以但是如果虽然而且或者还是不过只要只有" +
+	"心中身边手里眼里梦里怀里世界宇宙地球人间天堂地狱魔鬼天使王子公主英雄传说神话奇迹秘密答案问题理由方向" +
+	"醉酒茶饭菜鱼鸟狗猫马龙凤虎狼熊猴兔鹿蝶蜂燕鹰鸽鹤雀羊牛猪鸡鸭鹅蛇蛙龟虾蟹贝珠玉金银铜铁钢石沙土尘泥火" +
+	"灰烟雾霜露冰冻寒冷暖热凉温湿干燥晴阴风暴雷电闪光影色彩声音味香甜苦辣酸咸淡浓轻重快慢高低长短大小多少"
+
+// commonKanji 日文歌名中常见、但不在上表中的汉字
+const commonKanji = "桜恋夢涙君僕私歌声空風雪花星光翼扉扉駅街約束奇跡永遠物語未来明日昨日今日約束絆瞳唄煌"
+
+var commonSet = make(map[rune]bool)
+
+func init() {
+	for _, list := range []string{commonHan, normalize.ToTraditional(commonHan), commonKanji, "後裡裏們麼於並範臺隻"} {
+		for _, r := range list {
+			commonSet[r] = true
+		}
+	}
+}
+
+// isCommon 常用汉字、假名、中日文标点与全角 ASCII 视为常用字符；
+// 半角片假名 (U+FF61-FF9F) 常由 GBK 字节误读为 Shift-JIS 产生，不计入
+func isCommon(r rune) bool {
+	switch {
+	case commonSet[r]:
+		return true
+	case r >= 0x3040 && r <= 0x30FF: // 平假名、片假名
+		return true
+	case r >= 0x3000 && r <= 0x303F: // 中日文标点
+		return true
+	case r >= 0xFF01 && r <= 0xFF5E: // 全角 ASCII
+		return true
+	case r == 0x00B7 || r == 0x2014 || r == 0x2026 || r == 0x2018 || r == 0x2019 || r == 0x201C || r == 0x201D:
+		return true
+	}
+	return false
+}
+
+// commonRatio 非 ASCII 字符中常用字符所占的比例，没有非 ASCII 字符时返回 0
+func commonRatio(s string) float64 {
+	total, common := 0, 0
+	for _, r := range s {
+		if r < 0x80 || unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if isCommon(r) {
+			common++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(common) / float64(total)
+}
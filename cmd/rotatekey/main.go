@@ -0,0 +1,81 @@
+// rotatekey 生成新的主密钥，并用它重新加密数据库中保存的所有密码。
+//
+// 用法:
+//
+//	rotatekey                        # 生成新密钥并写入配置的 key_file
+//	rotatekey -new-key <base64>      # 使用指定的新密钥
+//	rotatekey -key-file /path/new.key
+//
+// 如果当前密钥来自环境变量 MUSIC_TAG_SECRET_KEY，轮换后需要手动更新该变量。
+package main
+
+import (
+	"flag"
+	"go-music-tag/config"
+	"go-music-tag/database"
+	"go-music-tag/secret"
+	"log"
+	"os"
+)
+
+func main() {
+	newKeyText := flag.String("new-key", "", "base64 encoded new key (generated when empty)")
+	keyFile := flag.String("key-file", "", "where to write the new key (defaults to security.key_file)")
+	flag.Parse()
+
+	cfg := config.GetConfig()
+
+	if err := secret.Init(cfg.Security.KeyFile); err != nil {
+		log.Fatalf("Failed to load current key: %v", err)
+	}
+	oldCipher, err := secret.Default()
+	if err != nil {
+		log.Fatalf("Failed to load current key: %v", err)
+	}
+
+	if err := database.Init(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	var newKey []byte
+	if *newKeyText != "" {
+		newKey, err = secret.DecodeKey(*newKeyText)
+	} else {
+		newKey, err = secret.GenerateKey()
+	}
+	if err != nil {
+		log.Fatalf("Invalid new key: %v", err)
+	}
+
+	newCipher, err := secret.NewCipher(newKey)
+	if err != nil {
+		log.Fatalf("Invalid new key: %v", err)
+	}
+
+	target := *keyFile
+	if target == "" {
+		target = cfg.Security.KeyFile
+	}
+
+	// 先备份旧密钥文件，避免重新加密成功但密钥写入失败后无法解密
+	if data, err := os.ReadFile(target); err == nil {
+		if err := os.WriteFile(target+".bak", data, 0600); err != nil {
+			log.Fatalf("Failed to back up key file: %v", err)
+		}
+	}
+
+	count, err := database.ReencryptSecrets(oldCipher, newCipher)
+	if err != nil {
+		log.Fatalf("Failed to re-encrypt secrets: %v", err)
+	}
+
+	if err := secret.WriteKeyFile(target, newKey); err != nil {
+		log.Fatalf("Secrets were re-encrypted but the new key could not be written: %v\nNew key: %s",
+			err, secret.EncodeKey(newKey))
+	}
+
+	log.Printf("Re-encrypted %d secret(s), new key written to %s", count, target)
+	if os.Getenv(secret.KeyEnv) != "" {
+		log.Printf("%s is set: update it to the new key before restarting the server", secret.KeyEnv)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"go-music-tag/config"
 	"go-music-tag/database"
 	"go-music-tag/routes"
+	"go-music-tag/secret"
 	"log"
 	"net/http"
 	"time"
@@ -13,6 +14,10 @@ import (
 func main() {
 	cfg := config.GetConfig()
 
+	if err := secret.Init(cfg.Security.KeyFile); err != nil {
+		log.Fatalf("Failed to load secret key: %v", err)
+	}
+
 	if err := database.Init(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
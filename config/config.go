@@ -2,16 +2,27 @@ package config
 
 import (
 	"fmt"
+	"go-music-tag/normalize"
 	"sync"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	WebDAV   WebDAVConfig   `mapstructure:"webdav"`
-	Scan     ScanConfig     `mapstructure:"scan"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	WebDAV      WebDAVConfig      `mapstructure:"webdav"`
+	Scan        ScanConfig        `mapstructure:"scan"`
+	Security    SecurityConfig    `mapstructure:"security"`
+	Fingerprint FingerprintConfig `mapstructure:"fingerprint"`
+	Duplicates  DuplicatesConfig  `mapstructure:"duplicates"`
+	Rename      RenameConfig      `mapstructure:"rename"`
+	Normalize   normalize.Rules   `mapstructure:"normalize"` // 标签规范化默认规则
+	Health      HealthConfig      `mapstructure:"health"`
+	Loudness    LoudnessConfig    `mapstructure:"loudness"`
+	Charset     CharsetConfig     `mapstructure:"charset"`
+	Fetcher     FetcherConfig     `mapstructure:"fetcher"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
 }
 
 type ServerConfig struct {
@@ -34,6 +45,90 @@ type ScanConfig struct {
 	Extensions []string `mapstructure:"extensions"`
 	BatchSize  int      `mapstructure:"batch_size"`
 	Concurrent int      `mapstructure:"concurrent"`
+	// 从路径提取标签的模式，例如 %artist%/%album% (%year%)/%track%. %title%
+	PathPatterns []string `mapstructure:"path_patterns"`
+}
+
+// SecurityConfig 敏感数据加密配置
+// 主密钥优先读取环境变量 MUSIC_TAG_SECRET_KEY，否则读取 KeyFile（不存在时自动生成）
+type SecurityConfig struct {
+	KeyFile string `mapstructure:"key_file"`
+}
+
+// FingerprintConfig 声学指纹配置
+// 指纹由 Chromaprint 的 fpcalc 命令计算，再通过 AcoustID 查询录音 MBID
+type FingerprintConfig struct {
+	FpcalcPath string         `mapstructure:"fpcalc_path"`
+	Length     int            `mapstructure:"length"` // 参与计算的音频秒数
+	AcoustID   AcoustIDConfig `mapstructure:"acoustid"`
+}
+
+type AcoustIDConfig struct {
+	APIKey   string  `mapstructure:"api_key"`
+	BaseURL  string  `mapstructure:"base_url"` // 测试时可指向本地替身服务
+	MinScore float64 `mapstructure:"min_score"`
+}
+
+// DuplicatesConfig 重复文件处理配置
+type DuplicatesConfig struct {
+	// 隐藏的重复文件移动到 WebDAV 根目录下的该目录，扫描时会跳过
+	QuarantineDir     string `mapstructure:"quarantine_dir"`
+	DurationTolerance int    `mapstructure:"duration_tolerance"` // 按元数据分组时允许的时长差 (秒)
+}
+
+// RenameConfig 按模板整理源文件的配置
+type RenameConfig struct {
+	Template string `mapstructure:"template"` // 例如 {album_artist}/{year} - {album}/{disc}-{track:02} {title}.{ext}
+}
+
+// HealthConfig 音乐库健康报告配置
+type HealthConfig struct {
+	MinBitRate int `mapstructure:"min_bitrate"` // 低于该比特率 (kbps) 的文件视为低音质
+}
+
+// LoudnessConfig 响度分析配置，使用 ffmpeg 的 ebur128 滤镜按 ReplayGain 2.0 (-18 LUFS) 计算增益
+type LoudnessConfig struct {
+	FfmpegPath string `mapstructure:"ffmpeg_path"`
+	WriteTags  bool   `mapstructure:"write_tags"` // 分析后默认写入 REPLAYGAIN_* 标签
+}
+
+// CharsetConfig 标签与歌词的编码检测配置
+// 以 ISO-8859-1 读取的文本会依次尝试 Candidates，无法区分时使用 Fallback
+type CharsetConfig struct {
+	Fallback   string   `mapstructure:"fallback"`   // gbk | big5 | shift_jis | gb18030 | iso-8859-1
+	Candidates []string `mapstructure:"candidates"` // 参与检测的编码
+}
+
+// FetcherConfig 歌词和封面获取配置
+type FetcherConfig struct {
+	DataDir     string                           `mapstructure:"data_dir"` // 歌词缓存 (lyrics/) 和封面库 (covers/) 所在目录
+	Proxy       string                           `mapstructure:"proxy"`    // http/https/socks5 代理，留空时使用 HTTP_PROXY 等环境变量
+	Timeout     int                              `mapstructure:"timeout"`  // 单次请求超时 (秒)
+	UserAgent   string                           `mapstructure:"user_agent"`
+	NotFoundTTL int                              `mapstructure:"not_found_ttl"` // 未找到的内容在多少小时内批量获取时跳过
+	Providers   map[string]FetcherProviderConfig `mapstructure:"providers"`     // netease | itunes | coverartarchive
+}
+
+// FetcherProviderConfig 单个提供方的配置，未设置的项使用内置默认值
+type FetcherProviderConfig struct {
+	Enabled   *bool   `mapstructure:"enabled"`    // 未设置时启用
+	BaseURL   string  `mapstructure:"base_url"`   // 测试时可指向本地替身服务
+	RateLimit float64 `mapstructure:"rate_limit"` // 每秒请求数，小于 0 时不限速
+	Burst     int     `mapstructure:"burst"`
+}
+
+// SchedulerConfig 定时任务配置，启动时同步到数据库
+type SchedulerConfig struct {
+	Schedules []ScheduleConfig `mapstructure:"schedules"`
+}
+
+// ScheduleConfig 单个定时任务。Cron 和 Task 每次启动时以配置为准，
+// Enabled 只在首次创建时使用，之后可以通过接口启用或停用
+type ScheduleConfig struct {
+	Name    string `mapstructure:"name"`
+	Cron    string `mapstructure:"cron"` // 分 时 日 月 周，或 @daily、@weekly 等简写
+	Task    string `mapstructure:"task"` // scan | fetch_lyrics | fetch_covers | fetch_all | artwork_gc
+	Enabled bool   `mapstructure:"enabled"`
 }
 
 var (
@@ -49,9 +144,10 @@ func GetConfig() *Config {
 		viper.AddConfigPath(".")
 		viper.AddConfigPath("./")
 
+		// 默认值在读取配置文件前设置，配置文件中缺失的项同样使用默认值
+		setDefaults()
 		if err := viper.ReadInConfig(); err != nil {
 			fmt.Printf("Warning: config file not found, using defaults: %v\n", err)
-			setDefaults()
 		}
 
 		if err := viper.Unmarshal(cfg); err != nil {
@@ -72,4 +168,19 @@ func setDefaults() {
 	viper.SetDefault("scan.extensions", []string{".mp3"})
 	viper.SetDefault("scan.batch_size", 50)
 	viper.SetDefault("scan.concurrent", 5)
+	viper.SetDefault("security.key_file", "./data/secret.key")
+	viper.SetDefault("fingerprint.fpcalc_path", "fpcalc")
+	viper.SetDefault("fingerprint.length", 120)
+	viper.SetDefault("fingerprint.acoustid.base_url", "https://api.acoustid.org/v2")
+	viper.SetDefault("fingerprint.acoustid.min_score", 0.8)
+	viper.SetDefault("duplicates.quarantine_dir", ".duplicates")
+	viper.SetDefault("duplicates.duration_tolerance", 3)
+	viper.SetDefault("rename.template", "{album_artist}/{year} - {album}/{disc}-{track:02} {title}.{ext}")
+	viper.SetDefault("health.min_bitrate", 192)
+	viper.SetDefault("loudness.ffmpeg_path", "ffmpeg")
+	viper.SetDefault("charset.fallback", "gbk")
+	viper.SetDefault("charset.candidates", []string{"gbk", "big5", "shift_jis"})
+	// 超时、User-Agent 和提供方地址等未设置时由 fetcher.NewFetcher 使用 fetcher.DefaultOptions
+	viper.SetDefault("fetcher.data_dir", "./data")
+	viper.SetDefault("fetcher.not_found_ttl", 168)
 }
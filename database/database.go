@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go-music-tag/config"
 	"go-music-tag/models"
+	"go-music-tag/secret"
 	"log"
 	"os"
 	"path/filepath"
@@ -43,12 +44,30 @@ func Init() error {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	if err := DB.AutoMigrate(&models.Music{}, &models.ScanLog{}, &models.WebDAVConfig{}); err != nil {
+	if err := DB.AutoMigrate(
+		&models.Music{},
+		&models.ScanLog{},
+		&models.WebDAVConfig{},
+		&models.MusicBrainzMatch{},
+		&models.Fingerprint{},
+		&models.RenameOperation{},
+		&models.TagChange{},
+		&models.Lyrics{},
+		&models.Artwork{},
+		&models.AlbumArtwork{},
+		&models.FetchResult{},
+		&models.FetchMiss{},
+		&models.Schedule{},
+	); err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
 	initDefaultWebDAVConfig(cfg)
 
+	if err := encryptPlaintextSecrets(); err != nil {
+		return fmt.Errorf("failed to encrypt stored secrets: %w", err)
+	}
+
 	log.Println("Database initialized successfully")
 	return nil
 }
@@ -61,16 +80,72 @@ func initDefaultWebDAVConfig(cfg *config.Config) {
 		defaultConfig := &models.WebDAVConfig{
 			URL:        cfg.WebDAV.URL,
 			Username:   cfg.WebDAV.Username,
-			Password:   cfg.WebDAV.Password,
 			RootPath:   cfg.WebDAV.RootPath,
 			Enabled:    true,
 			TestStatus: "pending",
 		}
+		if err := defaultConfig.SetPassword(cfg.WebDAV.Password); err != nil {
+			log.Printf("Failed to encrypt default WebDAV password: %v", err)
+			return
+		}
 		DB.Create(defaultConfig)
 		log.Println("Default WebDAV config created")
 	}
 }
 
+// encryptPlaintextSecrets 将旧版本以明文保存的 WebDAV 密码加密
+func encryptPlaintextSecrets() error {
+	var configs []models.WebDAVConfig
+	if err := DB.Find(&configs).Error; err != nil {
+		return err
+	}
+
+	for _, c := range configs {
+		if c.Password == "" || secret.IsEncrypted(c.Password) {
+			continue
+		}
+		if err := c.SetPassword(c.Password); err != nil {
+			return err
+		}
+		if err := DB.Model(&c).Update("password", c.Password).Error; err != nil {
+			return err
+		}
+		log.Printf("Encrypted plaintext WebDAV password (config %d)", c.ID)
+	}
+	return nil
+}
+
+// ReencryptSecrets 使用新密钥重新加密所有已保存的密码，在同一事务中完成
+func ReencryptSecrets(oldCipher, newCipher *secret.Cipher) (int, error) {
+	count := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var configs []models.WebDAVConfig
+		if err := tx.Find(&configs).Error; err != nil {
+			return err
+		}
+
+		for _, c := range configs {
+			if c.Password == "" {
+				continue
+			}
+			plain, err := oldCipher.Decrypt(c.Password)
+			if err != nil {
+				return fmt.Errorf("config %d: %w", c.ID, err)
+			}
+			encrypted, err := newCipher.Encrypt(plain)
+			if err != nil {
+				return fmt.Errorf("config %d: %w", c.ID, err)
+			}
+			if err := tx.Model(&c).Update("password", encrypted).Error; err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
@@ -0,0 +1,221 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-music-tag/artwork"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCoverSize 下载封面的大小上限
+const maxCoverSize = 20 << 20
+
+// ErrInvalidImageURL 图片地址不是 http/https
+var ErrInvalidImageURL = errors.New("invalid image url")
+
+// CoverCandidate 一个可供选择的封面，Width/Height 为下载后实际的分辨率
+type CoverCandidate struct {
+	Source     string  `json:"source"`
+	URL        string  `json:"url"`
+	PreviewURL string  `json:"preview_url"`
+	Album      string  `json:"album,omitempty"`
+	Artist     string  `json:"artist,omitempty"`
+	MIME       string  `json:"mime"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Size       int64   `json:"size"`
+	Hash       string  `json:"hash"`
+	Confidence float64 `json:"confidence"` // 与请求的艺术家/专辑的匹配程度 (0-1)
+}
+
+// DownloadImage 下载图片，只接受 http/https，429/5xx 时重试，非 2xx 响应或超过大小上限时返回错误
+func (f *Fetcher) DownloadImage(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidImageURL, rawURL)
+	}
+	// 图片由各提供方的 CDN 提供，不占用接口限速，也不进入响应缓存
+	resp, err := f.doWithRetry("", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCoverSize {
+		return nil, fmt.Errorf("image larger than %d MB", maxCoverSize>>20)
+	}
+	return data, nil
+}
+
+// SearchCoverCandidates 汇总各来源的封面供用户挑选，按置信度排序，同一图片只保留一次。
+// mbReleaseID 不为空时包含 Cover Art Archive 的正面封面
+func (f *Fetcher) SearchCoverCandidates(artist, album, mbReleaseID string, limit int) ([]CoverCandidate, error) {
+	if limit <= 0 {
+		limit = 8
+	}
+
+	var found []CoverCandidate
+	var errs []string
+	if mbReleaseID != "" && f.Enabled(ProviderCoverArtArchive) {
+		base := f.baseURL(ProviderCoverArtArchive) + "/release/" + url.PathEscape(mbReleaseID)
+		found = append(found, CoverCandidate{
+			Source:     ProviderCoverArtArchive,
+			URL:        base + "/front",
+			PreviewURL: base + "/front-250",
+			Album:      album,
+			Artist:     artist,
+			Confidence: 1, // 按发行 ID 精确对应
+		})
+	}
+	for _, provider := range CoverProviders() {
+		if !f.Enabled(provider) {
+			continue
+		}
+		list, err := f.coverCandidates(provider, artist, album, limit)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		found = append(found, list...)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Confidence > found[j].Confidence })
+
+	// 并发下载以得到实际分辨率，下载失败的候选丢弃
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 4)
+	for i := range found {
+		wg.Add(1)
+		go func(c *CoverCandidate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			data, err := f.DownloadImage(c.URL)
+			if err != nil {
+				log.Printf("[Fetcher] Candidate %s unavailable: %v", c.URL, err)
+				return
+			}
+			img, err := artwork.Inspect(data)
+			if err != nil {
+				return
+			}
+			c.MIME, c.Width, c.Height, c.Size, c.Hash = img.MIME, img.Width, img.Height, img.Size, img.Hash
+		}(&found[i])
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	candidates := make([]CoverCandidate, 0, len(found))
+	for _, c := range found {
+		if c.Hash == "" || seen[c.Hash] {
+			continue
+		}
+		seen[c.Hash] = true
+		candidates = append(candidates, c)
+		if len(candidates) == limit {
+			break
+		}
+	}
+	if len(candidates) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("cover search failed: %s", strings.Join(errs, "; "))
+	}
+	return candidates, nil
+}
+
+// neteaseCoverCandidates 网易云专辑搜索，图片地址可加 param=宽y高 缩放
+func (f *Fetcher) neteaseCoverCandidates(artist, album string, limit int) ([]CoverCandidate, error) {
+	term := strings.TrimSpace(album + " " + artist)
+	if term == "" {
+		return nil, nil
+	}
+	searchURL := fmt.Sprintf("%s/api/search/get?type=10&limit=%d&s=%s", f.baseURL(ProviderNetease), limit, url.QueryEscape(term))
+	body, err := f.getProvider(ProviderNetease, searchURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Result struct {
+			Albums []struct {
+				Name   string `json:"name"`
+				PicURL string `json:"picUrl"`
+				Artist struct {
+					Name string `json:"name"`
+				} `json:"artist"`
+			} `json:"albums"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("netease: %w", err)
+	}
+
+	var list []CoverCandidate
+	for _, a := range result.Result.Albums {
+		if a.PicURL == "" || strings.Contains(a.PicURL, "default_album") {
+			continue
+		}
+		pic := a.PicURL
+		if strings.HasPrefix(f.baseURL(ProviderNetease), "https://") {
+			pic = strings.Replace(pic, "http://", "https://", 1)
+		}
+		list = append(list, CoverCandidate{
+			Source:     ProviderNetease,
+			URL:        pic,
+			PreviewURL: pic + "?param=250y250",
+			Album:      a.Name,
+			Artist:     a.Artist.Name,
+		})
+	}
+	return list, nil
+}
+
+// itunesCoverCandidates iTunes 专辑搜索，artworkUrl100 替换尺寸即可得到大图
+func (f *Fetcher) itunesCoverCandidates(artist, album string, limit int) ([]CoverCandidate, error) {
+	term := strings.TrimSpace(album + " " + artist)
+	if term == "" {
+		return nil, nil
+	}
+	apiURL := fmt.Sprintf("%s/search?term=%s&media=music&entity=album&limit=%d", f.baseURL(ProviderITunes), url.QueryEscape(term), limit)
+	body, err := f.getProvider(ProviderITunes, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Results []struct {
+			CollectionName string `json:"collectionName"`
+			ArtistName     string `json:"artistName"`
+			ArtworkURL100  string `json:"artworkUrl100"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("itunes: %w", err)
+	}
+
+	var list []CoverCandidate
+	for _, r := range result.Results {
+		if r.ArtworkURL100 == "" {
+			continue
+		}
+		list = append(list, CoverCandidate{
+			Source:     ProviderITunes,
+			URL:        strings.Replace(r.ArtworkURL100, "100x100bb", "1200x1200bb", 1),
+			PreviewURL: strings.Replace(r.ArtworkURL100, "100x100bb", "250x250bb", 1),
+			Album:      r.CollectionName,
+			Artist:     r.ArtistName,
+		})
+	}
+	return list, nil
+}
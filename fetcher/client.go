@@ -0,0 +1,196 @@
+package fetcher
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 重试与缓存参数
+const (
+	maxRetries       = 3
+	retryBaseDelay   = 500 * time.Millisecond
+	retryMaxDelay    = 10 * time.Second
+	responseCacheTTL = 10 * time.Minute
+	responseCacheMax = 500
+	maxResponseSize  = 5 << 20
+)
+
+// tokenBucket 令牌桶限速：每秒补充 rate 个令牌，最多积累 burst 个
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait 阻塞直到取得一个令牌
+func (b *tokenBucket) Wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		time.Sleep(wait)
+		b.tokens = 1
+		b.last = time.Now()
+	}
+	b.tokens--
+}
+
+// 各提供方的限速在所有 Fetcher 之间共享，批量任务和单曲请求一起计算；
+// 配置的速率改变时才重新创建令牌桶
+var (
+	limiterMutex sync.Mutex
+	limiters     = make(map[string]*tokenBucket)
+)
+
+// waitForProvider 按提供方配置的速率等待，图片下载等不属于提供方接口的请求不限速
+func (f *Fetcher) waitForProvider(provider string) {
+	p, ok := f.opts.Providers[provider]
+	if !ok || p.RateLimit <= 0 {
+		return
+	}
+	burst := max(p.Burst, 1)
+
+	limiterMutex.Lock()
+	b := limiters[provider]
+	if b == nil || b.rate != p.RateLimit || b.burst != float64(burst) {
+		b = newTokenBucket(p.RateLimit, burst)
+		limiters[provider] = b
+	}
+	limiterMutex.Unlock()
+	b.Wait()
+}
+
+type cachedResponse struct {
+	body    []byte
+	expires time.Time
+}
+
+// 提供方接口的响应缓存，按 URL 保存成功的响应体
+var (
+	responseMutex sync.Mutex
+	responses     = make(map[string]cachedResponse)
+)
+
+func cachedBody(rawURL string) ([]byte, bool) {
+	responseMutex.Lock()
+	defer responseMutex.Unlock()
+	r, ok := responses[rawURL]
+	if !ok || time.Now().After(r.expires) {
+		return nil, false
+	}
+	return r.body, true
+}
+
+func cacheBody(rawURL string, body []byte) {
+	responseMutex.Lock()
+	defer responseMutex.Unlock()
+	now := time.Now()
+	if len(responses) >= responseCacheMax {
+		// 先清理过期项，仍然已满时丢弃最早过期的一项
+		var oldest string
+		for k, r := range responses {
+			if now.After(r.expires) {
+				delete(responses, k)
+			} else if oldest == "" || r.expires.Before(responses[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(responses) >= responseCacheMax {
+			delete(responses, oldest)
+		}
+	}
+	responses[rawURL] = cachedResponse{body: body, expires: now.Add(responseCacheTTL)}
+}
+
+// ClearResponseCache 清空响应缓存
+func ClearResponseCache() {
+	responseMutex.Lock()
+	defer responseMutex.Unlock()
+	responses = make(map[string]cachedResponse)
+}
+
+// retryable 429 和 5xx 可以重试
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryDelay 指数退避，服务端给出 Retry-After 秒数时以其为准
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, retryMaxDelay)
+		}
+	}
+	return min(retryBaseDelay<<attempt, retryMaxDelay)
+}
+
+// getProvider 经过限速、重试和响应缓存向提供方发送 GET 请求，返回响应体
+func (f *Fetcher) getProvider(provider, rawURL string) ([]byte, error) {
+	if body, ok := cachedBody(rawURL); ok {
+		return body, nil
+	}
+	resp, err := f.doWithRetry(provider, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: HTTP %d", provider, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	cacheBody(rawURL, body)
+	return body, nil
+}
+
+// doWithRetry 发送 GET 请求，网络错误、429 和 5xx 时按指数退避重试，
+// 返回最后一次的响应，由调用方检查状态码并关闭响应体
+func (f *Fetcher) doWithRetry(provider, rawURL string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		f.waitForProvider(provider)
+
+		req, err := http.NewRequest("GET", rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", f.opts.UserAgent)
+
+		resp, err := f.client.Do(req)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt == maxRetries {
+			return resp, err
+		}
+
+		wait := retryDelay(attempt, resp)
+		name := provider
+		if name == "" {
+			name = req.URL.Host
+		}
+		if err != nil {
+			log.Printf("[Fetcher] %s request failed (%v), retrying in %v", name, err, wait)
+		} else {
+			log.Printf("[Fetcher] %s returned HTTP %d, retrying in %v", name, resp.StatusCode, wait)
+			resp.Body.Close()
+		}
+		time.Sleep(wait)
+	}
+}
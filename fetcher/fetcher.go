@@ -4,13 +4,15 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"go-music-tag/artwork"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -19,32 +21,101 @@ type Fetcher struct {
 	client    *http.Client
 	lyricsDir string
 	coversDir string
+	opts      Options
 }
 
 type LyricResult struct {
-	Content string `json:"content"`
-	Source  string `json:"source"`
+	Content      string  `json:"content"`
+	Translation  string  `json:"translation,omitempty"`  // 翻译歌词 (LRC)，时间轴与原文对应
+	Romanization string  `json:"romanization,omitempty"` // 罗马音歌词 (LRC)
+	Source       string  `json:"source"`
+	Confidence   float64 `json:"confidence"` // 与请求的艺术家/标题的匹配程度 (0-1)
 }
 
+// 歌词附加层，保存为与原文同名、带层名后缀的 .lrc 文件
+const (
+	LyricLayerTranslation  = "trans"
+	LyricLayerRomanization = "roma"
+)
+
 type CoverResult struct {
-	URL    string `json:"url"`
-	Data   []byte `json:"data"`
-	Source string `json:"source"`
+	URL        string  `json:"url"`
+	Data       []byte  `json:"data"`
+	Source     string  `json:"source"`
+	Confidence float64 `json:"confidence"`
 }
 
-// NewFetcher 创建抓取器
-// ✅ 修复：强制将相对路径转换为绝对路径 (基于 /app/data)
-func NewFetcher(lyricsDir, coversDir string) *Fetcher {
-	// 确保使用绝对路径
-	if !filepath.IsAbs(lyricsDir) {
-		lyricsDir = filepath.Join("/app/data", lyricsDir)
-	}
-	if !filepath.IsAbs(coversDir) {
-		coversDir = filepath.Join("/app/data", coversDir)
+// Options 抓取器配置，未列出的提供方使用 DefaultOptions 中的设置
+type Options struct {
+	DataDir   string // 歌词缓存在 DataDir/lyrics，封面库在 DataDir/covers
+	Proxy     string // http、https 或 socks5 代理地址，为空时使用 HTTP_PROXY 等环境变量
+	Timeout   time.Duration
+	UserAgent string
+	Providers map[string]ProviderOptions
+}
+
+// ProviderOptions 单个提供方的配置，测试时可把 BaseURL 指向本地替身服务
+type ProviderOptions struct {
+	BaseURL   string
+	Disabled  bool
+	RateLimit float64 // 每秒请求数，为 0 时使用默认值，小于 0 时不限速
+	Burst     int     // 允许的突发请求数
+}
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		DataDir:   "./data",
+		Timeout:   30 * time.Second,
+		UserAgent: "Mozilla/5.0",
+		Providers: map[string]ProviderOptions{
+			ProviderNetease:         {BaseURL: "https://music.163.com", RateLimit: 2, Burst: 2},
+			ProviderITunes:          {BaseURL: "https://itunes.apple.com", RateLimit: 0.3, Burst: 3}, // 公开接口约每分钟 20 次
+			ProviderCoverArtArchive: {BaseURL: "https://coverartarchive.org"},
+		},
 	}
+}
 
-	log.Printf("[Fetcher] Initializing with lyricsDir=%s, coversDir=%s", lyricsDir, coversDir)
+// LyricsDir 数据目录下的歌词缓存目录
+func LyricsDir(dataDir string) string {
+	return filepath.Join(dataDir, "lyrics")
+}
+
+// CoversDir 数据目录下的封面库目录
+func CoversDir(dataDir string) string {
+	return filepath.Join(dataDir, "covers")
+}
 
+// NewFetcher 创建抓取器，opts 中为空的项使用默认值
+func NewFetcher(opts Options) *Fetcher {
+	defaults := DefaultOptions()
+	if opts.DataDir == "" {
+		opts.DataDir = defaults.DataDir
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = defaults.UserAgent
+	}
+	providers := defaults.Providers
+	for name, p := range opts.Providers {
+		d := providers[name]
+		if p.BaseURL == "" {
+			p.BaseURL = d.BaseURL
+		}
+		if p.RateLimit == 0 {
+			p.RateLimit = d.RateLimit
+		}
+		if p.Burst <= 0 {
+			p.Burst = d.Burst
+		}
+		p.BaseURL = strings.TrimSuffix(p.BaseURL, "/")
+		providers[name] = p
+	}
+	opts.Providers = providers
+
+	lyricsDir, coversDir := LyricsDir(opts.DataDir), CoversDir(opts.DataDir)
 	// 提前创建目录，如果失败则记录日志但不 panic
 	if err := os.MkdirAll(lyricsDir, 0755); err != nil {
 		log.Printf("[Fetcher] Warning: failed to create lyrics dir %s: %v", lyricsDir, err)
@@ -53,40 +124,84 @@ func NewFetcher(lyricsDir, coversDir string) *Fetcher {
 		log.Printf("[Fetcher] Warning: failed to create covers dir %s: %v", coversDir, err)
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		if proxyURL, err := url.Parse(opts.Proxy); err != nil || proxyURL.Host == "" {
+			log.Printf("[Fetcher] Warning: ignoring invalid proxy %q", opts.Proxy)
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
 	return &Fetcher{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   opts.Timeout,
+			Transport: transport,
 		},
 		lyricsDir: lyricsDir,
 		coversDir: coversDir,
+		opts:      opts,
 	}
 }
 
-func (f *Fetcher) SearchLyrics(artist, title string) (*LyricResult, error) {
-	result, err := f.searchNeteaseLyrics(artist, title)
-	if err == nil && result != nil {
-		return result, nil
-	}
-	return nil, fmt.Errorf("lyrics not found")
+// Enabled 提供方是否启用
+func (f *Fetcher) Enabled(provider string) bool {
+	p, ok := f.opts.Providers[provider]
+	return ok && !p.Disabled
 }
 
-func (f *Fetcher) searchNeteaseLyrics(artist, title string) (*LyricResult, error) {
-	searchURL := fmt.Sprintf("https://music.163.com/api/search/get?type=1&s=%s",
-		url.QueryEscape(title+" "+artist))
+// baseURL 提供方接口地址，不带结尾的 /
+func (f *Fetcher) baseURL(provider string) string {
+	return f.opts.Providers[provider].BaseURL
+}
 
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
+func (f *Fetcher) SearchLyrics(artist, title string) (*LyricResult, error) {
+	return f.SearchLyricsWith(artist, title, 0, SearchOptions{})
+}
 
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil, err
+// SearchLyricsWith 按提供方优先级搜索歌词，返回第一个达到最低置信度的结果；
+// 都没有找到时返回 ErrNotFound，提供方出错时返回最后一个错误。duration 为秒，未知时传 0
+func (f *Fetcher) SearchLyricsWith(artist, title string, duration int, opts SearchOptions) (*LyricResult, error) {
+	var lastErr error
+	best := 0.0
+	for _, provider := range LyricsProviders() {
+		if !f.Enabled(provider) || !opts.enabled(provider) {
+			continue
+		}
+		var result *LyricResult
+		var err error
+		switch provider {
+		case ProviderNetease:
+			result, err = f.searchNeteaseLyrics(artist, title, duration)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				lastErr = err
+			}
+			continue
+		}
+		if result.Confidence >= opts.MinConfidence {
+			return result, nil
+		}
+		if result.Confidence > best {
+			best = result.Confidence
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if best > 0 {
+		return nil, fmt.Errorf("%w: best match confidence %.2f is below %.2f", ErrNotFound, best, opts.MinConfidence)
 	}
-	defer resp.Body.Close()
+	return nil, fmt.Errorf("lyrics %w", ErrNotFound)
+}
+
+// searchNeteaseLyrics 在搜索结果中选择置信度最高的歌曲并获取其歌词
+func (f *Fetcher) searchNeteaseLyrics(artist, title string, duration int) (*LyricResult, error) {
+	searchURL := fmt.Sprintf("%s/api/search/get?type=1&s=%s",
+		f.baseURL(ProviderNetease), url.QueryEscape(title+" "+artist))
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := f.getProvider(ProviderNetease, searchURL)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +209,12 @@ func (f *Fetcher) searchNeteaseLyrics(artist, title string) (*LyricResult, error
 	var result struct {
 		Result struct {
 			Songs []struct {
-				ID int `json:"id"`
+				ID       int    `json:"id"`
+				Name     string `json:"name"`
+				Duration int    `json:"duration"` // 毫秒
+				Artists  []struct {
+					Name string `json:"name"`
+				} `json:"artists"`
 			} `json:"songs"`
 		} `json:"result"`
 	}
@@ -104,25 +224,24 @@ func (f *Fetcher) searchNeteaseLyrics(artist, title string) (*LyricResult, error
 	}
 
 	if len(result.Result.Songs) == 0 {
-		return nil, fmt.Errorf("no songs found")
+		return nil, fmt.Errorf("netease: no songs %w", ErrNotFound)
 	}
 
-	songID := result.Result.Songs[0].ID
-	lyricURL := fmt.Sprintf("https://music.163.com/api/song/lyric?id=%d&lv=1", songID)
-
-	req, err = http.NewRequest("GET", lyricURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-
-	resp, err = f.client.Do(req)
-	if err != nil {
-		return nil, err
+	songID, confidence := 0, -1.0
+	for _, song := range result.Result.Songs {
+		names := make([]string, len(song.Artists))
+		for i, a := range song.Artists {
+			names[i] = a.Name
+		}
+		c := songConfidence(artist, title, duration, strings.Join(names, " / "), song.Name, song.Duration/1000)
+		if c > confidence {
+			songID, confidence = song.ID, c
+		}
 	}
-	defer resp.Body.Close()
+	// tv/rv 为 -1 时同时返回翻译和罗马音
+	lyricURL := fmt.Sprintf("%s/api/song/lyric?id=%d&lv=1&tv=-1&rv=-1", f.baseURL(ProviderNetease), songID)
 
-	body, err = io.ReadAll(resp.Body)
+	body, err = f.getProvider(ProviderNetease, lyricURL)
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +250,12 @@ func (f *Fetcher) searchNeteaseLyrics(artist, title string) (*LyricResult, error
 		Lrc struct {
 			Lyric string `json:"lyric"`
 		} `json:"lrc"`
+		Tlyric struct {
+			Lyric string `json:"lyric"`
+		} `json:"tlyric"`
+		Romalrc struct {
+			Lyric string `json:"lyric"`
+		} `json:"romalrc"`
 	}
 
 	if err := json.Unmarshal(body, &lyricResult); err != nil {
@@ -138,227 +263,107 @@ func (f *Fetcher) searchNeteaseLyrics(artist, title string) (*LyricResult, error
 	}
 
 	if lyricResult.Lrc.Lyric == "" {
-		return nil, fmt.Errorf("no lyrics")
+		return nil, fmt.Errorf("netease: no lyrics %w", ErrNotFound)
 	}
 
 	return &LyricResult{
-		Content: lyricResult.Lrc.Lyric,
-		Source:  "netease",
+		Content:      lyricResult.Lrc.Lyric,
+		Translation:  lyricResult.Tlyric.Lyric,
+		Romanization: lyricResult.Romalrc.Lyric,
+		Source:       ProviderNetease,
+		Confidence:   confidence,
 	}, nil
 }
 
 func (f *Fetcher) SearchCover(artist, album string) (*CoverResult, error) {
-	// 1. 先试网易云
-	result, err := f.searchNeteaseCover(artist, album)
-	if err == nil && result != nil {
-		return result, nil
-	}
-
-	// 2. 网易云失败，试 iTunes
-	log.Printf("[Fetcher] Netease failed, trying iTunes...")
-	itunesResult, err := f.searchItunesCover(artist, album)
-	if err == nil && itunesResult != nil {
-		return itunesResult, nil
-	}
-
-	return nil, fmt.Errorf("cover not found in any source")
-}
-
-func (f *Fetcher) searchItunesCover(artist, album string) (*CoverResult, error) {
-	term := url.QueryEscape(album + " " + artist)
-	apiURL := fmt.Sprintf("https://itunes.apple.com/search?term=%s&media=music&limit=1", term)
-
-	resp, err := f.client.Get(apiURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Results []struct {
-			ArtworkURL100 string `json:"artworkUrl100"`
-		} `json:"results"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	if len(result.Results) == 0 {
-		return nil, fmt.Errorf("itunes: no results")
-	}
-
-	// iTunes 返回的是 100x100 小图，替换成大图
-	coverURL := strings.Replace(result.Results[0].ArtworkURL100, "100x100bb", "600x600bb", 1)
-
-	data, err := f.downloadImage(coverURL)
-	if err != nil {
-		return nil, err
-	}
-
-	return &CoverResult{
-		URL:    coverURL,
-		Data:   data,
-		Source: "itunes",
-	}, nil
+	return f.SearchCoverWith(artist, album, SearchOptions{})
 }
 
-func (f *Fetcher) searchNeteaseCover(artist, album string) (*CoverResult, error) {
-	// 定义多种搜索组合，按优先级尝试
-	searchTerms := []string{}
-
-	// 1. 优先：专辑名 + 歌手
-	if album != "" && artist != "" {
-		searchTerms = append(searchTerms, album+" "+artist)
-	}
-
-	// 2. 次选：仅专辑名
-	if album != "" {
-		searchTerms = append(searchTerms, album)
-	}
-
-	// 3. 再次：歌名 + 歌手 (把 album 当作歌名重试)
-	if album != "" && artist != "" {
-		// 有时候 album 字段存的是歌名
-		searchTerms = append(searchTerms, album)
-	}
-
-	// 4. 最后：仅歌手
-	if artist != "" && artist != "Various Artists" {
-		searchTerms = append(searchTerms, artist)
-	}
-
-	// 遍历所有搜索词
-	for _, term := range searchTerms {
-		result, err := f.searchWithTerm(term)
-		if err == nil && result != nil {
-			return result, nil
+// SearchCoverWith 汇总启用的提供方的专辑搜索结果，按置信度从高到低下载第一张可用的封面
+func (f *Fetcher) SearchCoverWith(artist, album string, opts SearchOptions) (*CoverResult, error) {
+	var candidates []CoverCandidate
+	var lastErr error
+	for _, provider := range CoverProviders() {
+		if !f.Enabled(provider) || !opts.enabled(provider) {
+			continue
 		}
+		list, err := f.coverCandidates(provider, artist, album, 10)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		candidates = append(candidates, list...)
 	}
+	// 同分时保持提供方优先级
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Confidence > candidates[j].Confidence })
 
-	return nil, fmt.Errorf("cover not found after trying all combinations")
-}
-
-// 辅助函数：用特定关键词搜索
-func (f *Fetcher) searchWithTerm(term string) (*CoverResult, error) {
-	searchURL := fmt.Sprintf("https://music.163.com/api/search/get?type=1&s=%s",
-		url.QueryEscape(term))
-
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var result struct {
-		Result struct {
-			Songs []struct {
-				Album struct {
-					PicURL string `json:"picUrl"`
-					Name   string `json:"name"` // 添加专辑名用于验证
-				} `json:"album"`
-				Name    string `json:"name"` // 歌名
-				Artists []struct {
-					Name string `json:"name"`
-				} `json:"artists"`
-			} `json:"songs"`
-		} `json:"result"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
-	if len(result.Result.Songs) == 0 {
-		return nil, fmt.Errorf("no songs found for: %s", term)
-	}
-
-	// 遍历结果，找到最匹配的
-	for _, song := range result.Result.Songs {
-		picURL := song.Album.PicURL
-		if picURL != "" && !strings.Contains(picURL, "default_album") {
-			// 排除默认专辑封面
-			picURL = strings.Replace(picURL, "http://", "https://", 1)
-
-			log.Printf("[Fetcher] Found cover for '%s': %s", term, picURL)
-
-			coverData, err := f.downloadImage(picURL)
-			if err != nil {
-				continue
+	best := 0.0
+	tried := 0
+	for _, c := range candidates {
+		if c.Confidence < opts.MinConfidence {
+			if c.Confidence > best {
+				best = c.Confidence
 			}
-
-			return &CoverResult{
-				URL:    picURL,
-				Data:   coverData,
-				Source: "netease",
-			}, nil
+			continue
+		}
+		// 只尝试最匹配的几张，下载失败时换下一张
+		if tried == 3 {
+			break
 		}
+		tried++
+		data, err := f.DownloadImage(c.URL)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		log.Printf("[Fetcher] Found %s cover for '%s - %s' (confidence %.2f): %s", c.Source, artist, album, c.Confidence, c.URL)
+		return &CoverResult{URL: c.URL, Data: data, Source: c.Source, Confidence: c.Confidence}, nil
 	}
-
-	return nil, fmt.Errorf("no valid cover found for: %s", term)
-}
-
-func (f *Fetcher) downloadImage(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+	if lastErr != nil {
+		return nil, lastErr
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil, err
+	if best > 0 {
+		return nil, fmt.Errorf("%w: best match confidence %.2f is below %.2f", ErrNotFound, best, opts.MinConfidence)
 	}
-	defer resp.Body.Close()
+	return nil, fmt.Errorf("cover %w", ErrNotFound)
+}
 
-	return io.ReadAll(resp.Body)
+// coverCandidates 单个提供方的封面候选，已计算置信度
+func (f *Fetcher) coverCandidates(provider, artist, album string, limit int) ([]CoverCandidate, error) {
+	var list []CoverCandidate
+	var err error
+	switch provider {
+	case ProviderNetease:
+		list, err = f.neteaseCoverCandidates(artist, album, limit)
+	case ProviderITunes:
+		list, err = f.itunesCoverCandidates(artist, album, limit)
+	default:
+		return nil, fmt.Errorf("unknown cover provider: %s", provider)
+	}
+	for i := range list {
+		list[i].Confidence = albumConfidence(artist, album, list[i].Artist, list[i].Album)
+	}
+	return list, err
 }
 
-// SaveCover 保存封面到本地
-func (f *Fetcher) SaveCover(artist, album string, data []byte) (string, error) {
+// SaveCover 按内容哈希保存封面，返回图片在 artwork 存储中的路径。
+// 不同的图片不会互相覆盖，由调用方记录到数据库并关联到曲目
+func (f *Fetcher) SaveCover(data []byte) (string, error) {
 	if len(data) == 0 {
 		return "", fmt.Errorf("cover data is empty")
 	}
-
-	filename := f.generateFilename(artist, album) + ".jpg"
-	filepath := filepath.Join(f.coversDir, filename)
-
-	// ✅ 修复：如果文件已存在，直接返回成功
-	if _, err := os.Stat(filepath); err == nil {
-		log.Printf("[Fetcher] Cover already exists: %s", filepath)
-		return filepath, nil
-	}
-
-	log.Printf("[Fetcher] Saving cover to: %s (size: %d bytes)", filepath, len(data))
-
-	if err := os.MkdirAll(f.coversDir, 0755); err != nil {
-		log.Printf("[Fetcher] Error creating directory: %v", err)
-		return "", fmt.Errorf("failed to create covers directory: %w", err)
-	}
-
-	if err := os.WriteFile(filepath, data, 0644); err != nil {
-		log.Printf("[Fetcher] Error writing file: %v", err)
-		return "", fmt.Errorf("failed to write cover file: %w", err)
-	}
-
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		log.Printf("[Fetcher] CRITICAL: File written but does not exist!")
-		return "", fmt.Errorf("file system error")
+	img, err := f.ArtworkStore().Put(data)
+	if err != nil {
+		log.Printf("[Fetcher] Error saving cover: %v", err)
+		return "", err
 	}
+	log.Printf("[Fetcher] Cover saved: %s (%s, %dx%d)", img.Hash, img.MIME, img.Width, img.Height)
+	return f.ArtworkStore().Path(img.Hash), nil
+}
 
-	log.Printf("[Fetcher] Cover saved successfully: %s", filepath)
-	return filepath, nil
+// ArtworkStore 封面目录对应的内容寻址存储
+func (f *Fetcher) ArtworkStore() *artwork.Store {
+	return artwork.NewStore(f.coversDir)
 }
 
 // SaveLyrics 同理修复
@@ -382,6 +387,22 @@ func (f *Fetcher) SaveLyrics(artist, title, content string) (string, error) {
 	return filepath, nil
 }
 
+// SaveLyricLayer 保存翻译或罗马音歌词，content 为空时不保存
+func (f *Fetcher) SaveLyricLayer(artist, title, layer, content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", nil
+	}
+	path := f.GetLocalLyricsLayerPath(artist, title, layer)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		log.Printf("[Fetcher] Error saving %s lyrics: %v", layer, err)
+		return "", err
+	}
+	return path, nil
+}
+
 func (f *Fetcher) generateFilename(artist, title string) string {
 	raw := artist + " - " + title
 	if raw == " - " {
@@ -396,11 +417,46 @@ func (f *Fetcher) GetLocalLyricsPath(artist, title string) string {
 	return filepath.Join(f.lyricsDir, filename)
 }
 
+// GetLocalLyricsLayerPath 翻译/罗马音歌词的本地路径
+func (f *Fetcher) GetLocalLyricsLayerPath(artist, title, layer string) string {
+	filename := f.generateFilename(artist, title) + "." + layer + ".lrc"
+	return filepath.Join(f.lyricsDir, filename)
+}
+
+// GetLocalCoverPath 旧版按艺术家+专辑命名的封面路径，只用于导入已有缓存
 func (f *Fetcher) GetLocalCoverPath(artist, album string) string {
 	filename := f.generateFilename(artist, album) + ".jpg"
 	return filepath.Join(f.coversDir, filename)
 }
 
+// CachedFiles 列出本地缓存目录中的歌词和封面文件
+func (f *Fetcher) CachedFiles() (lyrics, covers []string, err error) {
+	list := func(dir string) ([]string, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		var files []string
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(dir, e.Name()))
+			}
+		}
+		return files, nil
+	}
+
+	if lyrics, err = list(f.lyricsDir); err != nil {
+		return nil, nil, err
+	}
+	if covers, err = list(f.coversDir); err != nil {
+		return nil, nil, err
+	}
+	return lyrics, covers, nil
+}
+
 // FetchAndSave 获取并保存歌词和封面
 // ✅ 修复：严格分离歌词和封面逻辑，各自独立返回结果
 func (f *Fetcher) FetchAndSave(artist, title, album string) (lyricsPath, coverPath string, err error) {
@@ -414,6 +470,8 @@ func (f *Fetcher) FetchAndSave(artist, title, album string) (lyricsPath, coverPa
 			} else {
 				lyricsPath = path
 				log.Printf("[Fetcher] ✅ Lyrics saved: %s", lyricsPath)
+				f.SaveLyricLayer(artist, title, LyricLayerTranslation, lyric.Translation)
+				f.SaveLyricLayer(artist, title, LyricLayerRomanization, lyric.Romanization)
 			}
 		} else {
 			log.Printf("[Fetcher] ℹ️ Lyrics not found for: %s - %s", artist, title)
@@ -437,7 +495,7 @@ func (f *Fetcher) FetchAndSave(artist, title, album string) (lyricsPath, coverPa
 
 		// ✅ 关键修复：严格检查 cover.Data 是否存在且不为空
 		if coverErr == nil && cover != nil && cover.Data != nil && len(cover.Data) > 0 {
-			path, saveErr := f.SaveCover(searchArtist, searchAlbum, cover.Data)
+			path, saveErr := f.SaveCover(cover.Data)
 			if saveErr != nil {
 				log.Printf("[Fetcher] ❌ Cover fetched but save failed: %v", saveErr)
 				// 封面保存失败，返回错误，让上层知道
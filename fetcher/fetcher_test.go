@@ -0,0 +1,147 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestFetcher 把网易云接口指向本地替身服务，其他提供方停用
+func newTestFetcher(t *testing.T, handler http.Handler) (*Fetcher, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Cleanup(ClearResponseCache)
+
+	f := NewFetcher(Options{
+		DataDir:   t.TempDir(),
+		UserAgent: "go-music-tag-test",
+		Providers: map[string]ProviderOptions{
+			ProviderNetease:         {BaseURL: srv.URL + "/", RateLimit: -1},
+			ProviderITunes:          {Disabled: true},
+			ProviderCoverArtArchive: {Disabled: true},
+		},
+	})
+	return f, srv
+}
+
+func TestSearchLyricsUsesBaseURL(t *testing.T) {
+	var userAgent atomic.Value
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search/get", func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.UserAgent())
+		fmt.Fprint(w, `{"result":{"songs":[
+			{"id":1,"name":"Other Song","duration":200000,"artists":[{"name":"Someone"}]},
+			{"id":2,"name":"Hello","duration":180000,"artists":[{"name":"Adele"}]}
+		]}}`)
+	})
+	mux.HandleFunc("/api/song/lyric", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") != "2" {
+			http.Error(w, "unexpected song", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"lrc":{"lyric":"[00:01.00]Hello"},"tlyric":{"lyric":"[00:01.00]你好"}}`)
+	})
+	f, _ := newTestFetcher(t, mux)
+
+	result, err := f.SearchLyricsWith("Adele", "Hello", 180, SearchOptions{})
+	if err != nil {
+		t.Fatalf("SearchLyricsWith: %v", err)
+	}
+	if result.Content != "[00:01.00]Hello" || result.Translation != "[00:01.00]你好" {
+		t.Errorf("unexpected lyrics: %+v", result)
+	}
+	if result.Source != ProviderNetease || result.Confidence < 0.9 {
+		t.Errorf("source = %s, confidence = %.2f", result.Source, result.Confidence)
+	}
+	if got := userAgent.Load(); got != "go-music-tag-test" {
+		t.Errorf("User-Agent = %v", got)
+	}
+}
+
+func TestSearchLyricsNotFound(t *testing.T) {
+	f, _ := newTestFetcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"songs":[]}}`)
+	}))
+
+	_, err := f.SearchLyricsWith("Nobody", "Nothing", 0, SearchOptions{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSearchLyricsMinConfidence(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"songs":[{"id":1,"name":"Different","duration":100000,"artists":[{"name":"Other"}]}]}}`)
+	})
+	mux.HandleFunc("/api/song/lyric", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"lrc":{"lyric":"[00:01.00]x"}}`)
+	})
+	f, _ := newTestFetcher(t, mux)
+
+	_, err := f.SearchLyricsWith("Adele", "Hello", 180, SearchOptions{MinConfidence: 0.9})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound below min confidence", err)
+	}
+}
+
+func TestProviderRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	f, srv := newTestFetcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+
+	body, err := f.getProvider(ProviderNetease, srv.URL+"/retry")
+	if err != nil {
+		t.Fatalf("getProvider: %v", err)
+	}
+	if string(body) != "ok" || calls.Load() != 2 {
+		t.Errorf("body = %q after %d calls", body, calls.Load())
+	}
+
+	// 成功的响应被缓存，不再请求
+	if _, err := f.getProvider(ProviderNetease, srv.URL+"/retry"); err != nil {
+		t.Fatalf("cached getProvider: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want cached response", calls.Load())
+	}
+}
+
+func TestProviderDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	f, srv := newTestFetcher(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+
+	if _, err := f.getProvider(ProviderNetease, srv.URL+"/missing"); err == nil {
+		t.Fatal("expected error for HTTP 404")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestDisabledProvider(t *testing.T) {
+	f, _ := newTestFetcher(t, http.NotFoundHandler())
+
+	if f.Enabled(ProviderITunes) {
+		t.Error("itunes should be disabled")
+	}
+	if !f.Enabled(ProviderNetease) {
+		t.Error("netease should be enabled")
+	}
+	if got := f.baseURL(ProviderITunes); got != "https://itunes.apple.com" {
+		t.Errorf("disabled provider keeps default base URL, got %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package fetcher
+
+import (
+	"errors"
+	"go-music-tag/normalize"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 提供方名称
+const (
+	ProviderNetease         = "netease"
+	ProviderITunes          = "itunes"
+	ProviderCoverArtArchive = "coverartarchive" // 只用于按 MusicBrainz 发行 ID 列出封面候选
+)
+
+// ErrNotFound 启用的提供方都没有达到最低置信度的结果，与网络错误区分以便调用方缓存
+var ErrNotFound = errors.New("not found")
+
+// LyricsProviders 可获取歌词的提供方，按优先级排序
+func LyricsProviders() []string {
+	return []string{ProviderNetease}
+}
+
+// CoverProviders 可获取封面的提供方，按优先级排序
+func CoverProviders() []string {
+	return []string{ProviderNetease, ProviderITunes}
+}
+
+// SearchOptions 搜索选项，Providers 为空时使用全部提供方，MinConfidence 取值 0-1
+type SearchOptions struct {
+	Providers     []string
+	MinConfidence float64
+}
+
+func (o SearchOptions) enabled(provider string) bool {
+	if len(o.Providers) == 0 {
+		return true
+	}
+	for _, p := range o.Providers {
+		if strings.EqualFold(p, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// bracketSuffix 标题中的括号说明，如 (Live)、【伴奏】、(feat. X)
+var bracketSuffix = regexp.MustCompile(`\s*[(（\[【][^)）\]】]*[)）\]】]`)
+
+// matchKey 统一为简体小写并去掉空白与标点
+func matchKey(s string) string {
+	s = strings.ToLower(normalize.ToSimplified(s))
+	var sb strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// similarity 两个名称的相似度 (0-1)，同时比较去掉括号说明后的形式，取较高者
+func similarity(want, got string) float64 {
+	best := textSimilarity(matchKey(want), matchKey(got))
+	if stripped := textSimilarity(matchKey(bracketSuffix.ReplaceAllString(want, "")), matchKey(bracketSuffix.ReplaceAllString(got, ""))); stripped > best {
+		best = stripped
+	}
+	return best
+}
+
+// textSimilarity 基于编辑距离的相似度，一方完整包含另一方时至少为 0.85
+func textSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	score := 1 - float64(levenshtein(ra, rb))/float64(longer)
+	if (strings.Contains(a, b) || strings.Contains(b, a)) && score < 0.85 {
+		score = 0.85
+	}
+	return score
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// weightedConfidence 主名称与艺术家加权，请求中没有艺术家时只看主名称
+func weightedConfidence(wantName, gotName, wantArtist, gotArtist string, nameWeight float64) float64 {
+	name := similarity(wantName, gotName)
+	if strings.TrimSpace(wantArtist) == "" {
+		return name
+	}
+	return name*nameWeight + similarity(wantArtist, gotArtist)*(1-nameWeight)
+}
+
+// songConfidence 歌曲匹配置信度，两边都有时长且相差超过 10 秒时降低
+func songConfidence(artist, title string, duration int, gotArtist, gotTitle string, gotDuration int) float64 {
+	c := weightedConfidence(title, gotTitle, artist, gotArtist, 0.6)
+	if duration > 0 && gotDuration > 0 {
+		delta := duration - gotDuration
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta > 10 {
+			c *= 0.8
+		}
+	}
+	return c
+}
+
+// albumConfidence 专辑封面匹配置信度
+func albumConfidence(artist, album, gotArtist, gotAlbum string) float64 {
+	return weightedConfidence(album, gotAlbum, artist, gotArtist, 0.7)
+}
@@ -0,0 +1,167 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AcoustID 限制每个应用每秒最多 3 个请求
+const acoustIDMinInterval = 350 * time.Millisecond
+
+var (
+	acoustIDRateMutex   sync.Mutex
+	acoustIDLastRequest time.Time
+)
+
+// AcoustIDClient AcoustID Web Service 客户端
+type AcoustIDClient struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+// Match AcoustID 返回的一个录音匹配
+type Match struct {
+	AcoustID       string   `json:"acoustid"`
+	Score          float64  `json:"score"`
+	RecordingID    string   `json:"recording_id"`
+	Title          string   `json:"title"`
+	Artist         string   `json:"artist"`
+	ArtistIDs      []string `json:"artist_ids"`
+	Album          string   `json:"album"`
+	ReleaseGroupID string   `json:"release_group_id"`
+	Duration       int      `json:"duration"`
+}
+
+// NewAcoustIDClient 创建客户端，baseURL 为空时使用官方地址
+func NewAcoustIDClient(apiKey, baseURL string) *AcoustIDClient {
+	if baseURL == "" {
+		baseURL = "https://api.acoustid.org/v2"
+	}
+	return &AcoustIDClient{
+		client:  &http.Client{Timeout: 15 * time.Second},
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Lookup 根据指纹查询录音，结果按分数降序排列
+func (c *AcoustIDClient) Lookup(fp *Result) ([]Match, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("acoustid api key not configured")
+	}
+	if fp == nil || fp.Fingerprint == "" {
+		return nil, fmt.Errorf("no fingerprint")
+	}
+
+	form := url.Values{}
+	form.Set("client", c.apiKey)
+	form.Set("meta", "recordings releasegroups compress")
+	form.Set("duration", strconv.Itoa(fp.Duration))
+	form.Set("fingerprint", fp.Fingerprint)
+
+	waitForAcoustID()
+
+	req, err := http.NewRequest("POST", c.baseURL+"/lookup", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "MusicTagManager/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Results []struct {
+			ID         string  `json:"id"`
+			Score      float64 `json:"score"`
+			Recordings []struct {
+				ID       string  `json:"id"`
+				Title    string  `json:"title"`
+				Duration float64 `json:"duration"`
+				Artists  []struct {
+					ID         string `json:"id"`
+					Name       string `json:"name"`
+					JoinPhrase string `json:"joinphrase"`
+				} `json:"artists"`
+				ReleaseGroups []struct {
+					ID    string `json:"id"`
+					Title string `json:"title"`
+				} `json:"releasegroups"`
+			} `json:"recordings"`
+		} `json:"results"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("invalid acoustid response (%s): %w", resp.Status, err)
+	}
+	if result.Status != "ok" {
+		return nil, fmt.Errorf("acoustid error: %s", result.Error.Message)
+	}
+
+	var matches []Match
+	for _, r := range result.Results {
+		if len(r.Recordings) == 0 {
+			// 指纹已知但尚未关联 MusicBrainz 录音
+			matches = append(matches, Match{AcoustID: r.ID, Score: r.Score})
+			continue
+		}
+		for _, rec := range r.Recordings {
+			m := Match{
+				AcoustID:    r.ID,
+				Score:       r.Score,
+				RecordingID: rec.ID,
+				Title:       rec.Title,
+				Duration:    int(rec.Duration + 0.5),
+			}
+			var artist strings.Builder
+			for _, a := range rec.Artists {
+				artist.WriteString(a.Name)
+				artist.WriteString(a.JoinPhrase)
+				m.ArtistIDs = append(m.ArtistIDs, a.ID)
+			}
+			m.Artist = artist.String()
+			if len(rec.ReleaseGroups) > 0 {
+				m.Album = rec.ReleaseGroups[0].Title
+				m.ReleaseGroupID = rec.ReleaseGroups[0].ID
+			}
+			matches = append(matches, m)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches, nil
+}
+
+func waitForAcoustID() {
+	acoustIDRateMutex.Lock()
+	defer acoustIDRateMutex.Unlock()
+
+	if wait := acoustIDMinInterval - time.Since(acoustIDLastRequest); wait > 0 {
+		time.Sleep(wait)
+	}
+	acoustIDLastRequest = time.Now()
+}
@@ -0,0 +1,113 @@
+package fingerprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Result 一段音频的 Chromaprint 指纹
+type Result struct {
+	Fingerprint string `json:"fingerprint"` // fpcalc 输出的压缩 base64 指纹
+	Duration    int    `json:"duration"`    // 秒
+}
+
+// Fingerprinter 调用 Chromaprint 的 fpcalc 解码音频并计算指纹
+type Fingerprinter struct {
+	fpcalcPath string
+	length     int
+	timeout    time.Duration
+}
+
+// NewFingerprinter 创建指纹计算器，length 为参与计算的音频秒数
+func NewFingerprinter(fpcalcPath string, length int) *Fingerprinter {
+	if fpcalcPath == "" {
+		fpcalcPath = "fpcalc"
+	}
+	if length <= 0 {
+		length = 120
+	}
+	return &Fingerprinter{
+		fpcalcPath: fpcalcPath,
+		length:     length,
+		timeout:    2 * time.Minute,
+	}
+}
+
+// Available 检查 fpcalc 是否可用
+func (f *Fingerprinter) Available() error {
+	if _, err := exec.LookPath(f.fpcalcPath); err != nil {
+		return fmt.Errorf("fpcalc not found (%s): %w", f.fpcalcPath, err)
+	}
+	return nil
+}
+
+// Compute 计算音频数据的指纹；ext 为原文件扩展名，帮助解码器识别格式
+func (f *Fingerprinter) Compute(data []byte, ext string) (*Result, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty audio data")
+	}
+
+	tmp, err := os.CreateTemp("", "fingerprint-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	return f.ComputeFile(tmp.Name())
+}
+
+// ComputeFile 计算本地文件的指纹
+func (f *Fingerprinter) ComputeFile(path string) (*Result, error) {
+	cmd := exec.Command(f.fpcalcPath, "-json", "-length", strconv.Itoa(f.length), path)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run fpcalc: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("fpcalc failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+	case <-time.After(f.timeout):
+		cmd.Process.Kill()
+		<-done
+		return nil, fmt.Errorf("fpcalc timed out after %s", f.timeout)
+	}
+
+	var out struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("invalid fpcalc output: %w", err)
+	}
+	if out.Fingerprint == "" {
+		return nil, fmt.Errorf("fpcalc returned an empty fingerprint")
+	}
+
+	return &Result{
+		Fingerprint: out.Fingerprint,
+		Duration:    int(out.Duration + 0.5),
+	}, nil
+}
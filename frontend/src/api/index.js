@@ -41,6 +41,11 @@ export const api = {
 
   // ✅ 新增：批量更新 (匹配后端 POST /music/batch)
   batchUpdateMusic: (data) => request.post('/music/batch', data),
+  // 批量编辑：任意字段的 set/clear/replace/copy/number 操作
+  batchEditMusic: (data) => request.post('/music/batch-edit', data),
+  getEditableFields: () => request.get('/music/batch-edit/fields'),
+  repairEncoding: (data) => request.post('/music/repair-encoding', data),
+  getCharsetOptions: () => request.get('/music/repair-encoding/options'),
 
   // 删除单曲
   deleteMusic: (id) => request.delete(`/music/${id}`),
@@ -50,9 +55,33 @@ export const api = {
 
   // 获取歌词内容
   getLyrics: (id) => request.get(`/music/${id}/lyrics`),
+  getLyricsExportUrl: (id, format = 'lrc', layers = false) => `/api/v1/music/${id}/lyrics/export?format=${format}&layers=${layers}`,
+
+  // 手动编辑歌词 (content 为空时清除) 与时间轴平移
+  updateLyrics: (id, data) => request.put(`/music/${id}/lyrics`, data),
+  adjustLyricsTiming: (id, offsetMs) => request.post(`/music/${id}/lyrics/adjust`, { offset_ms: offsetMs }),
+
+  // 歌词历史版本
+  getLyricsVersions: (id) => request.get(`/music/${id}/lyrics/versions`),
+  restoreLyricsVersion: (id, version) => request.post(`/music/${id}/lyrics/versions/${version}/restore`),
   
   // 获取封面图片 URL (静态资源不需要经过 axios 拦截器)
   getCoverUrl: (id) => `/api/v1/music/${id}/cover`,
+  getArtworkUrl: (artworkId) => `/api/v1/artwork/${artworkId}`,
+
+  // 手动设置封面：上传文件 (File) 或指定图片地址，scope 为 track 或 album
+  uploadCover: (id, file, scope = 'track') => {
+    const form = new FormData()
+    form.append('file', file)
+    form.append('scope', scope)
+    return request.post(`/music/${id}/cover`, form)
+  },
+  setCoverFromUrl: (id, url, scope = 'track') => request.post(`/music/${id}/cover`, { url, scope }),
+  getCoverCandidates: (id, params) => request.get(`/music/${id}/cover/candidates`, { params }),
+  deleteCover: (id, scope = 'track') => request.delete(`/music/${id}/cover`, { params: { scope } }),
+
+  // 回收不再被曲目或专辑引用的封面图片
+  collectArtworkGarbage: (dryRun = false) => request.post('/artwork/gc', { dry_run: dryRun }),
   
   // 获取播放地址
   getPlayUrl: (id) => `/api/v1/music/${id}/play`,
@@ -73,9 +102,85 @@ export const api = {
   // 批量获取全部 (匹配后端 POST /music/batch-fetch-all)
   batchFetchAll: () => request.post('/music/batch-fetch-all'),
 
+  // 按选择或筛选条件批量获取：{ ids, album, artist, search, kinds, overwrite, providers, min_confidence, retry_job }
+  batchFetch: (data) => request.post('/music/batch-fetch', data),
+  getFetchJobs: () => request.get('/fetch/jobs'),
+  getFetchJobResults: (jobId, params) => request.get(`/fetch/jobs/${jobId}`, { params }),
+
+  // --- 定时任务 ---
+
+  getSchedules: () => request.get('/schedules'),
+  createSchedule: (data) => request.post('/schedules', data),
+  updateSchedule: (id, data) => request.put(`/schedules/${id}`, data),
+  deleteSchedule: (id) => request.delete(`/schedules/${id}`),
+  runSchedule: (id) => request.post(`/schedules/${id}/run`),
+
   // 获取批量任务状态
   getBatchStatus: () => request.get('/music/batch-status'),
   
+  // --- MusicBrainz 匹配 ---
+
+  getMusicBrainzCandidates: (id) => request.get(`/music/${id}/musicbrainz`),
+  applyMusicBrainzCandidate: (id, data) => request.post(`/music/${id}/musicbrainz/apply`, data),
+  batchMusicBrainzLookup: (data = {}) => request.post('/musicbrainz/batch', data),
+  getMusicBrainzMatches: (params = {}) => request.get('/musicbrainz/matches', { params }),
+  rejectMusicBrainzMatch: (id) => request.post(`/musicbrainz/matches/${id}/reject`),
+
+  // --- 声学指纹 ---
+
+  getFingerprint: (id) => request.get(`/music/${id}/fingerprint`),
+  fingerprintMusic: (id, data = {}) => request.post(`/music/${id}/fingerprint`, data),
+  batchFingerprint: (data = {}) => request.post('/fingerprint/batch', data),
+
+  // --- 重复曲目 ---
+
+  getDuplicates: (params = {}) => request.get('/duplicates', { params }),
+  resolveDuplicates: (data) => request.post('/duplicates/resolve', data),
+
+  // --- 标签修改记录 ---
+
+  getMusicHistory: (id, params = {}) => request.get(`/music/${id}/history`, { params }),
+  getTagHistory: (params = {}) => request.get('/history', { params }),
+  revertTagChange: (id, force = false) => request.post(`/history/${id}/revert`, { force }),
+  revertTagBatch: (batchId, force = false) => request.post(`/history/batches/${batchId}/revert`, { force }),
+
+  // --- 标签规范化 ---
+
+  getNormalizeRules: () => request.get('/normalize/rules'),
+  normalizeTags: (data) => request.post('/normalize', data),
+
+  // --- 从路径提取标签 ---
+
+  testPathPatterns: (data) => request.post('/path-patterns/test', data),
+  fillTagsFromPath: (data) => request.post('/music/fill-from-path', data),
+
+  // --- 按模板重命名 ---
+
+  previewRename: (data) => request.post('/rename/preview', data),
+  applyRename: (data) => request.post('/rename/apply', data),
+  getRenameBatches: () => request.get('/rename/batches'),
+  getRenameBatch: (batchId) => request.get(`/rename/batches/${batchId}`),
+  undoRename: (batchId) => request.post(`/rename/batches/${batchId}/undo`),
+
+  // --- 专辑一致性 ---
+  checkAlbums: (params = {}) => request.get('/albums/check', { params }),
+  getAlbumTracks: (params = {}) => request.get('/albums/tracks', { params }),
+  fixAlbum: (data) => request.post('/albums/fix', data),
+  editAlbum: (data) => request.put('/albums', data),
+
+  // --- 音乐库健康报告 ---
+  getHealthReport: () => request.get('/library/health'),
+  getHealthTracks: (params = {}) => request.get('/library/health/tracks', { params }),
+  getOrphanedFiles: () => request.get('/library/health/orphans'),
+
+  // --- MP3 完整性检查 ---
+  getIntegrity: (params = {}) => request.get('/integrity', { params }),
+  batchValidate: (data = {}) => request.post('/integrity/validate', data),
+  validateMusic: (id) => request.post(`/music/${id}/validate`),
+
+  // --- 响度分析 ---
+  analyzeLoudness: (data = {}) => request.post('/loudness/analyze', data),
+
   // --- WebDAV 配置 ---
   
   getWebDAVConfig: () => request.get('/webdav/config'),
@@ -8,6 +8,7 @@ export const usePlayerStore = defineStore('player', () => {
   const currentTrackIndex = ref(-1)
   const isPlaying = ref(false)
   const playMode = ref('order')
+  const replayGainMode = ref('track') // track | album | off
   const audio = new Audio()
 
   // ✅ 计算属性：安全地获取当前歌曲对象
@@ -47,6 +48,24 @@ export const usePlayerStore = defineStore('player', () => {
 
 
 
+  // ReplayGain：按曲目或专辑增益换算音量，HTMLAudioElement 只能衰减，正增益截断为 1
+  const replayGainVolume = (track) => {
+    if (!track || replayGainMode.value === 'off') return 1
+    const album = replayGainMode.value === 'album'
+    const gain = album ? (track.replaygain_album_gain ?? track.replaygain_track_gain) : track.replaygain_track_gain
+    const peak = album ? (track.replaygain_album_peak ?? track.replaygain_track_peak) : track.replaygain_track_peak
+    if (gain == null) return 1
+    let scale = Math.pow(10, gain / 20)
+    if (peak > 0) scale = Math.min(scale, 1 / peak) // 防止削波
+    return Math.min(1, scale)
+  }
+
+  const toggleReplayGain = () => {
+    const modes = ['track', 'album', 'off']
+    replayGainMode.value = modes[(modes.indexOf(replayGainMode.value) + 1) % modes.length]
+    audio.volume = replayGainVolume(currentTrack.value)
+  }
+
   // ✅ 核心修复：播放歌曲
   const playTrack = (track, list = null) => {
     console.log('🎵 准备播放:', track.title || track.file_name, 'ID:', track.id)
@@ -86,6 +105,7 @@ export const usePlayerStore = defineStore('player', () => {
       console.log('🔊 实际播放对象:', targetTrack.title)
       
       audio.src = targetTrack.playUrl
+      audio.volume = replayGainVolume(targetTrack)
       audio.load() // 必须调用 load 重新加载
       
       audio.play().then(() => {
@@ -199,6 +219,8 @@ export const usePlayerStore = defineStore('player', () => {
     isPlaying,
     audio,
     playMode,
+    replayGainMode,
+    toggleReplayGain,
     addTrackToQueue,
     toggleMode, 
     playAtIndex, 
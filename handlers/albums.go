@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 专辑一致性问题类型
+const (
+	AlbumIssueInconsistent   = "inconsistent_field"     // 共享字段在曲目间取值不同
+	AlbumIssueMissingTrack   = "missing_track_number"   // 部分曲目没有音轨号
+	AlbumIssueDuplicateTrack = "duplicate_track_number" // 同一碟中音轨号重复
+	AlbumIssueTrackGap       = "track_gap"              // 音轨号不连续
+	AlbumIssueDiscGap        = "disc_gap"               // 碟号不连续或部分缺失
+)
+
+// albumConsistentFields 同一专辑内应当一致的字段
+var albumConsistentFields = []string{"album_artist", "year", "genre", "label", "release_type", "mb_release_id"}
+
+// albumSharedFields 专辑编辑接口允许修改的字段
+var albumSharedFields = map[string]bool{
+	"album": true, "album_artist": true, "album_sort": true, "album_artist_sort": true,
+	"year": true, "original_year": true, "genre": true, "label": true, "catalog_number": true,
+	"release_type": true, "mb_release_id": true, "mb_release_group_id": true, "mb_album_artist_id": true,
+}
+
+// AlbumIssue 一个一致性问题，Type 同时是 FixAlbum 使用的修复类型
+type AlbumIssue struct {
+	Type    string         `json:"type"`
+	Field   string         `json:"field,omitempty"`
+	Values  map[string]int `json:"values,omitempty"` // 各取值出现的次数
+	Missing []int          `json:"missing,omitempty"`
+	Disc    int            `json:"disc,omitempty"` // 音轨号缺口所在的碟号，0 表示未设置碟号
+	IDs     []uint         `json:"ids,omitempty"`
+	Message string         `json:"message"`
+}
+
+// AlbumReport 一个专辑 (目录 + 专辑名) 的检查结果
+type AlbumReport struct {
+	Folder     string       `json:"folder"`
+	Album      string       `json:"album"`
+	TrackCount int          `json:"track_count"`
+	Discs      []int        `json:"discs"`
+	Issues     []AlbumIssue `json:"issues"`
+}
+
+// AlbumRef 通过目录和专辑名定位专辑
+type AlbumRef struct {
+	Folder string `json:"folder"`
+	Album  string `json:"album"`
+}
+
+// AlbumFixRequest 一键修复，Value 为空时不一致字段取多数值
+type AlbumFixRequest struct {
+	AlbumRef
+	Type      string `json:"type"`
+	Field     string `json:"field"`
+	Value     string `json:"value"`
+	DryRun    bool   `json:"dry_run"`
+	WriteBack bool   `json:"write_back"`
+}
+
+// AlbumEditRequest 把共享字段应用到专辑的全部曲目
+type AlbumEditRequest struct {
+	AlbumRef
+	Fields    map[string]string `json:"fields"` // 空字符串表示清空
+	DryRun    bool              `json:"dry_run"`
+	WriteBack bool              `json:"write_back"`
+}
+
+// AlbumQuery 专辑检查的筛选条件
+type AlbumQuery struct {
+	Folder     string `form:"folder"`
+	Album      string `form:"album"`
+	IssuesOnly bool   `form:"issues_only"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// groupAlbums 按所在目录和专辑名分组，返回排序后的分组键
+func groupAlbums(musicList []models.Music) []AlbumRef {
+	seen := make(map[AlbumRef]bool)
+	var refs []AlbumRef
+	for _, m := range musicList {
+		ref := AlbumRef{Folder: path.Dir(m.FilePath), Album: m.Album}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Folder != refs[j].Folder {
+			return refs[i].Folder < refs[j].Folder
+		}
+		return refs[i].Album < refs[j].Album
+	})
+	return refs
+}
+
+// majorityValue 出现次数最多的非空值，次数相同时取字典序最小的
+func majorityValue(values map[string]int) string {
+	best, count := "", 0
+	for v, n := range values {
+		if v == "" {
+			continue
+		}
+		if n > count || (n == count && v < best) {
+			best, count = v, n
+		}
+	}
+	return best
+}
+
+// checkAlbum 检查一个专辑的曲目
+func checkAlbum(ref AlbumRef, tracks []models.Music) AlbumReport {
+	report := AlbumReport{Folder: ref.Folder, Album: ref.Album, TrackCount: len(tracks), Issues: []AlbumIssue{}}
+
+	for _, field := range albumConsistentFields {
+		values := make(map[string]int)
+		for i := range tracks {
+			v, _ := tracks[i].TagValue(field)
+			values[v]++
+		}
+		if len(values) > 1 {
+			report.Issues = append(report.Issues, AlbumIssue{
+				Type:    AlbumIssueInconsistent,
+				Field:   field,
+				Values:  values,
+				Message: fmt.Sprintf("%s has %d different values", field, len(values)),
+			})
+		}
+	}
+
+	// 碟号：0 视为未设置
+	byDisc := make(map[int][]models.Music)
+	var missingDisc []uint
+	for _, t := range tracks {
+		if t.DiscNumber == 0 {
+			missingDisc = append(missingDisc, t.ID)
+		}
+		byDisc[t.DiscNumber] = append(byDisc[t.DiscNumber], t)
+	}
+	for d := range byDisc {
+		report.Discs = append(report.Discs, d)
+	}
+	sort.Ints(report.Discs)
+
+	var discs []int
+	for _, d := range report.Discs {
+		if d > 0 {
+			discs = append(discs, d)
+		}
+	}
+	if len(discs) > 0 {
+		var missing []int
+		for d, i := 1, 0; d <= discs[len(discs)-1]; d++ {
+			if i < len(discs) && discs[i] == d {
+				i++
+				continue
+			}
+			missing = append(missing, d)
+		}
+		if len(missing) > 0 || len(missingDisc) > 0 {
+			report.Issues = append(report.Issues, AlbumIssue{
+				Type:    AlbumIssueDiscGap,
+				Field:   "disc_number",
+				Missing: missing,
+				IDs:     missingDisc,
+				Message: fmt.Sprintf("%d missing discs, %d tracks without disc number", len(missing), len(missingDisc)),
+			})
+		}
+	}
+
+	var noTrack, duplicate []uint
+	var gaps []AlbumIssue
+	for _, d := range report.Discs {
+		seen := make(map[int]bool)
+		maxTrack := 0
+		for _, t := range byDisc[d] {
+			switch {
+			case t.TrackNumber == 0:
+				noTrack = append(noTrack, t.ID)
+			case seen[t.TrackNumber]:
+				duplicate = append(duplicate, t.ID)
+			default:
+				seen[t.TrackNumber] = true
+			}
+			if t.TrackNumber > maxTrack {
+				maxTrack = t.TrackNumber
+			}
+		}
+		var missing []int
+		for n := 1; n <= maxTrack; n++ {
+			if !seen[n] {
+				missing = append(missing, n)
+			}
+		}
+		if len(missing) > 0 {
+			message := fmt.Sprintf("%d track numbers missing", len(missing))
+			if d > 0 {
+				message = fmt.Sprintf("%d track numbers missing on disc %d", len(missing), d)
+			}
+			gaps = append(gaps, AlbumIssue{
+				Type:    AlbumIssueTrackGap,
+				Field:   "track_number",
+				Missing: missing,
+				Disc:    d,
+				Message: message,
+			})
+		}
+	}
+	if len(noTrack) > 0 {
+		report.Issues = append(report.Issues, AlbumIssue{
+			Type:    AlbumIssueMissingTrack,
+			Field:   "track_number",
+			IDs:     noTrack,
+			Message: fmt.Sprintf("%d tracks without track number", len(noTrack)),
+		})
+	}
+	if len(duplicate) > 0 {
+		report.Issues = append(report.Issues, AlbumIssue{
+			Type:    AlbumIssueDuplicateTrack,
+			Field:   "track_number",
+			IDs:     duplicate,
+			Message: fmt.Sprintf("%d tracks share a track number", len(duplicate)),
+		})
+	}
+	report.Issues = append(report.Issues, gaps...)
+	return report
+}
+
+// loadAlbumTracks 加载目录和专辑名都匹配且扫描成功的曲目，按文件路径排序
+func (h *MusicHandler) loadAlbumTracks(ref AlbumRef) ([]models.Music, error) {
+	var candidates []models.Music
+	err := h.db.Where("scan_status = ? AND album = ?", "success", ref.Album).Order("file_path").Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	var tracks []models.Music
+	for _, m := range candidates {
+		if path.Dir(m.FilePath) == ref.Folder {
+			tracks = append(tracks, m)
+		}
+	}
+	return tracks, nil
+}
+
+// CheckAlbums 按目录和专辑分组检查一致性
+func (h *MusicHandler) CheckAlbums(c *gin.Context) {
+	var q AlbumQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	query := h.db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	if q.Album != "" {
+		query = query.Where("album = ?", q.Album)
+	}
+	if q.Folder != "" {
+		query = query.Where("file_path LIKE ?", strings.TrimSuffix(q.Folder, "/")+"/%")
+	}
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	groups := make(map[AlbumRef][]models.Music)
+	for _, m := range musicList {
+		ref := AlbumRef{Folder: path.Dir(m.FilePath), Album: m.Album}
+		groups[ref] = append(groups[ref], m)
+	}
+
+	reports := make([]AlbumReport, 0)
+	withIssues := 0
+	for _, ref := range groupAlbums(musicList) {
+		report := checkAlbum(ref, groups[ref])
+		if len(report.Issues) > 0 {
+			withIssues++
+		} else if q.IssuesOnly {
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	total := len(reports)
+	start := (q.Page - 1) * q.PageSize
+	if start > total {
+		start = total
+	}
+	end := start + q.PageSize
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    reports[start:end],
+		"total":   total,
+		"summary": gin.H{
+			"albums":      len(groups),
+			"with_issues": withIssues,
+		},
+	})
+}
+
+// FixAlbum 对专辑执行一键修复
+func (h *MusicHandler) FixAlbum(c *gin.Context) {
+	var req AlbumFixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tracks, err := h.loadAlbumTracks(req.AlbumRef)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+	if len(tracks) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Album not found",
+		})
+		return
+	}
+
+	var edit func(music *models.Music) error
+	switch req.Type {
+	case AlbumIssueInconsistent:
+		if !albumSharedFields[req.Field] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Field is not an album field: " + req.Field,
+			})
+			return
+		}
+		value := req.Value
+		if value == "" {
+			values := make(map[string]int)
+			for i := range tracks {
+				v, _ := tracks[i].TagValue(req.Field)
+				values[v]++
+			}
+			value = majorityValue(values)
+		}
+		edit = func(music *models.Music) error {
+			return music.SetTagValue(req.Field, value)
+		}
+
+	case AlbumIssueMissingTrack, AlbumIssueDuplicateTrack, AlbumIssueTrackGap:
+		// 按文件顺序在每张碟内重新编号
+		numbers := numberByFileOrder(tracks, &EditOperation{Start: 1, RestartBy: "disc"})
+		edit = func(music *models.Music) error {
+			music.TrackNumber = numbers[music.ID]
+			return nil
+		}
+
+	case AlbumIssueDiscGap:
+		// 现有碟号按顺序压缩为 1..n，未设置的视为第 1 张
+		var discs []int
+		seen := make(map[int]bool)
+		for _, t := range tracks {
+			d := t.DiscNumber
+			if d == 0 {
+				d = 1
+			}
+			if !seen[d] {
+				seen[d] = true
+				discs = append(discs, d)
+			}
+		}
+		sort.Ints(discs)
+		mapping := make(map[int]int, len(discs))
+		for i, d := range discs {
+			mapping[d] = i + 1
+		}
+		edit = func(music *models.Music) error {
+			d := music.DiscNumber
+			if d == 0 {
+				d = 1
+			}
+			music.DiscNumber = mapping[d]
+			return nil
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Unknown fix type: " + req.Type,
+		})
+		return
+	}
+
+	originals, changed, results, err := collectEdits(tracks, edit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	h.commitEdits(c, len(tracks), originals, changed, results, req.DryRun, req.WriteBack, nil)
+}
+
+// EditAlbum 把共享字段应用到专辑的全部曲目
+func (h *MusicHandler) EditAlbum(c *gin.Context) {
+	var req AlbumEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if len(req.Fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "No fields provided",
+		})
+		return
+	}
+
+	// 按字段名排序，保证修改记录顺序稳定
+	fields := make([]string, 0, len(req.Fields))
+	for field := range req.Fields {
+		if !albumSharedFields[field] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Field is not an album field: " + field,
+			})
+			return
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	tracks, err := h.loadAlbumTracks(req.AlbumRef)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+	if len(tracks) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Album not found",
+		})
+		return
+	}
+
+	originals, changed, results, err := collectEdits(tracks, func(music *models.Music) error {
+		for _, field := range fields {
+			if err := music.SetTagValue(field, req.Fields[field]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	h.commitEdits(c, len(tracks), originals, changed, results, req.DryRun, req.WriteBack, nil)
+}
+
+// GetAlbumTracks 专辑的曲目列表
+func (h *MusicHandler) GetAlbumTracks(c *gin.Context) {
+	ref := AlbumRef{Folder: c.Query("folder"), Album: c.Query("album")}
+	tracks, err := h.loadAlbumTracks(ref)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	responses := make([]models.MusicResponse, len(tracks))
+	for i := range tracks {
+		responses[i] = tracks[i].ToResponse()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"folder": ref.Folder,
+			"album":  ref.Album,
+			"report": checkAlbum(ref, tracks),
+			"tracks": responses,
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"go-music-tag/models"
+	"reflect"
+	"testing"
+)
+
+func TestCheckAlbumTrackGapsPerDisc(t *testing.T) {
+	tracks := []models.Music{
+		{ID: 1, DiscNumber: 1, TrackNumber: 1},
+		{ID: 2, DiscNumber: 1, TrackNumber: 3},
+		{ID: 3, DiscNumber: 2, TrackNumber: 1},
+		{ID: 4, DiscNumber: 2, TrackNumber: 2},
+		{ID: 5, DiscNumber: 2, TrackNumber: 5},
+	}
+	report := checkAlbum(AlbumRef{Folder: "/music/a", Album: "A"}, tracks)
+
+	var gaps []AlbumIssue
+	for _, issue := range report.Issues {
+		if issue.Type == AlbumIssueTrackGap {
+			gaps = append(gaps, issue)
+		}
+	}
+	if len(gaps) != 2 {
+		t.Fatalf("track gap issues = %+v, want one per disc", gaps)
+	}
+	if gaps[0].Disc != 1 || !reflect.DeepEqual(gaps[0].Missing, []int{2}) {
+		t.Errorf("disc 1 gap = %+v", gaps[0])
+	}
+	if gaps[1].Disc != 2 || !reflect.DeepEqual(gaps[1].Missing, []int{3, 4}) {
+		t.Errorf("disc 2 gap = %+v", gaps[1])
+	}
+}
+
+func TestLoadAlbumTracksSkipsFailedScans(t *testing.T) {
+	h := newTestHandler(t)
+	for _, m := range []models.Music{
+		{FilePath: "/music/a/01.mp3", Album: "A", ScanStatus: "success"},
+		{FilePath: "/music/a/02.mp3", Album: "A", ScanStatus: "failed"},
+		{FilePath: "/music/b/01.mp3", Album: "A", ScanStatus: "success"},
+	} {
+		if err := h.db.Create(&m).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	tracks, err := h.loadAlbumTracks(AlbumRef{Folder: "/music/a", Album: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || tracks[0].FilePath != "/music/a/01.mp3" {
+		t.Errorf("tracks = %+v", tracks)
+	}
+}
@@ -0,0 +1,639 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"go-music-tag/artwork"
+	"go-music-tag/config"
+	"go-music-tag/fetcher"
+	"go-music-tag/models"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// artworkGCGrace 没有数据库记录的图片文件至少保留这么久，
+// 抓取时先写文件再写记录，避免回收正在保存的图片
+const artworkGCGrace = time.Hour
+
+// artworkUploadLimit 上传封面的大小上限
+const artworkUploadLimit = 20 << 20
+
+// ArtworkGCRequest 回收未引用的封面，dry_run 时只列出
+type ArtworkGCRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// ArtworkGCResult 回收结果，StrayFiles 为没有数据库记录的文件哈希
+type ArtworkGCResult struct {
+	Removed      []models.Artwork `json:"removed"`
+	StrayFiles   []string         `json:"stray_files"`
+	AlbumEntries int              `json:"album_entries"` // 已没有曲目的专辑封面记录
+	Bytes        int64            `json:"bytes"`
+	DryRun       bool             `json:"dry_run"`
+}
+
+// artworkStore 封面的内容寻址存储
+func artworkStore() *artwork.Store {
+	return artwork.NewStore(fetcher.CoversDir(config.GetConfig().Fetcher.DataDir))
+}
+
+// saveArtwork 保存图片并返回对应记录，相同内容只保留最早的一条
+func saveArtwork(db *gorm.DB, data []byte, source, sourceURL string) (*models.Artwork, error) {
+	img, err := artworkStore().Put(data)
+	if err != nil {
+		return nil, err
+	}
+	art := models.Artwork{
+		Hash:      img.Hash,
+		MIME:      img.MIME,
+		Width:     img.Width,
+		Height:    img.Height,
+		Size:      img.Size,
+		Source:    source,
+		SourceURL: sourceURL,
+	}
+	if err := db.Where(models.Artwork{Hash: img.Hash}).Attrs(art).FirstOrCreate(&art).Error; err != nil {
+		return nil, err
+	}
+	return &art, nil
+}
+
+// setTrackArtwork 让曲目引用指定封面，同步 has_cover 与 cover_mime
+func setTrackArtwork(tx *gorm.DB, music *models.Music, art *models.Artwork, source, user, batchID string) error {
+	before := *music
+	id := art.ID
+	music.ArtworkID = &id
+	music.HasCover = true
+	music.CoverMIME = art.MIME
+	_, err := saveMusicWithHistory(tx, &before, music, source, user, batchID)
+	return err
+}
+
+// ensureAlbumArtwork 专辑还没有封面时使用指定封面
+func ensureAlbumArtwork(tx *gorm.DB, music *models.Music, artworkID uint) error {
+	if music.Album == "" {
+		return nil
+	}
+	entry := models.AlbumArtwork{Folder: path.Dir(music.FilePath), Album: music.Album}
+	return tx.Where(entry).Attrs(models.AlbumArtwork{ArtworkID: artworkID}).FirstOrCreate(&entry).Error
+}
+
+// importCachedCover 把旧的按艺术家+专辑命名的缓存封面导入存储，不修改曲目。
+// 修改艺术家或专辑前必须先导入，否则封面会与曲目失去关联
+func importCachedCover(tx *gorm.DB, music *models.Music) (*models.Artwork, error) {
+	if music.ArtworkID != nil {
+		return nil, nil
+	}
+	f := getFetcher()
+	data, err := os.ReadFile(f.GetLocalCoverPath(music.Artist, music.Album))
+	if err != nil {
+		return nil, nil
+	}
+	art, err := saveArtwork(tx, data, models.ArtworkSourceCache, "")
+	if errors.Is(err, artwork.ErrNotImage) {
+		log.Printf("[Artwork] ⚠️ Skip invalid cached cover for %s - %s: %v", music.Artist, music.Album, err)
+		return nil, nil
+	}
+	return art, err
+}
+
+// linkFetchedCover 保存获取到的封面并关联到曲目，专辑没有封面时一并设置
+func linkFetchedCover(db *gorm.DB, music *models.Music, data []byte, sourceURL, user, batchID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		art, err := saveArtwork(tx, data, models.ArtworkSourceFetch, sourceURL)
+		if err != nil {
+			return err
+		}
+		if err := setTrackArtwork(tx, music, art, models.TagSourceFetch, user, batchID); err != nil {
+			return err
+		}
+		return ensureAlbumArtwork(tx, music, art.ID)
+	})
+}
+
+// getEmbeddedPicture 读取音频文件内嵌的封面
+func (h *MusicHandler) getEmbeddedPicture(music models.Music) []byte {
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		return nil
+	}
+	data, err := client.GetFile(music.FilePath)
+	if err != nil {
+		return nil
+	}
+	md, err := tag.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	if pic := md.Picture(); pic != nil {
+		return pic.Data
+	}
+	return nil
+}
+
+// consumeLegacyCover 移除封面后处理旧的缓存文件，避免下次读取封面时又被导入并关联。
+// keepForAlbum 时先把它设为专辑封面 (专辑还没有封面时)，与只移除曲目封面时显示专辑封面一致
+func consumeLegacyCover(db *gorm.DB, music *models.Music, keepForAlbum bool) error {
+	cachePath := getFetcher().GetLocalCoverPath(music.Artist, music.Album)
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil
+	}
+	if keepForAlbum && music.Album != "" {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			art, err := saveArtwork(tx, data, models.ArtworkSourceCache, "")
+			if err != nil {
+				return err
+			}
+			return ensureAlbumArtwork(tx, music, art.ID)
+		})
+		if err != nil && !errors.Is(err, artwork.ErrNotImage) {
+			return err
+		}
+	}
+	if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// lookupArtwork 曲目封面或专辑封面，只读取已入库的记录
+func (h *MusicHandler) lookupArtwork(music models.Music) *models.Artwork {
+	var art models.Artwork
+	if music.ArtworkID != nil && h.db.First(&art, *music.ArtworkID).Error == nil {
+		return &art
+	}
+	if music.Album != "" {
+		var entry models.AlbumArtwork
+		if h.db.Where("folder = ? AND album = ?", path.Dir(music.FilePath), music.Album).First(&entry).Error == nil &&
+			h.db.First(&art, entry.ArtworkID).Error == nil {
+			return &art
+		}
+	}
+	return nil
+}
+
+// resolveArtwork 依次使用曲目封面、专辑封面、旧的缓存文件和内嵌封面，
+// 后两者在第一次读取时导入存储并关联到曲目；没有封面时返回 nil
+func (h *MusicHandler) resolveArtwork(music models.Music) *models.Artwork {
+	if art := h.lookupArtwork(music); art != nil {
+		return art
+	}
+
+	link := func(found *models.Artwork) *models.Artwork {
+		if err := setTrackArtwork(h.db, &music, found, models.TagSourceFetch, "", newBatchID()); err != nil {
+			log.Printf("[Artwork] ⚠️ Failed to link artwork to %s: %v", music.FilePath, err)
+		}
+		return found
+	}
+
+	music.ArtworkID = nil
+	if found, err := importCachedCover(h.db, &music); err == nil && found != nil {
+		return link(found)
+	}
+	if music.HasCover {
+		if data := h.getEmbeddedPicture(music); len(data) > 0 {
+			if found, err := saveArtwork(h.db, data, models.ArtworkSourceEmbedded, ""); err == nil {
+				return link(found)
+			}
+		}
+	}
+	return nil
+}
+
+// serveArtwork 输出图片并以内容哈希作为 ETag
+func serveArtwork(c *gin.Context, art *models.Artwork, cacheControl string) {
+	data, err := artworkStore().Read(art.Hash)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	etag := `"` + art.Hash + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, art.MIME, data)
+}
+
+// GetArtwork 按 ID 返回封面图片，内容不会变化因此可以永久缓存
+func (h *MusicHandler) GetArtwork(c *gin.Context) {
+	var art models.Artwork
+	if err := h.db.First(&art, c.Param("id")).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	serveArtwork(c, &art, "public, max-age=31536000, immutable")
+}
+
+// collectArtworkGarbage 找出没有曲目或专辑引用的封面、没有曲目的专辑封面记录以及没有记录的文件，dryRun 为 false 时删除
+func collectArtworkGarbage(db *gorm.DB, dryRun bool) (*ArtworkGCResult, error) {
+	result := &ArtworkGCResult{Removed: []models.Artwork{}, StrayFiles: []string{}, DryRun: dryRun}
+
+	// 专辑分组与专辑检查一致：目录 + 专辑名
+	var tracks []models.Music
+	if err := db.Select("file_path", "album").Where("album <> ''").Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	albums := make(map[AlbumRef]bool)
+	for _, t := range tracks {
+		albums[AlbumRef{Folder: path.Dir(t.FilePath), Album: t.Album}] = true
+	}
+	var entries []models.AlbumArtwork
+	if err := db.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	var staleEntries []uint
+	for _, e := range entries {
+		if !albums[AlbumRef{Folder: e.Folder, Album: e.Album}] {
+			staleEntries = append(staleEntries, e.ID)
+		}
+	}
+	result.AlbumEntries = len(staleEntries)
+
+	trackRefs := db.Model(&models.Music{}).Where("artwork_id IS NOT NULL").Select("artwork_id")
+	albumRefs := db.Model(&models.AlbumArtwork{}).Select("artwork_id")
+	if len(staleEntries) > 0 {
+		albumRefs = albumRefs.Where("id NOT IN ?", staleEntries)
+	}
+	if err := db.Where("id NOT IN (?) AND id NOT IN (?)", trackRefs, albumRefs).Find(&result.Removed).Error; err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	if err := db.Model(&models.Artwork{}).Pluck("hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		known[hash] = true
+	}
+	store := artworkStore()
+	files, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-artworkGCGrace).Unix()
+	for _, f := range files {
+		if !known[f.Hash] && f.ModTime < cutoff {
+			result.StrayFiles = append(result.StrayFiles, f.Hash)
+			result.Bytes += f.Size
+		}
+	}
+	for _, art := range result.Removed {
+		result.Bytes += art.Size
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if len(staleEntries) > 0 {
+			if err := tx.Delete(&models.AlbumArtwork{}, staleEntries).Error; err != nil {
+				return err
+			}
+		}
+		for _, art := range result.Removed {
+			if err := tx.Delete(&models.Artwork{}, art.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, art := range result.Removed {
+		if err := store.Remove(art.Hash); err != nil {
+			log.Printf("[Artwork] ⚠️ Failed to remove %s: %v", art.Hash, err)
+		}
+	}
+	for _, hash := range result.StrayFiles {
+		if err := store.Remove(hash); err != nil {
+			log.Printf("[Artwork] ⚠️ Failed to remove %s: %v", hash, err)
+		}
+	}
+	return result, nil
+}
+
+// CollectArtworkGarbage 删除不再被任何曲目或专辑引用的封面图片
+func (h *MusicHandler) CollectArtworkGarbage(c *gin.Context) {
+	var req ArtworkGCRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	result, err := collectArtworkGarbage(h.db, req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to collect artwork: " + err.Error(),
+		})
+		return
+	}
+	log.Printf("[Artwork] GC: %d unused, %d stray files, %d album entries, %d bytes (dry_run=%v)",
+		len(result.Removed), len(result.StrayFiles), result.AlbumEntries, result.Bytes, req.DryRun)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// 封面作用范围
+const (
+	coverScopeTrack = "track"
+	coverScopeAlbum = "album"
+)
+
+// SetCoverRequest 以 JSON 指定封面地址 (如候选列表中的 url)，上传文件时使用 multipart 的 file、url、scope 字段
+type SetCoverRequest struct {
+	URL   string `json:"url" form:"url"`
+	Scope string `json:"scope" form:"scope"` // track (默认) | album
+}
+
+// coverScope 校验作用范围，专辑范围要求曲目有专辑名
+func coverScope(c *gin.Context, scope string, music *models.Music) (string, bool) {
+	if scope == "" {
+		scope = coverScopeTrack
+	}
+	if scope != coverScopeTrack && scope != coverScopeAlbum {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "scope must be track or album",
+		})
+		return "", false
+	}
+	if scope == coverScopeAlbum && music.Album == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Track has no album",
+		})
+		return "", false
+	}
+	return scope, true
+}
+
+// applyCover 把封面设置到曲目或整张专辑，art 为 nil 时移除；返回受影响的曲目 ID
+func (h *MusicHandler) applyCover(music *models.Music, art *models.Artwork, scope, user string) ([]uint, error) {
+	tracks := []models.Music{*music}
+	if scope == coverScopeAlbum {
+		var err error
+		if tracks, err = h.loadAlbumTracks(AlbumRef{Folder: path.Dir(music.FilePath), Album: music.Album}); err != nil {
+			return nil, err
+		}
+	}
+
+	batchID := newBatchID()
+	affected := make([]uint, 0, len(tracks))
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if scope == coverScopeAlbum {
+			ref := models.AlbumArtwork{Folder: path.Dir(music.FilePath), Album: music.Album}
+			if art == nil {
+				if err := tx.Where(ref).Delete(&models.AlbumArtwork{}).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Where(ref).Assign(models.AlbumArtwork{ArtworkID: art.ID}).FirstOrCreate(&ref).Error; err != nil {
+				return err
+			}
+		}
+		for i := range tracks {
+			t := &tracks[i]
+			if art != nil {
+				if err := setTrackArtwork(tx, t, art, models.TagSourceManual, user, batchID); err != nil {
+					return err
+				}
+			} else {
+				before := *t
+				t.ArtworkID = nil
+				t.HasCover = false
+				t.CoverMIME = ""
+				if _, err := saveMusicWithHistory(tx, &before, t, models.TagSourceManual, user, batchID); err != nil {
+					return err
+				}
+			}
+			affected = append(affected, t.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if art == nil {
+		for i := range tracks {
+			if err := consumeLegacyCover(h.db, &tracks[i], scope == coverScopeTrack); err != nil {
+				log.Printf("[Artwork] ⚠️ Failed to remove cached cover for %s: %v", tracks[i].FilePath, err)
+			}
+		}
+	}
+	return affected, nil
+}
+
+// SetCover 上传封面文件或指定图片地址，scope=album 时应用到同目录同专辑的全部曲目
+func (h *MusicHandler) SetCover(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var req SetCoverRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	scope, ok := coverScope(c, req.Scope, &music)
+	if !ok {
+		return
+	}
+
+	var data []byte
+	source, sourceURL := models.ArtworkSourceUpload, ""
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err == nil {
+			data, err = io.ReadAll(io.LimitReader(f, artworkUploadLimit+1))
+			f.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Failed to read upload: " + err.Error(),
+			})
+			return
+		}
+		if len(data) > artworkUploadLimit {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"code":    413,
+				"message": "Image is too large",
+			})
+			return
+		}
+	} else if req.URL != "" {
+		f := getFetcher()
+		if data, err = f.DownloadImage(req.URL); err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, fetcher.ErrInvalidImageURL) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{
+				"code":    status,
+				"message": "Failed to download image: " + err.Error(),
+			})
+			return
+		}
+		source, sourceURL = models.ArtworkSourceFetch, req.URL
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Provide a file or url",
+		})
+		return
+	}
+
+	art, err := saveArtwork(h.db, data, source, sourceURL)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, artwork.ErrNotImage) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	affected, err := h.applyCover(&music, art, scope, requestUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to set cover: " + err.Error(),
+		})
+		return
+	}
+	log.Printf("[Artwork] Cover %s set on %d track(s) (scope=%s)", art.Hash[:12], len(affected), scope)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Cover updated",
+		"data": gin.H{
+			"artwork":   art,
+			"scope":     scope,
+			"music_ids": affected,
+		},
+	})
+}
+
+// GetCoverCandidates 从各来源搜索封面候选，返回预览地址与实际分辨率，选中后以 url 调用 SetCover
+func (h *MusicHandler) GetCoverCandidates(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	artist := c.DefaultQuery("artist", music.AlbumArtist)
+	if artist == "" {
+		artist = music.Artist
+	}
+	album := c.DefaultQuery("album", music.Album)
+	if album == "" {
+		album = music.Title
+	}
+	limit := getInt(c.DefaultQuery("limit", "8"))
+	if limit < 1 || limit > 30 {
+		limit = 8
+	}
+
+	f := getFetcher()
+	candidates, err := f.SearchCoverCandidates(artist, album, music.MBReleaseID, limit)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var currentHash string
+	if art := h.lookupArtwork(music); art != nil {
+		currentHash = art.Hash
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"artist":       artist,
+			"album":        album,
+			"current_hash": currentHash,
+			"candidates":   candidates,
+		},
+	})
+}
+
+// DeleteCover 移除曲目或整张专辑的封面，图片文件由回收任务删除。
+// 只移除曲目封面时，如果专辑有封面曲目仍会显示专辑封面
+func (h *MusicHandler) DeleteCover(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+	scope, ok := coverScope(c, c.Query("scope"), &music)
+	if !ok {
+		return
+	}
+
+	affected, err := h.applyCover(&music, nil, scope, requestUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to remove cover: " + err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{"scope": scope, "music_ids": affected}
+	if scope == coverScopeTrack {
+		h.db.First(&music, music.ID)
+		if fallback := h.lookupArtwork(music); fallback != nil {
+			data["fallback_artwork_id"] = fallback.ID
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Cover removed",
+		"data":    data,
+	})
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 批量编辑操作类型
+const (
+	EditOpKeep    = "keep"    // 保持不变
+	EditOpSet     = "set"     // 设为 Value
+	EditOpClear   = "clear"   // 清空
+	EditOpReplace = "replace" // 正则查找替换
+	EditOpCopy    = "copy"    // 从 From 字段复制
+	EditOpNumber  = "number"  // 按文件顺序编号
+)
+
+// EditOperation 对单个字段的操作
+type EditOperation struct {
+	Field       string `json:"field"`
+	Op          string `json:"op"`
+	Value       string `json:"value"`       // set
+	Pattern     string `json:"pattern"`     // replace，Go 正则语法
+	Replacement string `json:"replacement"` // replace，支持 $1 引用分组
+	From        string `json:"from"`        // copy
+	Start       int    `json:"start"`       // number，起始编号，默认 1
+	RestartBy   string `json:"restart_by"`  // number，"" | disc | folder，按碟号或目录重新编号
+
+	re *regexp.Regexp
+}
+
+// BatchEditRequest 批量编辑请求，操作按顺序作用于每个曲目
+type BatchEditRequest struct {
+	IDs        []uint          `json:"ids"`
+	Album      string          `json:"album"`
+	Artist     string          `json:"artist"`
+	Operations []EditOperation `json:"operations"`
+	DryRun     bool            `json:"dry_run"`
+	WriteBack  bool            `json:"write_back"` // 同时写回源文件标签 (仅 MP3)
+}
+
+// BatchEditResult 单个曲目的修改
+type BatchEditResult struct {
+	MusicID    uint                    `json:"music_id"`
+	FilePath   string                  `json:"file_path"`
+	Changes    []models.TagFieldChange `json:"changes"`
+	WriteError string                  `json:"write_error,omitempty"`
+	Encodings  []EncodingRepair        `json:"encodings,omitempty"` // 乱码修复时检测到的原始编码
+}
+
+// validate 检查操作参数并预编译正则
+func (op *EditOperation) validate() error {
+	if !models.IsTagField(op.Field) {
+		return fmt.Errorf("unknown field: %s", op.Field)
+	}
+	switch op.Op {
+	case EditOpKeep, EditOpSet, EditOpClear:
+	case EditOpReplace:
+		if op.Pattern == "" {
+			return fmt.Errorf("%s: pattern is required for replace", op.Field)
+		}
+		re, err := regexp.Compile(op.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", op.Field, err)
+		}
+		op.re = re
+	case EditOpCopy:
+		if !models.IsTagField(op.From) {
+			return fmt.Errorf("%s: unknown source field: %s", op.Field, op.From)
+		}
+	case EditOpNumber:
+		if op.Field != "track_number" && op.Field != "disc_number" {
+			return fmt.Errorf("number only applies to track_number or disc_number")
+		}
+		if op.Start < 0 {
+			return fmt.Errorf("%s: start must not be negative", op.Field)
+		}
+		if op.Start == 0 {
+			op.Start = 1
+		}
+		if op.RestartBy != "" && op.RestartBy != "disc" && op.RestartBy != "folder" {
+			return fmt.Errorf("restart_by must be disc or folder")
+		}
+	default:
+		return fmt.Errorf("%s: unknown op %q", op.Field, op.Op)
+	}
+	return nil
+}
+
+// numberByFileOrder 按文件路径排序后计算每个曲目的编号
+func numberByFileOrder(musicList []models.Music, op *EditOperation) map[uint]int {
+	sorted := make([]*models.Music, len(musicList))
+	for i := range musicList {
+		sorted[i] = &musicList[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FilePath < sorted[j].FilePath
+	})
+
+	numbers := make(map[uint]int, len(sorted))
+	next := make(map[string]int)
+	for _, m := range sorted {
+		var group string
+		switch op.RestartBy {
+		case "disc":
+			group = strconv.Itoa(m.DiscNumber)
+		case "folder":
+			group = path.Dir(m.FilePath)
+		}
+		if _, ok := next[group]; !ok {
+			next[group] = op.Start
+		}
+		numbers[m.ID] = next[group]
+		next[group]++
+	}
+	return numbers
+}
+
+// applyEditOperations 依次执行操作，copy 读取的是前面操作之后的值
+func applyEditOperations(music *models.Music, ops []EditOperation, numbers []map[uint]int) error {
+	for i, op := range ops {
+		var value string
+		switch op.Op {
+		case EditOpKeep:
+			continue
+		case EditOpSet:
+			value = op.Value
+		case EditOpClear:
+			value = ""
+		case EditOpReplace:
+			current, err := music.TagValue(op.Field)
+			if err != nil {
+				return err
+			}
+			value = op.re.ReplaceAllString(current, op.Replacement)
+		case EditOpCopy:
+			v, err := music.TagValue(op.From)
+			if err != nil {
+				return err
+			}
+			value = v
+		case EditOpNumber:
+			value = strconv.Itoa(numbers[i][music.ID])
+		}
+		if err := music.SetTagValue(op.Field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchEdit 对选中曲目执行字段操作，所有修改在同一事务中提交
+func (h *MusicHandler) BatchEdit(c *gin.Context) {
+	var req BatchEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.IDs) == 0 && req.Album == "" && req.Artist == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "ids, album or artist is required",
+		})
+		return
+	}
+	if len(req.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "No operations provided",
+		})
+		return
+	}
+	for i := range req.Operations {
+		if err := req.Operations[i].validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	query := h.db.Model(&models.Music{})
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ?", req.Artist)
+	}
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	numbers := make([]map[uint]int, len(req.Operations))
+	for i := range req.Operations {
+		if req.Operations[i].Op == EditOpNumber {
+			numbers[i] = numberByFileOrder(musicList, &req.Operations[i])
+		}
+	}
+
+	originals, changed, results, err := collectEdits(musicList, func(music *models.Music) error {
+		return applyEditOperations(music, req.Operations, numbers)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.commitEdits(c, len(musicList), originals, changed, results, req.DryRun, req.WriteBack, nil)
+}
+
+// collectEdits 对每个曲目执行 edit，返回有变化的曲目及其修改前的副本
+func collectEdits(musicList []models.Music, edit func(music *models.Music) error) (originals, changed []models.Music, results []BatchEditResult, err error) {
+	for _, music := range musicList {
+		original := music
+		if err := edit(&music); err != nil {
+			return nil, nil, nil, fmt.Errorf("track %d: %w", music.ID, err)
+		}
+		changes := models.DiffTags(&original, &music)
+		if len(changes) == 0 {
+			continue
+		}
+		results = append(results, BatchEditResult{MusicID: music.ID, FilePath: music.FilePath, Changes: changes})
+		changed = append(changed, music)
+		originals = append(originals, original)
+	}
+	return originals, changed, results, nil
+}
+
+// commitEdits 在同一事务中保存 collectEdits 的结果并写入修改记录，dryRun 时只返回差异。
+// 写回文件在事务提交后进行，失败的文件记录在对应结果的 WriteError 中；
+// 除修改过的字段外，extraFields 中有值的字段也一并写入
+func (h *MusicHandler) commitEdits(c *gin.Context, total int, originals, changed []models.Music, results []BatchEditResult, dryRun, writeBack bool, extraFields []string) {
+	if dryRun || len(changed) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data": gin.H{
+				"dry_run": dryRun,
+				"total":   total,
+				"changed": len(changed),
+				"results": results,
+			},
+		})
+		return
+	}
+
+	user, batchID := requestUser(c), newBatchID()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for i := range changed {
+			changed[i].UpdatedAt = time.Now()
+			if _, err := saveMusicWithHistory(tx, &originals[i], &changed[i], models.TagSourceManual, user, batchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to update: " + err.Error(),
+		})
+		return
+	}
+
+	writeFailed := 0
+	if writeBack {
+		// 数据库专有字段 (如 MusicBrainz ID) 不写入文件
+		for i := range changed {
+			var fields []string
+			for _, ch := range results[i].Changes {
+				if isWritableTagField(ch.Field) {
+					fields = append(fields, ch.Field)
+				}
+			}
+			for _, f := range extraFields {
+				if hasTagFieldValue(&changed[i], f) && !slices.Contains(fields, f) {
+					fields = append(fields, f)
+				}
+			}
+			if err := h.writeTagFields(&changed[i], fields); err != nil {
+				results[i].WriteError = err.Error()
+				writeFailed++
+				continue
+			}
+			h.db.Model(&models.Music{ID: changed[i].ID}).Update("file_size", changed[i].FileSize)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Updated %d tracks", len(changed)),
+		"data": gin.H{
+			"dry_run":      false,
+			"batch_id":     batchID,
+			"total":        total,
+			"changed":      len(changed),
+			"write_failed": writeFailed,
+			"results":      results,
+		},
+	})
+}
+
+// GetEditableFields 返回批量编辑支持的字段和操作
+func (h *MusicHandler) GetEditableFields(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"fields":     models.TagFieldNames(),
+			"operations": []string{EditOpKeep, EditOpSet, EditOpClear, EditOpReplace, EditOpCopy, EditOpNumber},
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"go-music-tag/charset"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// encodingRepairFields 可能以本地编码保存的文本字段
+var encodingRepairFields = []string{
+	"title", "artist", "featured_artists", "album", "album_artist", "composer", "genre", "comment",
+	"label", "catalog_number", "artist_sort", "album_sort", "title_sort", "album_artist_sort", "composer_sort",
+}
+
+// encodingWriteBackFields 写回修复结果时一并写入的字段。只有 ID3v1 的文件会改写为 ID3v2，
+// 这些字段以数据库中的值写入，而不是沿用 ID3v1 中未修复的原始字节
+var encodingWriteBackFields = []string{
+	"title", "artist", "album", "album_artist", "composer", "genre", "comment", "year", "track", "disc",
+}
+
+// RepairEncodingRequest 批量修复乱码，Fallback/Candidates 为空时使用配置
+type RepairEncodingRequest struct {
+	IDs        []uint   `json:"ids"` // 为空时检查全部曲目
+	Album      string   `json:"album"`
+	Artist     string   `json:"artist"`
+	Fields     []string `json:"fields"`
+	Fallback   string   `json:"fallback"`
+	Candidates []string `json:"candidates"`
+	DryRun     bool     `json:"dry_run"`
+	WriteBack  bool     `json:"write_back"` // 写回文件，ID3v2.4 使用 UTF-8，ID3v2.3 使用 UTF-16
+}
+
+// EncodingRepair 单个字段的检测结果
+type EncodingRepair struct {
+	Field      string  `json:"field"`
+	Encoding   string  `json:"encoding"`
+	Confidence float64 `json:"confidence"`
+}
+
+// configuredCharsetDetector 按配置创建编码检测器，配置无效时使用默认候选并记录日志
+func configuredCharsetDetector() *charset.Detector {
+	cfg := config.GetConfig().Charset
+	d, err := charset.NewDetector(cfg.Candidates, cfg.Fallback)
+	if err != nil {
+		log.Printf("[Charset] ⚠️ Invalid charset config, using defaults: %v", err)
+		d, _ = charset.NewDetector(nil, charset.GBK)
+	}
+	return d
+}
+
+// RepairEncoding 检测并修复以 ISO-8859-1 读取的 GBK/Big5/Shift-JIS 标签，dry_run 时只预览
+func (h *MusicHandler) RepairEncoding(c *gin.Context) {
+	var req RepairEncodingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	detector := configuredCharsetDetector()
+	if req.Fallback != "" || len(req.Candidates) > 0 {
+		cfg := config.GetConfig().Charset
+		fallback, candidates := cfg.Fallback, cfg.Candidates
+		if req.Fallback != "" {
+			fallback = req.Fallback
+		}
+		if len(req.Candidates) > 0 {
+			candidates = req.Candidates
+		}
+		d, err := charset.NewDetector(candidates, fallback)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		detector = d
+	}
+
+	fields := req.Fields
+	if len(fields) == 0 {
+		fields = encodingRepairFields
+	}
+	for _, f := range fields {
+		if !models.IsTagField(f) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Unknown field: " + f,
+			})
+			return
+		}
+	}
+
+	query := h.db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ?", req.Artist)
+	}
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	repairs := make(map[uint][]EncodingRepair)
+	originals, changed, results, err := collectEdits(musicList, func(music *models.Music) error {
+		for _, f := range fields {
+			value, _ := music.TagValue(f)
+			r, ok := detector.Repair(value)
+			if !ok {
+				continue
+			}
+			if err := music.SetTagValue(f, r.Text); err != nil {
+				return err
+			}
+			repairs[music.ID] = append(repairs[music.ID], EncodingRepair{Field: f, Encoding: r.Encoding, Confidence: r.Confidence})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+	for i := range results {
+		results[i].Encodings = repairs[results[i].MusicID]
+	}
+
+	h.commitEdits(c, len(musicList), originals, changed, results, req.DryRun, req.WriteBack, encodingWriteBackFields)
+}
+
+// GetCharsetOptions 返回支持的编码与当前配置
+func (h *MusicHandler) GetCharsetOptions(c *gin.Context) {
+	cfg := config.GetConfig().Charset
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"supported":  charset.Supported(),
+			"fallback":   cfg.Fallback,
+			"candidates": cfg.Candidates,
+			"fields":     encodingRepairFields,
+		},
+	})
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/width"
+	"gorm.io/gorm"
+)
+
+// 重复文件分组方式
+const (
+	DuplicateByMetadata    = "metadata"
+	DuplicateByHash        = "hash"
+	DuplicateByFingerprint = "fingerprint"
+)
+
+// TrackQuality 用于在重复文件中挑选保留版本
+type TrackQuality struct {
+	FormatRank   int `json:"format_rank"`
+	BitRate      int `json:"bit_rate"`
+	Completeness int `json:"completeness"` // 标签完整度 0-100
+}
+
+// DuplicateTrack 重复组中的一首曲目
+type DuplicateTrack struct {
+	models.MusicResponse
+	Quality TrackQuality `json:"quality"`
+}
+
+// DuplicateGroup 一组重复曲目，按质量从高到低排列
+type DuplicateGroup struct {
+	Key    string           `json:"key"`
+	Count  int              `json:"count"`
+	BestID uint             `json:"best_id"`
+	Tracks []DuplicateTrack `json:"tracks"`
+}
+
+// ResolveDuplicatesRequest 保留 KeepID，隐藏或删除 IDs 对应的源文件。
+// IDs 必须与 KeepID 在同一重复组中，By 为空时任一分组方式相同即可
+type ResolveDuplicatesRequest struct {
+	KeepID            uint   `json:"keep_id" binding:"required"`
+	IDs               []uint `json:"ids" binding:"required"`
+	Action            string `json:"action" binding:"required,oneof=hide delete"`
+	By                string `json:"by"`
+	DurationTolerance int    `json:"duration_tolerance"`
+}
+
+// formatRanks 格式优先级，无损格式优先
+var formatRanks = map[string]int{
+	"FLAC": 5,
+	"ALAC": 5,
+	"WAV":  4,
+	"AIFF": 4,
+	"OPUS": 3,
+	"OGG":  3,
+	"M4A":  2,
+	"AAC":  2,
+	"MP3":  2,
+	"WMA":  1,
+}
+
+func trackQuality(m *models.Music) TrackQuality {
+	filled := 0
+	checks := []bool{
+		m.Title != "",
+		m.Artist != "",
+		m.Album != "",
+		m.AlbumArtist != "",
+		m.Year > 0,
+		m.TrackNumber > 0,
+		m.Genre != "",
+		m.HasCover,
+		m.HasLyrics,
+		m.MBRecordingID != "",
+	}
+	for _, ok := range checks {
+		if ok {
+			filled++
+		}
+	}
+
+	return TrackQuality{
+		FormatRank:   formatRanks[strings.ToUpper(m.Format)],
+		BitRate:      m.BitRate,
+		Completeness: filled * 100 / len(checks),
+	}
+}
+
+// betterQuality 依次比较格式、比特率、标签完整度和文件大小
+func betterQuality(a, b *models.Music) bool {
+	qa, qb := trackQuality(a), trackQuality(b)
+	if qa.FormatRank != qb.FormatRank {
+		return qa.FormatRank > qb.FormatRank
+	}
+	if qa.BitRate != qb.BitRate {
+		return qa.BitRate > qb.BitRate
+	}
+	if qa.Completeness != qb.Completeness {
+		return qa.Completeness > qb.Completeness
+	}
+	return a.FileSize > b.FileSize
+}
+
+// normalizeTagText 统一全角半角和大小写，并去掉空白和标点
+func normalizeTagText(s string) string {
+	s = strings.ToLower(width.Fold.String(s))
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func newDuplicateGroup(key string, tracks []models.Music) DuplicateGroup {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return betterQuality(&tracks[i], &tracks[j])
+	})
+
+	group := DuplicateGroup{
+		Key:    key,
+		Count:  len(tracks),
+		BestID: tracks[0].ID,
+	}
+	for i := range tracks {
+		group.Tracks = append(group.Tracks, DuplicateTrack{
+			MusicResponse: tracks[i].ToResponse(),
+			Quality:       trackQuality(&tracks[i]),
+		})
+	}
+	return group
+}
+
+// groupByMetadata 按标准化的艺术家/标题分组，再按时长差拆分
+func (h *MusicHandler) groupByMetadata(tolerance int) ([]DuplicateGroup, error) {
+	var musicList []models.Music
+	if err := h.db.Where("scan_status = ? AND title != ''", "success").Find(&musicList).Error; err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string][]models.Music)
+	for _, m := range musicList {
+		key := normalizeTagText(m.Artist) + "|" + normalizeTagText(m.Title)
+		buckets[key] = append(buckets[key], m)
+	}
+
+	var groups []DuplicateGroup
+	for key, tracks := range buckets {
+		if len(tracks) < 2 {
+			continue
+		}
+		sort.Slice(tracks, func(i, j int) bool { return tracks[i].Duration < tracks[j].Duration })
+
+		// 相邻时长差不超过 tolerance 的曲目视为同一录音
+		clusterStart := 0
+		for i := 1; i <= len(tracks); i++ {
+			if i < len(tracks) && tracks[i].Duration-tracks[i-1].Duration <= tolerance {
+				continue
+			}
+			if i-clusterStart >= 2 {
+				cluster := append([]models.Music(nil), tracks[clusterStart:i]...)
+				groups = append(groups, newDuplicateGroup(fmt.Sprintf("%s@%d", key, cluster[0].Duration), cluster))
+			}
+			clusterStart = i
+		}
+	}
+	return groups, nil
+}
+
+// groupByHash 按音频内容哈希分组
+func (h *MusicHandler) groupByHash() ([]DuplicateGroup, error) {
+	var musicList []models.Music
+	err := h.db.Where("scan_status = ? AND audio_hash IN (?)", "success",
+		h.db.Model(&models.Music{}).
+			Select("audio_hash").
+			Where("audio_hash != '' AND audio_hash IS NOT NULL").
+			Group("audio_hash").
+			Having("COUNT(*) > 1"),
+	).Find(&musicList).Error
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string][]models.Music)
+	for _, m := range musicList {
+		buckets[m.AudioHash] = append(buckets[m.AudioHash], m)
+	}
+
+	var groups []DuplicateGroup
+	for key, tracks := range buckets {
+		if len(tracks) >= 2 {
+			groups = append(groups, newDuplicateGroup(key, tracks))
+		}
+	}
+	return groups, nil
+}
+
+// groupByFingerprint 按 AcoustID 分组，没有 AcoustID 时按完全相同的指纹分组
+func (h *MusicHandler) groupByFingerprint() ([]DuplicateGroup, error) {
+	var records []models.Fingerprint
+	if err := h.db.Where("fingerprint != '' AND fingerprint IS NOT NULL").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	keyByMusic := make(map[uint]string, len(records))
+	for _, r := range records {
+		if r.AcoustID != "" {
+			keyByMusic[r.MusicID] = r.AcoustID
+		} else {
+			sum := sha1.Sum([]byte(r.Fingerprint))
+			keyByMusic[r.MusicID] = "fp:" + hex.EncodeToString(sum[:])
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, key := range keyByMusic {
+		counts[key]++
+	}
+	var ids []uint
+	for id, key := range keyByMusic {
+		if counts[key] > 1 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var musicList []models.Music
+	if err := h.db.Where("scan_status = ? AND id IN ?", "success", ids).Find(&musicList).Error; err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string][]models.Music)
+	for _, m := range musicList {
+		key := keyByMusic[m.ID]
+		buckets[key] = append(buckets[key], m)
+	}
+
+	var groups []DuplicateGroup
+	for key, tracks := range buckets {
+		if len(tracks) >= 2 {
+			groups = append(groups, newDuplicateGroup(key, tracks))
+		}
+	}
+	return groups, nil
+}
+
+func validDuplicateGrouping(by string) bool {
+	return by == DuplicateByMetadata || by == DuplicateByHash || by == DuplicateByFingerprint
+}
+
+// findDuplicateGroups 按指定方式分组
+func (h *MusicHandler) findDuplicateGroups(by string, tolerance int) ([]DuplicateGroup, error) {
+	switch by {
+	case DuplicateByHash:
+		return h.groupByHash()
+	case DuplicateByFingerprint:
+		return h.groupByFingerprint()
+	default:
+		return h.groupByMetadata(tolerance)
+	}
+}
+
+// duplicatesOf 与 keepID 在同一重复组中的曲目，by 为空时合并所有分组方式的结果
+func (h *MusicHandler) duplicatesOf(keepID uint, by string, tolerance int) (map[uint]bool, error) {
+	groupings := []string{by}
+	if by == "" {
+		groupings = []string{DuplicateByMetadata, DuplicateByHash, DuplicateByFingerprint}
+	}
+
+	members := make(map[uint]bool)
+	for _, g := range groupings {
+		groups, err := h.findDuplicateGroups(g, tolerance)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			inGroup := false
+			for _, t := range group.Tracks {
+				if t.ID == keepID {
+					inGroup = true
+					break
+				}
+			}
+			if !inGroup {
+				continue
+			}
+			for _, t := range group.Tracks {
+				if t.ID != keepID {
+					members[t.ID] = true
+				}
+			}
+		}
+	}
+	return members, nil
+}
+
+// FindDuplicates 重复曲目报告
+// by=metadata (默认) | hash | fingerprint
+func (h *MusicHandler) FindDuplicates(c *gin.Context) {
+	by := c.DefaultQuery("by", DuplicateByMetadata)
+	tolerance := getInt(c.DefaultQuery("duration_tolerance", "0"))
+	if tolerance <= 0 {
+		tolerance = config.GetConfig().Duplicates.DurationTolerance
+	}
+
+	if !validDuplicateGrouping(by) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid grouping: " + by,
+		})
+		return
+	}
+	groups, err := h.findDuplicateGroups(by, tolerance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to find duplicates: " + err.Error(),
+		})
+		return
+	}
+
+	// 组内曲目多的排在前面
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	redundant := 0
+	for _, g := range groups {
+		redundant += g.Count - 1
+	}
+	if groups == nil {
+		groups = []DuplicateGroup{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"by":        by,
+			"total":     len(groups),
+			"redundant": redundant,
+			"groups":    groups,
+		},
+	})
+}
+
+// ResolveDuplicates 处理重复曲目：hide 移动到隔离目录，delete 直接删除源文件
+func (h *MusicHandler) ResolveDuplicates(c *gin.Context) {
+	var req ResolveDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	for _, id := range req.IDs {
+		if id == req.KeepID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "keep_id must not be in ids",
+			})
+			return
+		}
+	}
+
+	if req.By != "" && !validDuplicateGrouping(req.By) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid grouping: " + req.By,
+		})
+		return
+	}
+
+	var keep models.Music
+	if err := h.db.First(&keep, req.KeepID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music to keep not found",
+		})
+		return
+	}
+
+	// 只处理与保留曲目确实重复的文件，避免误删任意曲目
+	tolerance := req.DurationTolerance
+	if tolerance <= 0 {
+		tolerance = config.GetConfig().Duplicates.DurationTolerance
+	}
+	members, err := h.duplicatesOf(req.KeepID, req.By, tolerance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to find duplicates: " + err.Error(),
+		})
+		return
+	}
+	var outside []uint
+	for _, id := range req.IDs {
+		if !members[id] {
+			outside = append(outside, id)
+		}
+	}
+	if len(outside) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Some ids are not duplicates of keep_id",
+			"data":    gin.H{"ids": outside},
+		})
+		return
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	quarantine := path.Join(client.RootPath(), config.GetConfig().Duplicates.QuarantineDir)
+
+	var results []gin.H
+	success := 0
+	for _, id := range req.IDs {
+		var music models.Music
+		if err := h.db.First(&music, id).Error; err != nil {
+			results = append(results, gin.H{"id": id, "status": "failed", "error": "Music not found"})
+			continue
+		}
+
+		var opErr error
+		target := ""
+		if req.Action == "hide" {
+			rel := strings.TrimPrefix(music.FilePath, client.RootPath())
+			target = path.Join(quarantine, rel)
+			opErr = client.Move(music.FilePath, target)
+		} else {
+			opErr = client.Remove(music.FilePath)
+		}
+		if opErr != nil {
+			log.Printf("[Duplicates] ❌ %s %s: %v", req.Action, music.FilePath, opErr)
+			results = append(results, gin.H{"id": id, "status": "failed", "error": opErr.Error()})
+			continue
+		}
+
+		// 源文件已不在音乐库中，删除曲目及所有关联记录
+		if err := h.db.Transaction(func(tx *gorm.DB) error {
+			return deleteTrackRecords(tx, []uint{music.ID})
+		}); err != nil {
+			log.Printf("[Duplicates] ❌ Failed to delete records of %s: %v", music.FilePath, err)
+			results = append(results, gin.H{"id": id, "status": "failed", "moved_to": target,
+				"error": "File handled but failed to delete records: " + err.Error()})
+			continue
+		}
+
+		success++
+		results = append(results, gin.H{"id": id, "status": "success", "moved_to": target})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Resolved %d/%d duplicates", success, len(req.IDs)),
+		"data": gin.H{
+			"keep_id": req.KeepID,
+			"action":  req.Action,
+			"results": results,
+		},
+	})
+}
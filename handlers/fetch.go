@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/fetcher"
+	"go-music-tag/models"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BatchFetchRequest 按选择或筛选条件批量获取歌词和封面，条件可以组合，都为空时处理全部曲目
+type BatchFetchRequest struct {
+	IDs           []uint   `json:"ids"`
+	Album         string   `json:"album"`
+	Artist        string   `json:"artist"`
+	Search        string   `json:"search"`    // 标题、艺术家或专辑包含该关键词
+	RetryJob      string   `json:"retry_job"` // 只重试该任务中失败或未找到的曲目和内容
+	Kinds         []string `json:"kinds"`     // lyrics、cover，为空时两者都获取
+	Overwrite     bool     `json:"overwrite"` // 已有歌词或封面时仍然获取并替换
+	Providers     []string `json:"providers"` // 为空时使用全部提供方
+	MinConfidence float64  `json:"min_confidence"`
+	RetryNotFound bool     `json:"retry_not_found"` // 不跳过最近未找到的内容
+}
+
+// FetchResultItem 逐曲结果，附带曲目信息便于展示
+type FetchResultItem struct {
+	models.FetchResult
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+}
+
+// maxFetchErrorLength 与 FetchResult.Error 的列宽一致
+const maxFetchErrorLength = 500
+
+// fetcherOptions 按配置生成抓取器选项
+func fetcherOptions() fetcher.Options {
+	cfg := config.GetConfig().Fetcher
+	opts := fetcher.Options{
+		DataDir:   cfg.DataDir,
+		Proxy:     cfg.Proxy,
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		UserAgent: cfg.UserAgent,
+		Providers: make(map[string]fetcher.ProviderOptions),
+	}
+	for name, p := range cfg.Providers {
+		opts.Providers[name] = fetcher.ProviderOptions{
+			BaseURL:   p.BaseURL,
+			Disabled:  p.Enabled != nil && !*p.Enabled,
+			RateLimit: p.RateLimit,
+			Burst:     p.Burst,
+		}
+	}
+	return opts
+}
+
+var (
+	fetcherMutex      sync.Mutex
+	sharedFetcher     *fetcher.Fetcher
+	sharedFetcherOpts fetcher.Options // 创建 sharedFetcher 时使用的选项
+)
+
+// getFetcher 返回共享的抓取器，复用连接池并只创建一次缓存目录；配置变化时重新创建
+func getFetcher() *fetcher.Fetcher {
+	opts := fetcherOptions()
+	fetcherMutex.Lock()
+	defer fetcherMutex.Unlock()
+	if sharedFetcher == nil || !reflect.DeepEqual(opts, sharedFetcherOpts) {
+		sharedFetcher = fetcher.NewFetcher(opts)
+		sharedFetcherOpts = opts
+	}
+	return sharedFetcher
+}
+
+// enabledProviders 过滤掉配置中停用的提供方
+func enabledProviders(f *fetcher.Fetcher, providers []string) []string {
+	enabled := make([]string, 0, len(providers))
+	for _, p := range providers {
+		if f.Enabled(p) {
+			enabled = append(enabled, p)
+		}
+	}
+	return enabled
+}
+
+// fetchProviders 所有内容类型的提供方
+func fetchProviders() []string {
+	seen := make(map[string]bool)
+	var providers []string
+	for _, p := range append(fetcher.LyricsProviders(), fetcher.CoverProviders()...) {
+		if !seen[p] {
+			seen[p] = true
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// validateBatchFetch 校验内容类型、提供方和置信度，返回要获取的内容类型
+func validateBatchFetch(req *BatchFetchRequest) ([]string, error) {
+	kinds := req.Kinds
+	if len(kinds) == 0 {
+		kinds = []string{models.FetchKindLyrics, models.FetchKindCover}
+	}
+	for _, k := range kinds {
+		if k != models.FetchKindLyrics && k != models.FetchKindCover {
+			return nil, fmt.Errorf("unknown kind: %s", k)
+		}
+	}
+	known := make(map[string]bool)
+	for _, p := range fetchProviders() {
+		known[p] = true
+	}
+	f := getFetcher()
+	for _, p := range req.Providers {
+		if !known[p] {
+			return nil, fmt.Errorf("unknown provider: %s", p)
+		}
+		if !f.Enabled(p) {
+			return nil, fmt.Errorf("provider is disabled: %s", p)
+		}
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 1 {
+		return nil, fmt.Errorf("min_confidence must be between 0 and 1")
+	}
+	return kinds, nil
+}
+
+// BatchFetch 对选中的曲目批量获取歌词和封面，后台执行，逐曲结果按任务 ID 记录
+func (h *MusicHandler) BatchFetch(c *gin.Context) {
+	var req BatchFetchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+	kinds, err := validateBatchFetch(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	db := h.getDB()
+	query := db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ? OR album_artist = ?", req.Artist, req.Artist)
+	}
+	if req.Search != "" {
+		like := "%" + req.Search + "%"
+		query = query.Where("title LIKE ? OR artist LIKE ? OR album LIKE ?", like, like, like)
+	}
+
+	// 重试时每首曲目只重新获取上次失败或未找到的内容
+	var retryKinds map[uint]map[string]bool
+	if req.RetryJob != "" {
+		var previous []models.FetchResult
+		if err := db.Where("job_id = ? AND status IN ?", req.RetryJob,
+			[]string{models.FetchStatusFailed, models.FetchStatusNotFound}).Find(&previous).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to load job results: " + err.Error(),
+			})
+			return
+		}
+		retryKinds = make(map[uint]map[string]bool)
+		ids := make([]uint, 0, len(previous))
+		for _, r := range previous {
+			if retryKinds[r.MusicID] == nil {
+				retryKinds[r.MusicID] = make(map[string]bool)
+				ids = append(ids, r.MusicID)
+			}
+			retryKinds[r.MusicID][r.Kind] = true
+		}
+		query = query.Where("id IN ?", ids)
+	}
+
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get music list: " + err.Error(),
+		})
+		return
+	}
+	if len(musicList) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "No music matches the selection",
+			"data":    gin.H{"total": 0, "success": 0, "failed": 0},
+		})
+		return
+	}
+
+	job := newFetchJob(kinds, requestUser(c))
+	job.retryKinds = retryKinds
+	job.overwrite = req.Overwrite
+	job.useMisses = req.RetryJob == "" && !req.RetryNotFound
+	job.options = fetcher.SearchOptions{Providers: req.Providers, MinConfidence: req.MinConfidence}
+	h.startBatchFetch(c, "fetch", musicList, job)
+}
+
+// fetchJob 一次获取任务的参数和任务内共享的状态
+type fetchJob struct {
+	id         string
+	kinds      []string
+	retryKinds map[uint]map[string]bool // 不为空时每首曲目只获取其中列出的内容
+	overwrite  bool
+	useMisses  bool // 跳过最近未找到且标签未改变的内容
+	options    fetcher.SearchOptions
+	user       string
+
+	fetcher *fetcher.Fetcher
+	covers  map[string]coverLookup
+}
+
+// coverLookup 同一任务中同一专辑的封面只搜索一次
+type coverLookup struct {
+	result *fetcher.CoverResult
+	err    error
+}
+
+func newFetchJob(kinds []string, user string) *fetchJob {
+	return &fetchJob{
+		id:      newBatchID(),
+		kinds:   kinds,
+		user:    user,
+		fetcher: getFetcher(),
+		covers:  make(map[string]coverLookup),
+	}
+}
+
+// claimBatchStatus 没有批量任务在运行时占用批量任务状态，否则返回 false
+func claimBatchStatus(taskType string, total int, jobID string) bool {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+	if batchStatus.Running {
+		return false
+	}
+	batchStatus = &BatchStatus{
+		Running:   true,
+		TaskType:  taskType,
+		Total:     total,
+		Message:   "Starting...",
+		CreatedAt: time.Now(),
+		JobID:     jobID,
+	}
+	return true
+}
+
+// startBatchFetch 占用批量任务状态并在后台执行获取任务
+func (h *MusicHandler) startBatchFetch(c *gin.Context, taskType string, musicList []models.Music, job *fetchJob) {
+	if !claimBatchStatus(taskType, len(musicList), job.id) {
+		c.JSON(StatusBusy, gin.H{
+			"code":    409,
+			"message": "Another batch task is running",
+		})
+		return
+	}
+
+	go h.runBatchFetch(job, musicList)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Batch fetch started",
+		"data": gin.H{
+			"job_id":  job.id,
+			"total":   len(musicList),
+			"kinds":   job.kinds,
+			"success": 0,
+			"failed":  0,
+		},
+	})
+}
+
+// runBatchFetch 逐首获取并记录结果；一首曲目的所有内容都成功或跳过才计为成功。
+// 请求间隔由 fetcher 按提供方限速控制
+func (h *MusicHandler) runBatchFetch(job *fetchJob, musicList []models.Music) (success, failed int) {
+
+	for i := range musicList {
+		music := &musicList[i]
+
+		statusMutex.Lock()
+		batchStatus.Current = i + 1
+		batchStatus.Message = fmt.Sprintf("Processing: %s", music.Title)
+		statusMutex.Unlock()
+
+		if h.fetchTrack(job, music) {
+			success++
+		} else {
+			failed++
+		}
+		statusMutex.Lock()
+		batchStatus.Success = success
+		batchStatus.Failed = failed
+		statusMutex.Unlock()
+	}
+
+	statusMutex.Lock()
+	batchStatus.Running = false
+	batchStatus.Message = "Completed"
+	statusMutex.Unlock()
+	log.Printf("[Fetch] 🎉 Job %s done: total=%d, success=%d, failed=%d", job.id, len(musicList), success, failed)
+	return success, failed
+}
+
+// fetchTrack 获取一首曲目的各项内容并记录结果，全部成功或跳过时返回 true
+func (h *MusicHandler) fetchTrack(job *fetchJob, music *models.Music) bool {
+	ok := true
+	var results []models.FetchResult
+	for _, kind := range job.kinds {
+		if job.retryKinds != nil && !job.retryKinds[music.ID][kind] {
+			continue
+		}
+		result := h.fetchOne(job, music, kind)
+		if result.Status != models.FetchStatusSuccess && result.Status != models.FetchStatusSkipped {
+			ok = false
+		}
+		results = append(results, result)
+	}
+	if len(results) > 0 {
+		if err := h.getDB().Create(&results).Error; err != nil {
+			log.Printf("[Fetch] ⚠️ Failed to record results for %s: %v", music.Title, err)
+		}
+	}
+	return ok
+}
+
+// fetchOne 获取一项内容：已有内容且不覆盖、或最近未找到且标签未变时跳过；
+// 未找到时记录到未找到缓存，成功时清除
+func (h *MusicHandler) fetchOne(job *fetchJob, music *models.Music, kind string) models.FetchResult {
+	db := h.getDB()
+	result := models.FetchResult{JobID: job.id, MusicID: music.ID, Kind: kind}
+
+	has := music.HasLyrics
+	if kind == models.FetchKindCover {
+		has = music.HasCover
+	}
+	if has && !job.overwrite {
+		result.Status = models.FetchStatusSkipped
+		return result
+	}
+
+	tagKey := fetchTagKey(music)
+	if job.useMisses {
+		if miss := recentMiss(db, music.ID, kind, tagKey); miss != nil {
+			result.Status = models.FetchStatusSkipped
+			result.Error = fmt.Sprintf("not found on %s, skipped until %s or tags change",
+				miss.CreatedAt.Format("2006-01-02 15:04"), miss.ExpiresAt.Format("2006-01-02 15:04"))
+			return result
+		}
+	}
+
+	switch kind {
+	case models.FetchKindLyrics:
+		h.fetchLyricsFor(job, music, &result)
+	case models.FetchKindCover:
+		h.fetchCoverFor(job, music, &result)
+	}
+
+	switch result.Status {
+	case models.FetchStatusNotFound:
+		if err := recordMiss(db, music.ID, kind, tagKey, result.Error); err != nil {
+			log.Printf("[Fetch] ⚠️ Failed to record miss for %s: %v", music.Title, err)
+		}
+	case models.FetchStatusSuccess:
+		db.Where("music_id = ? AND kind = ?", music.ID, kind).Delete(&models.FetchMiss{})
+	}
+	if len(result.Error) > maxFetchErrorLength {
+		result.Error = result.Error[:maxFetchErrorLength]
+	}
+	return result
+}
+
+// fetchTagKey 获取时所用标签的摘要，任一标签改变后未找到记录失效
+func fetchTagKey(music *models.Music) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		music.Artist, music.Title, music.Album, music.AlbumArtist, strconv.Itoa(music.Duration),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recentMiss 未过期且标签未改变的未找到记录
+func recentMiss(db *gorm.DB, musicID uint, kind, tagKey string) *models.FetchMiss {
+	var miss models.FetchMiss
+	err := db.Where("music_id = ? AND kind = ? AND tag_key = ? AND expires_at > ?",
+		musicID, kind, tagKey, time.Now()).First(&miss).Error
+	if err != nil {
+		return nil
+	}
+	return &miss
+}
+
+// recordMiss 记录或刷新未找到记录
+func recordMiss(db *gorm.DB, musicID uint, kind, tagKey, reason string) error {
+	if len(reason) > maxFetchErrorLength {
+		reason = reason[:maxFetchErrorLength]
+	}
+	now := time.Now()
+	ttl := time.Duration(config.GetConfig().Fetcher.NotFoundTTL) * time.Hour
+	var miss models.FetchMiss
+	return db.Where(models.FetchMiss{MusicID: musicID, Kind: kind}).
+		Assign(models.FetchMiss{TagKey: tagKey, Reason: reason, ExpiresAt: now.Add(ttl), CreatedAt: now}).
+		FirstOrCreate(&miss).Error
+}
+
+// fetchStatus 区分未找到与可重试的错误
+func fetchStatus(err error) string {
+	if errors.Is(err, fetcher.ErrNotFound) {
+		return models.FetchStatusNotFound
+	}
+	return models.FetchStatusFailed
+}
+
+func (h *MusicHandler) fetchLyricsFor(job *fetchJob, music *models.Music, result *models.FetchResult) {
+	if music.Title == "" {
+		result.Status = models.FetchStatusNotFound
+		result.Error = "track has no title"
+		return
+	}
+
+	lyric, err := job.fetcher.SearchLyricsWith(music.Artist, music.Title, music.Duration, job.options)
+	if err != nil {
+		result.Status = fetchStatus(err)
+		result.Error = err.Error()
+		return
+	}
+	result.Provider, result.Confidence = lyric.Source, lyric.Confidence
+	if err := saveFetchedLyrics(h.getDB(), music, lyric.Content, lyric.Translation, lyric.Romanization, job.user, job.id); err != nil {
+		result.Status = models.FetchStatusFailed
+		result.Error = "save failed: " + err.Error()
+		return
+	}
+	result.Status = models.FetchStatusSuccess
+}
+
+func (h *MusicHandler) fetchCoverFor(job *fetchJob, music *models.Music, result *models.FetchResult) {
+	artist := music.AlbumArtist
+	if artist == "" {
+		artist = music.Artist
+	}
+	album := music.Album
+	if album == "" {
+		album = music.Title
+	}
+	if album == "" {
+		result.Status = models.FetchStatusNotFound
+		result.Error = "track has no album or title"
+		return
+	}
+
+	key := artist + "\x00" + album
+	lookup, cached := job.covers[key]
+	if !cached {
+		lookup.result, lookup.err = job.fetcher.SearchCoverWith(artist, album, job.options)
+		job.covers[key] = lookup
+	}
+	if lookup.err != nil {
+		result.Status = fetchStatus(lookup.err)
+		result.Error = lookup.err.Error()
+		return
+	}
+	cover := lookup.result
+	result.Provider, result.Confidence = cover.Source, cover.Confidence
+	if err := linkFetchedCover(h.getDB(), music, cover.Data, cover.URL, job.user, job.id); err != nil {
+		result.Status = models.FetchStatusFailed
+		result.Error = "save failed: " + err.Error()
+		return
+	}
+	result.Status = models.FetchStatusSuccess
+}
+
+// GetFetchJobResults 返回任务的逐曲结果，可按 status、kind 过滤
+func (h *MusicHandler) GetFetchJobResults(c *gin.Context) {
+	jobID := c.Param("job_id")
+	db := h.getDB()
+
+	var summary []struct {
+		Status string
+		Count  int
+	}
+	if err := db.Model(&models.FetchResult{}).Select("status, COUNT(*) AS count").
+		Where("job_id = ?", jobID).Group("status").Scan(&summary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load job results: " + err.Error(),
+		})
+		return
+	}
+	if len(summary) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Fetch job not found",
+		})
+		return
+	}
+	counts := gin.H{
+		models.FetchStatusSuccess:  0,
+		models.FetchStatusNotFound: 0,
+		models.FetchStatusFailed:   0,
+		models.FetchStatusSkipped:  0,
+	}
+	for _, s := range summary {
+		counts[s.Status] = s.Count
+	}
+
+	query := db.Where("job_id = ?", jobID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var results []models.FetchResult
+	if err := query.Order("id").Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load job results: " + err.Error(),
+		})
+		return
+	}
+
+	ids := make([]uint, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.MusicID)
+	}
+	var musicList []models.Music
+	db.Select("id", "title", "artist", "album").Where("id IN ?", ids).Find(&musicList)
+	byID := make(map[uint]models.Music, len(musicList))
+	for _, m := range musicList {
+		byID[m.ID] = m
+	}
+	items := make([]FetchResultItem, 0, len(results))
+	for _, r := range results {
+		m := byID[r.MusicID]
+		items = append(items, FetchResultItem{FetchResult: r, Title: m.Title, Artist: m.Artist, Album: m.Album})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"job_id":  jobID,
+			"summary": counts,
+			"total":   len(items),
+			"list":    items,
+		},
+	})
+}
+
+// ListFetchJobs 最近的获取任务及各状态数量，任务 ID 按开始时间生成，按 ID 倒序即最新在前
+func (h *MusicHandler) ListFetchJobs(c *gin.Context) {
+	limit := getInt(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var jobs []struct {
+		JobID    string `json:"job_id"`
+		Total    int    `json:"total"`
+		Success  int    `json:"success"`
+		NotFound int    `json:"not_found"`
+		Failed   int    `json:"failed"`
+		Skipped  int    `json:"skipped"`
+	}
+	err := h.getDB().Model(&models.FetchResult{}).
+		Select("job_id, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS success, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS not_found, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS skipped",
+			models.FetchStatusSuccess, models.FetchStatusNotFound, models.FetchStatusFailed, models.FetchStatusSkipped).
+		Group("job_id").Order("job_id DESC").Limit(limit).Scan(&jobs).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to list fetch jobs: " + err.Error(),
+		})
+		return
+	}
+
+	f := getFetcher()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"providers": gin.H{
+				models.FetchKindLyrics: enabledProviders(f, fetcher.LyricsProviders()),
+				models.FetchKindCover:  enabledProviders(f, fetcher.CoverProviders()),
+			},
+			"list": jobs,
+		},
+	})
+}
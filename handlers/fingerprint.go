@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/fingerprint"
+	"go-music-tag/models"
+	"go-music-tag/webdav"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FingerprintRequest 单曲指纹识别选项
+type FingerprintRequest struct {
+	Apply bool `json:"apply"` // 匹配分数达到阈值时写入识别结果
+}
+
+// FingerprintBatchRequest 批量指纹识别请求，IDs 为空时处理全部曲目
+type FingerprintBatchRequest struct {
+	IDs          []uint  `json:"ids"`
+	OnlyUntagged bool    `json:"only_untagged"` // 只处理缺少艺术家或 MBID 的曲目
+	AutoApply    bool    `json:"auto_apply"`
+	MinScore     float64 `json:"min_score"` // 为 0 时使用配置中的阈值
+}
+
+func newFingerprintTools() (*fingerprint.Fingerprinter, *fingerprint.AcoustIDClient) {
+	cfg := config.GetConfig().Fingerprint
+	return fingerprint.NewFingerprinter(cfg.FpcalcPath, cfg.Length),
+		fingerprint.NewAcoustIDClient(cfg.AcoustID.APIKey, cfg.AcoustID.BaseURL)
+}
+
+// identifyTrack 下载音频、计算指纹并查询 AcoustID，结果保存到 fingerprints 表
+func (h *MusicHandler) identifyTrack(music *models.Music, client *webdav.Client,
+	fp *fingerprint.Fingerprinter, ac *fingerprint.AcoustIDClient) (*models.Fingerprint, []fingerprint.Match, error) {
+
+	record := models.Fingerprint{MusicID: music.ID}
+	h.getDB().Where("music_id = ?", music.ID).First(&record)
+
+	fail := func(err error) (*models.Fingerprint, []fingerprint.Match, error) {
+		record.Status = models.FingerprintFailed
+		record.Error = err.Error()
+		h.getDB().Save(&record)
+		return &record, nil, err
+	}
+
+	data, err := client.GetFile(music.FilePath)
+	if err != nil {
+		return fail(fmt.Errorf("failed to download: %w", err))
+	}
+
+	result, err := fp.Compute(data, filepath.Ext(music.FilePath))
+	if err != nil {
+		return fail(err)
+	}
+	record.Fingerprint = result.Fingerprint
+	record.Duration = result.Duration
+
+	matches, err := ac.Lookup(result)
+	if err != nil {
+		return fail(err)
+	}
+
+	record.Error = ""
+	record.AcoustID, record.RecordingID, record.Title, record.Artist, record.Album = "", "", "", "", ""
+	record.Score = 0
+	record.Status = models.FingerprintNoMatch
+	if len(matches) > 0 {
+		best := matches[0]
+		record.AcoustID = best.AcoustID
+		record.RecordingID = best.RecordingID
+		record.Title = best.Title
+		record.Artist = best.Artist
+		record.Album = best.Album
+		record.Score = best.Score
+		if best.RecordingID != "" {
+			record.Status = models.FingerprintMatched
+		}
+	}
+
+	if err := h.getDB().Save(&record).Error; err != nil {
+		return &record, matches, err
+	}
+	return &record, matches, nil
+}
+
+// isUntagged 没有艺术家的曲目，其标题通常只是从文件名推断的
+func isUntagged(music *models.Music) bool {
+	return music.Artist == ""
+}
+
+// applyFingerprintMatch 写入识别出的录音 MBID；未打标签的曲目同时补全标题、艺术家和专辑
+func applyFingerprintMatch(music *models.Music, record *models.Fingerprint) bool {
+	if record.Status != models.FingerprintMatched {
+		return false
+	}
+
+	untagged := isUntagged(music)
+	music.MBRecordingID = record.RecordingID
+	if untagged {
+		if record.Title != "" {
+			music.Title = record.Title
+		}
+		music.Artist = record.Artist
+		if music.Album == "" {
+			music.Album = record.Album
+		}
+	}
+	music.UpdatedAt = time.Now()
+	return true
+}
+
+// FingerprintMusic 计算单曲指纹并查询 AcoustID
+func (h *MusicHandler) FingerprintMusic(c *gin.Context) {
+	id := c.Param("id")
+
+	var music models.Music
+	if err := h.db.First(&music, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var req FingerprintRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	fp, ac := newFingerprintTools()
+	if err := fp.Available(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	record, matches, err := h.identifyTrack(&music, client, fp, ac)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "Fingerprint lookup failed: " + err.Error(),
+		})
+		return
+	}
+
+	applied := false
+	if req.Apply && record.Score >= config.GetConfig().Fingerprint.AcoustID.MinScore {
+		before := music
+		if applyFingerprintMatch(&music, record) {
+			if _, err := saveMusicWithHistory(h.db, &before, &music, models.TagSourceFingerprint, requestUser(c), newBatchID()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "Failed to save: " + err.Error(),
+				})
+				return
+			}
+			applied = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"music":       music.ToResponse(),
+			"fingerprint": record,
+			"matches":     matches,
+			"applied":     applied,
+		},
+	})
+}
+
+// GetFingerprint 获取已保存的指纹识别结果
+func (h *MusicHandler) GetFingerprint(c *gin.Context) {
+	id := c.Param("id")
+
+	var record models.Fingerprint
+	if err := h.db.Where("music_id = ?", id).First(&record).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Fingerprint not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    record,
+	})
+}
+
+// BatchFingerprint 后台批量计算指纹并识别
+func (h *MusicHandler) BatchFingerprint(c *gin.Context) {
+	var req FingerprintBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+	if req.MinScore <= 0 {
+		req.MinScore = config.GetConfig().Fingerprint.AcoustID.MinScore
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	fp, ac := newFingerprintTools()
+	if err := fp.Available(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.getDB().Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.OnlyUntagged {
+		query = query.Where("artist = '' OR artist IS NULL OR mb_recording_id = '' OR mb_recording_id IS NULL")
+	}
+
+	var musicList []models.Music
+	if err := query.Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get music list: " + err.Error(),
+		})
+		return
+	}
+
+	if len(musicList) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "No music to fingerprint",
+			"data":    gin.H{"total": 0, "success": 0, "failed": 0},
+		})
+		return
+	}
+
+	statusMutex.Lock()
+	if batchStatus.Running {
+		statusMutex.Unlock()
+		c.JSON(StatusBusy, gin.H{
+			"code":    409,
+			"message": "Another batch task is running",
+		})
+		return
+	}
+	batchStatus = &BatchStatus{
+		Running:   true,
+		TaskType:  "fingerprint",
+		Total:     len(musicList),
+		Message:   "Starting...",
+		CreatedAt: time.Now(),
+	}
+	statusMutex.Unlock()
+
+	user, batchID := requestUser(c), newBatchID()
+	go func() {
+		success := 0
+		failed := 0
+		applied := 0
+
+		for i := range musicList {
+			music := &musicList[i]
+			before := *music
+
+			statusMutex.Lock()
+			batchStatus.Current = i + 1
+			batchStatus.Message = fmt.Sprintf("Processing: %s", music.FileName)
+			statusMutex.Unlock()
+
+			record, _, err := h.identifyTrack(music, client, fp, ac)
+			if err != nil {
+				failed++
+				log.Printf("[Fingerprint] ❌ %s: %v", music.FileName, err)
+			} else {
+				success++
+				if req.AutoApply && record.Score >= req.MinScore && applyFingerprintMatch(music, record) {
+					if _, err := saveMusicWithHistory(h.getDB(), &before, music, models.TagSourceFingerprint, user, batchID); err == nil {
+						applied++
+					}
+				}
+			}
+
+			statusMutex.Lock()
+			batchStatus.Success = success
+			batchStatus.Failed = failed
+			statusMutex.Unlock()
+		}
+
+		statusMutex.Lock()
+		batchStatus.Running = false
+		batchStatus.Message = fmt.Sprintf("Completed, %d identified", applied)
+		statusMutex.Unlock()
+		log.Printf("[Fingerprint] 🎉 Batch done: total=%d, success=%d, failed=%d, applied=%d",
+			len(musicList), success, failed, applied)
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Fingerprint batch started",
+		"data": gin.H{
+			"total":   len(musicList),
+			"success": 0,
+			"failed":  0,
+		},
+	})
+}
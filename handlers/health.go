@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"go-music-tag/config"
+	"go-music-tag/fetcher"
+	"go-music-tag/models"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// healthCategory 健康报告中的一类问题，scope 用于筛选对应的曲目
+type healthCategory struct {
+	Name        string
+	Description string
+	scope       func(db *gorm.DB) *gorm.DB
+}
+
+// HealthCategoryResult 一类问题的统计，Link 指向可筛选的曲目列表
+type HealthCategoryResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Count       int64  `json:"count"`
+	Link        string `json:"link"`
+}
+
+// OrphanedFile 不属于任何曲目的本地缓存文件
+type OrphanedFile struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"` // lyrics | cover
+	Size int64  `json:"size"`
+}
+
+// HealthTracksQuery 按问题类别列出曲目
+type HealthTracksQuery struct {
+	Category string `form:"category" binding:"required"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// healthCategories 按报告中的展示顺序排列
+func healthCategories() []healthCategory {
+	success := func(db *gorm.DB) *gorm.DB { return db.Where("scan_status = ?", "success") }
+	minBitRate := config.GetConfig().Health.MinBitRate
+
+	return []healthCategory{
+		{"failed", "Files that failed to scan", func(db *gorm.DB) *gorm.DB {
+			return db.Where("scan_status = ?", "failed")
+		}},
+		{"corrupt", "Damaged audio streams (sync loss, truncated frames, bad CRC)", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("integrity_status = ?", models.IntegrityCorrupt)
+		}},
+		{"integrity_warning", "Garbage data or duplicate tags", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("integrity_status = ?", models.IntegrityWarning)
+		}},
+		{"missing_title", "Tracks without title", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("title = '' OR title IS NULL")
+		}},
+		{"missing_artist", "Tracks without artist", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("artist = '' OR artist IS NULL")
+		}},
+		{"missing_album", "Tracks without album", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("album = '' OR album IS NULL")
+		}},
+		{"low_bitrate", "Files below the bitrate threshold", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("bit_rate > 0 AND bit_rate < ?", minBitRate)
+		}},
+		{"estimated_duration", "Durations estimated from file size", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("duration_estimated = ?", true)
+		}},
+		{"missing_cover", "Tracks without cover", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("has_cover = ?", false)
+		}},
+		{"missing_lyrics", "Tracks without lyrics", func(db *gorm.DB) *gorm.DB {
+			return success(db).Where("has_lyrics = ?", false)
+		}},
+	}
+}
+
+// findOrphanedFiles 列出本地缓存中与所有曲目都不对应的歌词和旧版封面文件，
+// 以及封面库中没有被曲目或专辑引用的图片 (与封面回收任务的判断一致)
+func (h *MusicHandler) findOrphanedFiles() ([]OrphanedFile, error) {
+	f := getFetcher()
+	lyrics, covers, err := f.CachedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []models.Music
+	if err := h.db.Select("artist", "title", "album").Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	expected := make(map[string]bool, len(tracks)*4)
+	for _, t := range tracks {
+		expected[f.GetLocalLyricsPath(t.Artist, t.Title)] = true
+		expected[f.GetLocalLyricsLayerPath(t.Artist, t.Title, fetcher.LyricLayerTranslation)] = true
+		expected[f.GetLocalLyricsLayerPath(t.Artist, t.Title, fetcher.LyricLayerRomanization)] = true
+		expected[f.GetLocalCoverPath(t.Artist, t.Album)] = true
+	}
+
+	var orphans []OrphanedFile
+	add := func(p, kind string) {
+		var size int64
+		if info, err := os.Stat(p); err == nil {
+			size = info.Size()
+		}
+		orphans = append(orphans, OrphanedFile{Path: p, Kind: kind, Size: size})
+	}
+	collect := func(files []string, kind string) {
+		for _, p := range files {
+			if !expected[p] {
+				add(p, kind)
+			}
+		}
+	}
+	collect(lyrics, "lyrics")
+	collect(covers, "cover")
+
+	garbage, err := collectArtworkGarbage(h.db, true)
+	if err != nil {
+		return nil, err
+	}
+	store := artworkStore()
+	for _, art := range garbage.Removed {
+		add(store.Path(art.Hash), "cover")
+	}
+	for _, hash := range garbage.StrayFiles {
+		add(store.Path(hash), "cover")
+	}
+	return orphans, nil
+}
+
+// HealthReport 音乐库健康报告，每一类问题都附带曲目列表的链接
+func (h *MusicHandler) HealthReport(c *gin.Context) {
+	var total int64
+	h.db.Model(&models.Music{}).Count(&total)
+
+	categories := healthCategories()
+	results := make([]HealthCategoryResult, 0, len(categories))
+	for _, cat := range categories {
+		var count int64
+		cat.scope(h.db.Model(&models.Music{})).Count(&count)
+		results = append(results, HealthCategoryResult{
+			Name:        cat.Name,
+			Description: cat.Description,
+			Count:       count,
+			Link:        "/api/v1/library/health/tracks?category=" + cat.Name,
+		})
+	}
+
+	orphanSummary := gin.H{"lyrics": 0, "covers": 0, "bytes": int64(0)}
+	if orphans, err := h.findOrphanedFiles(); err == nil {
+		var lyrics, covers int
+		var size int64
+		for _, o := range orphans {
+			if o.Kind == "lyrics" {
+				lyrics++
+			} else {
+				covers++
+			}
+			size += o.Size
+		}
+		orphanSummary = gin.H{"lyrics": lyrics, "covers": covers, "bytes": size}
+	}
+	orphanSummary["link"] = "/api/v1/library/health/orphans"
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total_tracks":   total,
+			"min_bitrate":    config.GetConfig().Health.MinBitRate,
+			"categories":     results,
+			"orphaned_cache": orphanSummary,
+		},
+	})
+}
+
+// HealthTracks 列出某一类问题的曲目
+func (h *MusicHandler) HealthTracks(c *gin.Context) {
+	var q HealthTracksQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	var category *healthCategory
+	categories := healthCategories()
+	for i := range categories {
+		if categories[i].Name == q.Category {
+			category = &categories[i]
+			break
+		}
+	}
+	if category == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Unknown category: " + q.Category,
+		})
+		return
+	}
+
+	query := category.scope(h.db.Model(&models.Music{}))
+	var total int64
+	query.Count(&total)
+
+	var musicList []models.Music
+	query.Order("file_path").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&musicList)
+
+	responses := make([]models.MusicResponse, len(musicList))
+	for i := range musicList {
+		responses[i] = musicList[i].ToResponse()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    responses,
+		"total":   total,
+	})
+}
+
+// HealthOrphans 列出孤立的缓存文件
+func (h *MusicHandler) HealthOrphans(c *gin.Context) {
+	orphans, err := h.findOrphanedFiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to read cache: " + err.Error(),
+		})
+		return
+	}
+	if orphans == nil {
+		orphans = []OrphanedFile{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    orphans,
+		"total":   len(orphans),
+	})
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HistoryQuery 修改记录查询条件
+type HistoryQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	MusicID  uint   `form:"music_id"`
+	BatchID  string `form:"batch_id"`
+	Source   string `form:"source"`
+	User     string `form:"user"`
+	Field    string `form:"field"`
+}
+
+// RevertRequest 撤销修改，Force 为 true 时忽略字段已被再次修改的冲突
+type RevertRequest struct {
+	Force bool `json:"force"`
+}
+
+// newBatchID 生成一次操作的批次 ID
+func newBatchID() string {
+	return time.Now().Format("20060102150405.000000")
+}
+
+// requestUser 取请求方标识：X-User 头，未提供时使用客户端 IP
+func requestUser(c *gin.Context) string {
+	if user := strings.TrimSpace(c.GetHeader("X-User")); user != "" {
+		return user
+	}
+	return c.ClientIP()
+}
+
+// saveMusicWithHistory 保存曲目并为与 before 相比变化的每个字段写入 tag_changes 记录
+func saveMusicWithHistory(tx *gorm.DB, before, after *models.Music, source, user, batchID string) ([]models.TagFieldChange, error) {
+	// 本地缓存歌词以艺术家+标题命名，改名前先导入数据库
+	if after.ID != 0 && (before.Artist != after.Artist || before.Title != after.Title) {
+		imported, err := importCachedLyrics(tx, before)
+		if err != nil {
+			return nil, err
+		}
+		if imported != nil && before.HasLyrics == after.HasLyrics {
+			after.HasLyrics = true
+		}
+	}
+	// 旧的缓存封面以艺术家+专辑命名，同样先导入并关联到曲目
+	if after.ID != 0 && after.ArtworkID == nil && (before.Artist != after.Artist || before.Album != after.Album) {
+		art, err := importCachedCover(tx, before)
+		if err != nil {
+			return nil, err
+		}
+		if art != nil {
+			id := art.ID
+			after.ArtworkID = &id
+			if before.HasCover == after.HasCover {
+				after.HasCover = true
+				after.CoverMIME = art.MIME
+			}
+		}
+	}
+
+	changes := models.DiffTags(before, after)
+	if err := tx.Save(after).Error; err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	records := make([]models.TagChange, 0, len(changes))
+	for _, ch := range changes {
+		records = append(records, models.TagChange{
+			MusicID:  after.ID,
+			BatchID:  batchID,
+			Field:    ch.Field,
+			OldValue: ch.OldValue,
+			NewValue: ch.NewValue,
+			Source:   source,
+			User:     user,
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ListTagHistory 全局修改记录
+func (h *MusicHandler) ListTagHistory(c *gin.Context) {
+	var q HistoryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	h.respondTagHistory(c, q)
+}
+
+// GetMusicHistory 单个曲目的修改记录
+func (h *MusicHandler) GetMusicHistory(c *gin.Context) {
+	var q HistoryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	q.MusicID = uint(getInt(c.Param("id")))
+	h.respondTagHistory(c, q)
+}
+
+func (h *MusicHandler) respondTagHistory(c *gin.Context, q HistoryQuery) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	query := h.db.Model(&models.TagChange{})
+	if q.MusicID > 0 {
+		query = query.Where("music_id = ?", q.MusicID)
+	}
+	if q.BatchID != "" {
+		query = query.Where("batch_id = ?", q.BatchID)
+	}
+	if q.Source != "" {
+		query = query.Where("source = ?", q.Source)
+	}
+	if q.User != "" {
+		query = query.Where("user = ?", q.User)
+	}
+	if q.Field != "" {
+		query = query.Where("field = ?", q.Field)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var changes []models.TagChange
+	query.Order("id DESC").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&changes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    changes,
+		"total":   total,
+	})
+}
+
+// revertChanges 在事务中把记录对应的字段恢复为旧值，按 ID 倒序处理，返回冲突的记录
+func (h *MusicHandler) revertChanges(changes []models.TagChange, force bool, user string) ([]uint, string, error) {
+	byMusic := make(map[uint][]models.TagChange)
+	var order []uint
+	for _, ch := range changes {
+		if _, ok := byMusic[ch.MusicID]; !ok {
+			order = append(order, ch.MusicID)
+		}
+		byMusic[ch.MusicID] = append(byMusic[ch.MusicID], ch)
+	}
+
+	batchID := newBatchID()
+	var conflicts []uint
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, musicID := range order {
+			var music models.Music
+			if err := tx.First(&music, musicID).Error; err != nil {
+				return fmt.Errorf("music %d not found", musicID)
+			}
+			before := music
+
+			// 同一曲目同一字段可能在批次中被修改多次，倒序恢复到最早的旧值
+			for _, ch := range byMusic[musicID] {
+				current, err := music.TagValue(ch.Field)
+				if err != nil {
+					return err
+				}
+				if current != ch.NewValue && !force {
+					conflicts = append(conflicts, ch.ID)
+					continue
+				}
+				if err := music.SetTagValue(ch.Field, ch.OldValue); err != nil {
+					return err
+				}
+			}
+			if len(conflicts) > 0 {
+				continue
+			}
+
+			music.UpdatedAt = time.Now()
+			if _, err := saveMusicWithHistory(tx, &before, &music, models.TagSourceRevert, user, batchID); err != nil {
+				return err
+			}
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%d changes conflict with later edits", len(conflicts))
+		}
+
+		ids := make([]uint, len(changes))
+		for i, ch := range changes {
+			ids[i] = ch.ID
+		}
+		now := time.Now()
+		return tx.Model(&models.TagChange{}).Where("id IN ?", ids).Update("reverted_at", &now).Error
+	})
+	return conflicts, batchID, err
+}
+
+func (h *MusicHandler) respondRevert(c *gin.Context, changes []models.TagChange) {
+	var req RevertRequest
+	c.ShouldBindJSON(&req)
+
+	conflicts, batchID, err := h.revertChanges(changes, req.Force, requestUser(c))
+	if len(conflicts) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "Fields were modified after this change, use force to revert anyway",
+			"data":    gin.H{"conflicts": conflicts},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to revert: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Reverted %d changes", len(changes)),
+		"data":    gin.H{"batch_id": batchID, "reverted": len(changes)},
+	})
+}
+
+// RevertTagChange 撤销单条修改
+func (h *MusicHandler) RevertTagChange(c *gin.Context) {
+	var change models.TagChange
+	if err := h.db.First(&change, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Change not found",
+		})
+		return
+	}
+	if change.RevertedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Change already reverted",
+		})
+		return
+	}
+	h.respondRevert(c, []models.TagChange{change})
+}
+
+// RevertTagBatch 撤销一次操作中的全部修改
+func (h *MusicHandler) RevertTagBatch(c *gin.Context) {
+	var changes []models.TagChange
+	h.db.Where("batch_id = ? AND reverted_at IS NULL", c.Param("batch_id")).
+		Order("id DESC").Find(&changes)
+	if len(changes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "No revertible changes in batch",
+		})
+		return
+	}
+	h.respondRevert(c, changes)
+}
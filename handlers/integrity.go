@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"go-music-tag/parser"
+	"go-music-tag/webdav"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrityQuery 按完整性结论或具体问题筛选曲目
+type IntegrityQuery struct {
+	Status   string `form:"status"` // ok | warning | corrupt | unchecked
+	Issue    string `form:"issue"`  // 如 crc_error、sync_loss
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// BatchValidateRequest 批量完整性检查
+type BatchValidateRequest struct {
+	IDs           []uint `json:"ids"` // 为空时检查全部 MP3
+	OnlyUnchecked bool   `json:"only_unchecked"`
+}
+
+// validateTrack 下载源文件并重新检查完整性，只更新完整性相关字段
+func (h *MusicHandler) validateTrack(client *webdav.Client, music *models.Music) error {
+	if !strings.EqualFold(music.Format, "MP3") {
+		return fmt.Errorf("integrity check is not supported for %s files", music.Format)
+	}
+	data, err := client.GetFile(music.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+
+	music.Integrity = parser.ValidateMP3(data)
+	music.IntegrityStatus = music.Integrity.Status
+	return h.getDB().Model(music).
+		Select("integrity_status", "integrity").
+		Updates(music).Error
+}
+
+// ValidateMusic 重新检查单个曲目的完整性
+func (h *MusicHandler) ValidateMusic(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.validateTrack(client, &music); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    music.Integrity,
+	})
+}
+
+// BatchValidate 后台批量检查完整性，进度通过 /music/batch-status 查询
+func (h *MusicHandler) BatchValidate(c *gin.Context) {
+	var req BatchValidateRequest
+	c.ShouldBindJSON(&req)
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.getDB().Model(&models.Music{}).Where("scan_status = ? AND format = ?", "success", "MP3")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.OnlyUnchecked {
+		query = query.Where("integrity_status = '' OR integrity_status IS NULL")
+	}
+	var musicList []models.Music
+	if err := query.Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get music list: " + err.Error(),
+		})
+		return
+	}
+
+	if len(musicList) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "No music to validate",
+			"data":    gin.H{"total": 0},
+		})
+		return
+	}
+
+	statusMutex.Lock()
+	if batchStatus.Running {
+		statusMutex.Unlock()
+		c.JSON(StatusBusy, gin.H{
+			"code":    409,
+			"message": "Another batch task is running",
+		})
+		return
+	}
+	batchStatus = &BatchStatus{
+		Running:   true,
+		TaskType:  "integrity",
+		Total:     len(musicList),
+		Message:   "Starting...",
+		CreatedAt: time.Now(),
+	}
+	statusMutex.Unlock()
+
+	go func() {
+		success, failed, corrupt := 0, 0, 0
+		for i := range musicList {
+			music := &musicList[i]
+
+			statusMutex.Lock()
+			batchStatus.Current = i + 1
+			batchStatus.Message = fmt.Sprintf("Validating: %s", music.FileName)
+			statusMutex.Unlock()
+
+			if err := h.validateTrack(client, music); err != nil {
+				failed++
+				log.Printf("[Integrity] ❌ %s: %v", music.FileName, err)
+			} else {
+				success++
+				if music.IntegrityStatus == models.IntegrityCorrupt {
+					corrupt++
+				}
+			}
+
+			statusMutex.Lock()
+			batchStatus.Success = success
+			batchStatus.Failed = failed
+			statusMutex.Unlock()
+		}
+
+		statusMutex.Lock()
+		batchStatus.Running = false
+		batchStatus.Message = fmt.Sprintf("Completed, %d corrupt", corrupt)
+		statusMutex.Unlock()
+		log.Printf("[Integrity] 🎉 Batch done: total=%d, success=%d, failed=%d, corrupt=%d",
+			len(musicList), success, failed, corrupt)
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Integrity check started",
+		"data":    gin.H{"total": len(musicList)},
+	})
+}
+
+// ListIntegrity 按完整性结论列出曲目，用于查找需要替换的损坏文件
+func (h *MusicHandler) ListIntegrity(c *gin.Context) {
+	var q IntegrityQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	query := h.db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	switch q.Status {
+	case "":
+		query = query.Where("integrity_status IN ?", []string{models.IntegrityWarning, models.IntegrityCorrupt})
+	case "unchecked":
+		query = query.Where("integrity_status = '' OR integrity_status IS NULL")
+	default:
+		query = query.Where("integrity_status = ?", q.Status)
+	}
+	if q.Issue != "" {
+		query = query.Where("integrity LIKE ?", `%"`+q.Issue+`"%`)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var musicList []models.Music
+	query.Order("file_path").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&musicList)
+
+	responses := make([]models.MusicResponse, len(musicList))
+	for i := range musicList {
+		responses[i] = musicList[i].ToResponse()
+	}
+
+	var counts []struct {
+		IntegrityStatus string `json:"status"`
+		Count           int64  `json:"count"`
+	}
+	h.db.Model(&models.Music{}).Select("integrity_status, COUNT(*) AS count").
+		Where("scan_status = ?", "success").Group("integrity_status").Scan(&counts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    responses,
+		"total":   total,
+		"summary": counts,
+	})
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/loudness"
+	"go-music-tag/models"
+	"go-music-tag/tagwriter"
+	"go-music-tag/webdav"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyzeLoudnessRequest 响度分析请求，WriteTags 为空时使用配置
+type AnalyzeLoudnessRequest struct {
+	IDs         []uint `json:"ids"`
+	Album       string `json:"album"`
+	Artist      string `json:"artist"`
+	OnlyMissing bool   `json:"only_missing"` // 只分析尚未测量响度的曲目
+	WriteTags   *bool  `json:"write_tags"`
+}
+
+// roundGain 增益与峰值保留的精度与标签一致
+func roundGain(v float64, digits int) *float64 {
+	p := math.Pow(10, float64(digits))
+	r := math.Round(v*p) / p
+	return &r
+}
+
+// writeReplayGainTags 把 ReplayGain 字段写入 TXXX:REPLAYGAIN_* 帧
+func (h *MusicHandler) writeReplayGainTags(music *models.Music) error {
+	if !writableFormats[strings.ToUpper(music.Format)] {
+		return fmt.Errorf("writing tags is not supported for %s files", music.Format)
+	}
+	format := func(v *float64, gain bool) string {
+		switch {
+		case v == nil:
+			return ""
+		case gain:
+			return fmt.Sprintf("%+.2f dB", *v)
+		default:
+			return strconv.FormatFloat(*v, 'f', 6, 64)
+		}
+	}
+	return h.rewriteTag(music, func(tag *tagwriter.Tag) error {
+		tag.SetUserText("REPLAYGAIN_TRACK_GAIN", format(music.ReplayGainTrackGain, true))
+		tag.SetUserText("REPLAYGAIN_TRACK_PEAK", format(music.ReplayGainTrackPeak, false))
+		tag.SetUserText("REPLAYGAIN_ALBUM_GAIN", format(music.ReplayGainAlbumGain, true))
+		tag.SetUserText("REPLAYGAIN_ALBUM_PEAK", format(music.ReplayGainAlbumPeak, false))
+		return nil
+	})
+}
+
+// analyzeTrack 下载并测量单个曲目，结果写入 music 的 Loudness 与曲目增益/峰值
+func analyzeTrack(client *webdav.Client, analyzer *loudness.Analyzer, music *models.Music) error {
+	data, err := client.GetFile(music.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	result, err := analyzer.Analyze(data, filepath.Ext(music.FilePath))
+	if err != nil {
+		return err
+	}
+	music.Loudness = roundGain(result.Integrated, 2)
+	music.ReplayGainTrackGain = roundGain(result.Gain(), 2)
+	music.ReplayGainTrackPeak = roundGain(result.Peak(), 6)
+	return nil
+}
+
+// albumLoudness 用专辑中已测量曲目的响度计算专辑增益与峰值
+func albumLoudness(tracks []models.Music) (gain, peak *float64) {
+	var results []loudness.Result
+	var durations []float64
+	for _, t := range tracks {
+		if t.Loudness == nil {
+			continue
+		}
+		r := loudness.Result{Integrated: *t.Loudness}
+		if t.ReplayGainTrackPeak != nil && *t.ReplayGainTrackPeak > 0 {
+			r.TruePeak = 20 * math.Log10(*t.ReplayGainTrackPeak)
+		}
+		results = append(results, r)
+		durations = append(durations, float64(t.Duration))
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	album := loudness.AlbumResult(results, durations)
+	return roundGain(album.Gain(), 2), roundGain(album.Peak(), 6)
+}
+
+// AnalyzeLoudness 后台测量响度并计算曲目/专辑增益，进度通过 /music/batch-status 查询
+func (h *MusicHandler) AnalyzeLoudness(c *gin.Context) {
+	var req AnalyzeLoudnessRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+	writeTags := config.GetConfig().Loudness.WriteTags
+	if req.WriteTags != nil {
+		writeTags = *req.WriteTags
+	}
+
+	analyzer := loudness.NewAnalyzer(config.GetConfig().Loudness.FfmpegPath)
+	if err := analyzer.Available(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.getDB().Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ?", req.Artist)
+	}
+	if req.OnlyMissing {
+		query = query.Where("loudness IS NULL")
+	}
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get music list: " + err.Error(),
+		})
+		return
+	}
+
+	if len(musicList) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "No music to analyze",
+			"data":    gin.H{"total": 0},
+		})
+		return
+	}
+
+	statusMutex.Lock()
+	if batchStatus.Running {
+		statusMutex.Unlock()
+		c.JSON(StatusBusy, gin.H{
+			"code":    409,
+			"message": "Another batch task is running",
+		})
+		return
+	}
+	batchStatus = &BatchStatus{
+		Running:   true,
+		TaskType:  "loudness",
+		Total:     len(musicList),
+		Message:   "Starting...",
+		CreatedAt: time.Now(),
+	}
+	statusMutex.Unlock()
+
+	user, batchID := requestUser(c), newBatchID()
+	go h.runLoudnessAnalysis(client, analyzer, musicList, writeTags, user, batchID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Loudness analysis started",
+		"data":    gin.H{"total": len(musicList), "batch_id": batchID},
+	})
+}
+
+func (h *MusicHandler) runLoudnessAnalysis(client *webdav.Client, analyzer *loudness.Analyzer, musicList []models.Music, writeTags bool, user, batchID string) {
+	db := h.getDB()
+	success, failed := 0, 0
+	albums := make(map[AlbumRef]bool)
+	var singles []uint
+
+	// 1. 逐首测量
+	for i := range musicList {
+		music := &musicList[i]
+		before := *music
+
+		statusMutex.Lock()
+		batchStatus.Current = i + 1
+		batchStatus.Message = fmt.Sprintf("Analyzing: %s", music.FileName)
+		statusMutex.Unlock()
+
+		if err := analyzeTrack(client, analyzer, music); err != nil {
+			failed++
+			log.Printf("[Loudness] ❌ %s: %v", music.FileName, err)
+		} else if _, err := saveMusicWithHistory(db, &before, music, models.TagSourceAnalysis, user, batchID); err != nil {
+			failed++
+			log.Printf("[Loudness] ❌ Failed to save %s: %v", music.FileName, err)
+		} else {
+			success++
+			if music.Album != "" {
+				albums[AlbumRef{Folder: path.Dir(music.FilePath), Album: music.Album}] = true
+			} else {
+				singles = append(singles, music.ID)
+			}
+		}
+
+		statusMutex.Lock()
+		batchStatus.Success = success
+		batchStatus.Failed = failed
+		statusMutex.Unlock()
+	}
+
+	// 2. 按专辑汇总，未选中的同专辑曲目使用已保存的测量结果
+	statusMutex.Lock()
+	batchStatus.Message = "Calculating album gain"
+	statusMutex.Unlock()
+
+	var changed []models.Music
+	for ref := range albums {
+		tracks, err := h.loadAlbumTracks(ref)
+		if err != nil {
+			continue
+		}
+		gain, peak := albumLoudness(tracks)
+		for _, t := range tracks {
+			before := t
+			t.ReplayGainAlbumGain, t.ReplayGainAlbumPeak = gain, peak
+			if _, err := saveMusicWithHistory(db, &before, &t, models.TagSourceAnalysis, user, batchID); err != nil {
+				log.Printf("[Loudness] ❌ Failed to save album gain for %s: %v", t.FileName, err)
+				continue
+			}
+			if t.Loudness != nil {
+				changed = append(changed, t)
+			}
+		}
+	}
+	if len(singles) > 0 {
+		var list []models.Music
+		db.Where("id IN ?", singles).Find(&list)
+		changed = append(changed, list...)
+	}
+
+	// 3. 写入 REPLAYGAIN_* 标签
+	writeFailed := 0
+	if writeTags {
+		for i := range changed {
+			music := &changed[i]
+			statusMutex.Lock()
+			batchStatus.Message = fmt.Sprintf("Writing tags: %s", music.FileName)
+			statusMutex.Unlock()
+
+			if err := h.writeReplayGainTags(music); err != nil {
+				writeFailed++
+				log.Printf("[Loudness] ❌ Failed to write tags for %s: %v", music.FileName, err)
+				continue
+			}
+			db.Model(music).Update("file_size", music.FileSize)
+		}
+	}
+
+	statusMutex.Lock()
+	batchStatus.Running = false
+	batchStatus.Message = fmt.Sprintf("Completed, %d albums, %d tag writes failed", len(albums), writeFailed)
+	statusMutex.Unlock()
+	log.Printf("[Loudness] 🎉 Batch done: total=%d, success=%d, failed=%d, albums=%d",
+		len(musicList), success, failed, len(albums))
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-music-tag/fetcher"
+	"go-music-tag/lyrics"
+	"go-music-tag/models"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 未入库歌词的来源，只出现在接口返回中
+const (
+	lyricsSourceEmbedded = "embedded" // 音频文件内嵌
+	lyricsSourceFile     = "file"     // 同名 .lrc 文件
+)
+
+// UpdateLyricsRequest 手动编辑歌词，content 为空字符串表示清除；
+// translation/romanization 省略时沿用当前版本
+type UpdateLyricsRequest struct {
+	Content      *string `json:"content" binding:"required"`
+	Translation  *string `json:"translation"`
+	Romanization *string `json:"romanization"`
+	Language     string  `json:"language"`
+	Note         string  `json:"note"`
+}
+
+// AdjustLyricsRequest 整体平移时间轴，正值推迟、负值提前
+type AdjustLyricsRequest struct {
+	OffsetMs int    `json:"offset_ms"`
+	Note     string `json:"note"`
+}
+
+// readCachedLyrics 读取以艺术家+标题命名的本地缓存歌词及其翻译、罗马音
+func readCachedLyrics(f *fetcher.Fetcher, artist, title string) (content, translation, romanization string) {
+	detector := configuredCharsetDetector()
+	read := func(p string) string {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return ""
+		}
+		return detector.DecodeBytes(data).Text
+	}
+	content = read(f.GetLocalLyricsPath(artist, title))
+	if content == "" {
+		return "", "", ""
+	}
+	translation = read(f.GetLocalLyricsLayerPath(artist, title, fetcher.LyricLayerTranslation))
+	romanization = read(f.GetLocalLyricsLayerPath(artist, title, fetcher.LyricLayerRomanization))
+	return content, translation, romanization
+}
+
+// currentLyrics 曲目当前使用的歌词版本，没有入库的歌词时返回 nil
+func currentLyrics(db *gorm.DB, musicID uint) (*models.Lyrics, error) {
+	var entry models.Lyrics
+	err := db.Where("music_id = ? AND current = ?", musicID, true).Order("version DESC").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// createLyricsVersion 新增一个版本并设为当前版本，Synced 和缺省的语言由歌词内容推断
+func createLyricsVersion(tx *gorm.DB, entry *models.Lyrics) error {
+	var latest int
+	if err := tx.Model(&models.Lyrics{}).Where("music_id = ?", entry.MusicID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Lyrics{}).Where("music_id = ? AND current = ?", entry.MusicID, true).
+		Update("current", false).Error; err != nil {
+		return err
+	}
+
+	parsed := lyrics.Parse(entry.Content)
+	entry.ID = 0
+	entry.Version = latest + 1
+	entry.Current = true
+	entry.Synced = parsed.Synced
+	if entry.Language == "" {
+		entry.Language = parsed.Metadata.Language
+	}
+	return tx.Create(entry).Error
+}
+
+// lyricsTagSource 歌词来源对应的 has_lyrics 修改来源
+func lyricsTagSource(source string) string {
+	switch source {
+	case models.LyricsSourceFetch, models.LyricsSourceCache:
+		return models.TagSourceFetch
+	case models.LyricsSourceRevert:
+		return models.TagSourceRevert
+	default:
+		return models.TagSourceManual
+	}
+}
+
+// saveLyricsVersion 保存新版本，并让曲目的 has_lyrics 与歌词是否为空保持一致
+func saveLyricsVersion(tx *gorm.DB, music *models.Music, entry *models.Lyrics, user, batchID string) error {
+	entry.MusicID = music.ID
+	entry.User = user
+	if err := createLyricsVersion(tx, entry); err != nil {
+		return err
+	}
+
+	hasLyrics := strings.TrimSpace(entry.Content) != ""
+	if music.HasLyrics == hasLyrics {
+		return nil
+	}
+	before := *music
+	music.HasLyrics = hasLyrics
+	_, err := saveMusicWithHistory(tx, &before, music, lyricsTagSource(entry.Source), user, batchID)
+	return err
+}
+
+// importCachedLyrics 曲目还没有入库的歌词时导入旧的 md5 缓存文件，不修改 has_lyrics。
+// 缓存文件按艺术家+标题命名，修改这两个标签前必须先导入，否则歌词会与曲目失去关联
+func importCachedLyrics(tx *gorm.DB, music *models.Music) (*models.Lyrics, error) {
+	var count int64
+	if err := tx.Model(&models.Lyrics{}).Where("music_id = ?", music.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	f := getFetcher()
+	content, translation, romanization := readCachedLyrics(f, music.Artist, music.Title)
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	entry := &models.Lyrics{
+		MusicID:      music.ID,
+		Content:      content,
+		Translation:  translation,
+		Romanization: romanization,
+		Source:       models.LyricsSourceCache,
+		Note:         "Imported from local cache",
+	}
+	if err := createLyricsVersion(tx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// saveFetchedLyrics 把获取到的歌词保存为新版本，内容与当前版本相同时只同步 has_lyrics
+func saveFetchedLyrics(db *gorm.DB, music *models.Music, content, translation, romanization, user, batchID string) error {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		current, err := currentLyrics(tx, music.ID)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.Content == content &&
+			current.Translation == translation && current.Romanization == romanization {
+			if music.HasLyrics {
+				return nil
+			}
+			before := *music
+			music.HasLyrics = true
+			_, err := saveMusicWithHistory(tx, &before, music, models.TagSourceFetch, user, batchID)
+			return err
+		}
+		return saveLyricsVersion(tx, music, &models.Lyrics{
+			Content:      content,
+			Translation:  translation,
+			Romanization: romanization,
+			Source:       models.LyricsSourceFetch,
+		}, user, batchID)
+	})
+}
+
+// loadLyrics 依次读取数据库中的当前版本、旧的本地缓存 (读取时导入数据库)、嵌入式歌词和同名 .lrc 文件。
+// 当前版本内容为空表示歌词已被清除，不再回退到文件；没有任何歌词时返回 nil
+func (h *MusicHandler) loadLyrics(music models.Music) *models.Lyrics {
+	current, err := currentLyrics(h.db, music.ID)
+	if err == nil && current != nil {
+		if strings.TrimSpace(current.Content) == "" {
+			return nil
+		}
+		return current
+	}
+
+	var imported *models.Lyrics
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		entry, err := importCachedLyrics(tx, &music)
+		imported = entry
+		if err != nil || entry == nil || music.HasLyrics {
+			return err
+		}
+		before := music
+		music.HasLyrics = true
+		_, err = saveMusicWithHistory(tx, &before, &music, models.TagSourceFetch, "", newBatchID())
+		return err
+	})
+	if err == nil && imported != nil {
+		return imported
+	}
+
+	if text := h.getEmbeddedLyrics(music); text != "" {
+		return &models.Lyrics{MusicID: music.ID, Content: text, Source: lyricsSourceEmbedded, Synced: lyrics.Parse(text).Synced}
+	}
+	if text := h.getExternalLyrics(music); text != "" {
+		return &models.Lyrics{MusicID: music.ID, Content: text, Source: lyricsSourceFile, Synced: lyrics.Parse(text).Synced}
+	}
+	return nil
+}
+
+// ExportLyrics 以 LRC、SRT、WebVTT 或 TTML 格式下载歌词，layers=true 时附带翻译和罗马音
+func (h *MusicHandler) ExportLyrics(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	entry := h.loadLyrics(music)
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "No lyrics available",
+		})
+		return
+	}
+
+	parsed := lyrics.Parse(entry.Content)
+	if c.Query("layers") == "true" {
+		parsed.MergeLayer(lyrics.Parse(entry.Translation), lyrics.LayerTranslation)
+		parsed.MergeLayer(lyrics.Parse(entry.Romanization), lyrics.LayerRomanization)
+	}
+	// 原文没有标题时使用曲目标签
+	if parsed.Metadata.Title == "" {
+		parsed.Metadata.Title = music.Title
+	}
+	if parsed.Metadata.Artist == "" {
+		parsed.Metadata.Artist = music.Artist
+	}
+	if parsed.Metadata.Language == "" {
+		parsed.Metadata.Language = entry.Language
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", lyrics.FormatLRC))
+	content, mime, err := parsed.Export(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    gin.H{"formats": lyrics.Formats()},
+		})
+		return
+	}
+
+	name := strings.TrimSuffix(music.FileName, path.Ext(music.FileName)) + "." + format
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name)))
+	c.Data(http.StatusOK, mime, []byte(content))
+}
+
+// UpdateLyrics 手动保存歌词为新版本，content 为空时清除歌词
+func (h *MusicHandler) UpdateLyrics(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var req UpdateLyricsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	entry := &models.Lyrics{
+		Content:  strings.TrimPrefix(*req.Content, "\ufeff"),
+		Source:   models.LyricsSourceManual,
+		Language: req.Language,
+		Note:     req.Note,
+	}
+	if strings.TrimSpace(entry.Content) != "" {
+		// 省略的翻译与罗马音沿用当前版本
+		if previous := h.loadLyrics(music); previous != nil {
+			entry.Translation, entry.Romanization = previous.Translation, previous.Romanization
+			if entry.Language == "" {
+				entry.Language = previous.Language
+			}
+		}
+		if req.Translation != nil {
+			entry.Translation = *req.Translation
+		}
+		if req.Romanization != nil {
+			entry.Romanization = *req.Romanization
+		}
+		// loadLyrics 可能刚导入缓存并修改了 has_lyrics
+		h.db.First(&music, music.ID)
+	}
+
+	h.saveLyrics(c, &music, entry, "Lyrics saved")
+}
+
+// AdjustLyricsTiming 把原文、翻译和罗马音的所有时间标签平移 offset_ms 毫秒并保存为新版本
+func (h *MusicHandler) AdjustLyricsTiming(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var req AdjustLyricsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.OffsetMs == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "offset_ms must be a non-zero number of milliseconds",
+		})
+		return
+	}
+
+	current := h.loadLyrics(music)
+	if current == nil || !current.Synced {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "No synced lyrics to adjust",
+		})
+		return
+	}
+	h.db.First(&music, music.ID)
+
+	seconds := float64(req.OffsetMs) / 1000
+	note := req.Note
+	if note == "" {
+		note = fmt.Sprintf("Shifted timing by %+d ms", req.OffsetMs)
+	}
+	entry := &models.Lyrics{
+		Content:      lyrics.ShiftText(current.Content, seconds),
+		Translation:  lyrics.ShiftText(current.Translation, seconds),
+		Romanization: lyrics.ShiftText(current.Romanization, seconds),
+		Source:       models.LyricsSourceAdjust,
+		Language:     current.Language,
+		Note:         note,
+	}
+	h.saveLyrics(c, &music, entry, "Lyrics timing adjusted")
+}
+
+// GetLyricsVersions 列出曲目的全部歌词版本，最新的在前
+func (h *MusicHandler) GetLyricsVersions(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var versions []models.Lyrics
+	if err := h.db.Where("music_id = ?", music.ID).Order("version DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load lyrics versions: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total": len(versions),
+			"list":  versions,
+		},
+	})
+}
+
+// RestoreLyricsVersion 以指定历史版本的内容创建新版本
+func (h *MusicHandler) RestoreLyricsVersion(c *gin.Context) {
+	var music models.Music
+	if err := h.db.First(&music, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	var old models.Lyrics
+	if err == nil {
+		err = h.db.Where("music_id = ? AND version = ?", music.ID, version).First(&old).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Lyrics version not found",
+		})
+		return
+	}
+
+	entry := &models.Lyrics{
+		Content:      old.Content,
+		Translation:  old.Translation,
+		Romanization: old.Romanization,
+		Source:       models.LyricsSourceRevert,
+		Language:     old.Language,
+		Note:         fmt.Sprintf("Restored from version %d", old.Version),
+	}
+	h.saveLyrics(c, &music, entry, "Lyrics version restored")
+}
+
+// saveLyrics 在事务中保存新版本并返回该版本
+func (h *MusicHandler) saveLyrics(c *gin.Context, music *models.Music, entry *models.Lyrics, message string) {
+	user := requestUser(c)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return saveLyricsVersion(tx, music, entry, user, newBatchID())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to save lyrics: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"lyrics":     entry,
+			"has_lyrics": music.HasLyrics,
+		},
+	})
+}
@@ -2,20 +2,18 @@ package handlers
 
 import (
 	"bytes"
-	"errors" // ✅ 新增
 	"fmt"
 	"go-music-tag/database"
-	"go-music-tag/fetcher"
+	"go-music-tag/lyrics"
 	"go-music-tag/models"
 	"go-music-tag/parser"
+	"go-music-tag/scheduler"
 	"go-music-tag/webdav"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,6 +30,7 @@ type MusicHandler struct {
 	dav      *webdav.Client
 	davMutex sync.RWMutex
 	davReady bool
+	sched    *scheduler.Scheduler
 }
 
 type ScanRequest struct {
@@ -54,12 +53,14 @@ type MusicListResponse struct {
 	Size  int                    `json:"page_size"`
 }
 
+// WebDAVConfigRequest 密码字段只写不读：留空表示保持原密码，ClearPassword 显式清除
 type WebDAVConfigRequest struct {
-	URL      string `json:"url" binding:"required"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	RootPath string `json:"root_path"`
-	Enabled  bool   `json:"enabled"`
+	URL           string `json:"url" binding:"required"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	ClearPassword bool   `json:"clear_password"`
+	RootPath      string `json:"root_path"`
+	Enabled       bool   `json:"enabled"`
 }
 
 type UpdateMusicRequest struct {
@@ -73,6 +74,31 @@ type UpdateMusicRequest struct {
 	TrackNumber int    `json:"track_number"`
 	DiscNumber  int    `json:"disc_number"`
 	Comment     string `json:"comment"`
+
+	MBRecordingID    string `json:"mb_recording_id"`
+	MBTrackID        string `json:"mb_track_id"`
+	MBReleaseID      string `json:"mb_release_id"`
+	MBReleaseGroupID string `json:"mb_release_group_id"`
+	MBArtistID       string `json:"mb_artist_id"`
+	MBAlbumArtistID  string `json:"mb_album_artist_id"`
+
+	FeaturedArtists     string   `json:"featured_artists"`
+	ISRC                string   `json:"isrc"`
+	Label               string   `json:"label"`
+	CatalogNumber       string   `json:"catalog_number"`
+	BPM                 int      `json:"bpm"`
+	InitialKey          string   `json:"initial_key"`
+	ReleaseType         string   `json:"release_type"`
+	OriginalYear        int      `json:"original_year"`
+	ArtistSort          string   `json:"artist_sort"`
+	AlbumSort           string   `json:"album_sort"`
+	TitleSort           string   `json:"title_sort"`
+	AlbumArtistSort     string   `json:"album_artist_sort"`
+	ComposerSort        string   `json:"composer_sort"`
+	ReplayGainTrackGain *float64 `json:"replaygain_track_gain"`
+	ReplayGainTrackPeak *float64 `json:"replaygain_track_peak"`
+	ReplayGainAlbumGain *float64 `json:"replaygain_album_gain"`
+	ReplayGainAlbumPeak *float64 `json:"replaygain_album_peak"`
 }
 
 type BatchUpdateRequest struct {
@@ -93,7 +119,8 @@ type BatchStatus struct {
 	Success   int       `json:"success"`
 	Failed    int       `json:"failed"`
 	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"` // 可选
+	CreatedAt time.Time `json:"created_at"`       // 可选
+	JobID     string    `json:"job_id,omitempty"` // 有逐曲结果记录的任务，见 GetFetchJobResults
 }
 
 var (
@@ -118,7 +145,7 @@ func (h *MusicHandler) getDB() *gorm.DB {
 func NewMusicHandler() (*MusicHandler, error) {
 	return &MusicHandler{
 		db:       database.GetDB(),
-		parser:   parser.NewMP3Parser(),
+		parser:   parser.NewMP3ParserWithPatterns(configuredPathPatterns()).WithCharset(configuredCharsetDetector()),
 		dav:      nil,
 		davReady: false,
 	}, nil
@@ -127,7 +154,7 @@ func NewMusicHandler() (*MusicHandler, error) {
 func NewMusicHandlerLazy() *MusicHandler {
 	return &MusicHandler{
 		db:       database.GetDB(),
-		parser:   parser.NewMP3Parser(),
+		parser:   parser.NewMP3ParserWithPatterns(configuredPathPatterns()).WithCharset(configuredCharsetDetector()),
 		dav:      nil,
 		davReady: false,
 	}
@@ -159,7 +186,12 @@ func (h *MusicHandler) getWebDAVClient() (*webdav.Client, error) {
 		return nil, fmt.Errorf("WebDAV is disabled")
 	}
 
-	client := webdav.NewClientNoCheck(dbConfig.URL, dbConfig.Username, dbConfig.Password, dbConfig.RootPath)
+	password, err := dbConfig.PlainPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt WebDAV password: %w", err)
+	}
+
+	client := webdav.NewClientNoCheck(dbConfig.URL, dbConfig.Username, password, dbConfig.RootPath)
 
 	h.dav = client
 	h.davReady = true
@@ -189,8 +221,23 @@ func (h *MusicHandler) Scan(c *gin.Context) {
 	}
 	statusMutex.Unlock()
 
-	// ✅ 修复：使用 TRUNCATE 或 DELETE 清空旧数据
-	db.Exec("DELETE FROM music")
+	// 扫描 (包括定时的增量扫描) 同一时间只能有一个，定时任务也据此避开手动扫描
+	taskID := time.Now().Format("20060102150405")
+	if !claimScan(taskID) {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "A scan is already running",
+		})
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			releaseScan()
+		}
+	}()
+
+	// 曲目按 file_path 更新而不是清空重建，保持 ID 不变，歌词、修改历史和封面引用才不会失效
 	db.Exec("DELETE FROM scan_logs")
 
 	var cfg models.WebDAVConfig
@@ -199,7 +246,13 @@ func (h *MusicHandler) Scan(c *gin.Context) {
 		return
 	}
 
-	client, err := webdav.NewClientWithConfig(cfg.URL, cfg.Username, cfg.Password, cfg.RootPath)
+	password, err := cfg.PlainPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to decrypt WebDAV password"})
+		return
+	}
+
+	client, err := webdav.NewClientWithConfig(cfg.URL, cfg.Username, password, cfg.RootPath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
 		return
@@ -211,72 +264,12 @@ func (h *MusicHandler) Scan(c *gin.Context) {
 		return
 	}
 
-	taskID := time.Now().Format("20060102150405")
-	total := len(files)
-	success := 0
-	failed := 0
-
+	started = true
 	go func() {
-		for i, file := range files {
-			log.Printf("Processing [%d/%d]: %s", i+1, total, file.Name)
-			h.logScan(taskID, fmt.Sprintf("Processing [%d/%d]: %s", i+1, total, file.Name), "info")
-
-			data, err := client.GetFile(file.Path)
-			if err != nil {
-				log.Printf("Failed to get file %s: %v", file.Name, err)
-				h.logScan(taskID, fmt.Sprintf("Failed to get file %s: %v", file.Name, err), "error")
-				failed++
-				continue
-			}
-
-			metadata, err := tag.ReadFrom(strings.NewReader(string(data)))
-
-			scanTime := time.Now()
-			music := models.Music{
-				FilePath:   file.Path,
-				FileName:   file.Name,
-				FileSize:   file.Size,
-				ScanStatus: "success",
-				ScannedAt:  &scanTime,
-			}
-
-			if err == nil && metadata != nil {
-				music.Title = metadata.Title()
-				music.Artist = metadata.Artist()
-				music.Album = metadata.Album()
-				music.Year = metadata.Year()
-				music.Genre = metadata.Genre()
-				music.Duration = 0
-
-				picture := metadata.Picture()
-				if picture != nil && len(picture.Data) > 0 {
-					music.HasCover = true
-					music.CoverMIME = "image/jpeg"
-				}
-			} else {
-				music.Title = file.Name
-			}
-
-			// ✅ 修复：使用 FirstOrCreate 避免唯一约束冲突
-			var existing models.Music
-			result := db.Where("file_path = ?", file.Path).First(&existing)
-			if result.Error == nil {
-				// 文件已存在，更新
-				db.Model(&existing).Updates(music)
-			} else {
-				// 新文件，插入
-				db.Create(&music)
-			}
-
-			if result.Error == nil || errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				success++
-			} else {
-				log.Printf("Failed to save %s: %v", file.Name, result.Error)
-				h.logScan(taskID, fmt.Sprintf("Failed to save %s: %v", file.Name, result.Error), "error")
-				failed++
-			}
+		defer releaseScan()
+		if _, err := h.scanLibrary(taskID, client, files, false); err != nil {
+			h.logScan(taskID, fmt.Sprintf("Scan failed: %v", err), "error")
 		}
-		h.logScan(taskID, fmt.Sprintf("Scan completed. Total: %d, Success: %d, Failed: %d", total, success, failed), "info")
 	}()
 
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Scan started", "task_id": taskID})
@@ -349,18 +342,205 @@ func (h *MusicHandler) saveMusic(music *models.Music) error {
 		Error
 }
 
+// saveFailedMusic 记录读取或解析失败的文件；已有曲目保留 ID 和上次的标签，只标记为失败
 func (h *MusicHandler) saveFailedMusic(file webdav.FileInfo, errMsg string) {
 	now := time.Now()
 	music := &models.Music{
-		FilePath:   file.Path,
-		FileName:   file.Name,
-		FileSize:   file.Size,
-		ScanStatus: "failed",
-		ScanError:  errMsg,
-		ScannedAt:  &now,
-	}
-	h.db.Where("file_path = ?", music.FilePath).
-		FirstOrCreate(music, music)
+		FilePath: file.Path,
+		FileName: file.Name,
+	}
+	err := h.getDB().Where(models.Music{FilePath: file.Path}).
+		Assign(map[string]interface{}{
+			"file_size":   file.Size,
+			"scan_status": "failed",
+			"scan_error":  errMsg,
+			"scanned_at":  &now,
+		}).
+		FirstOrCreate(music).Error
+	if err != nil {
+		log.Printf("[Scan] ⚠️ Failed to record scan error for %s: %v", file.Path, err)
+	}
+}
+
+// claimScan 标记扫描开始，已有扫描在运行时返回 false
+func claimScan(taskID string) bool {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	if scanTaskID != "" {
+		return false
+	}
+	scanTaskID = taskID
+	return true
+}
+
+// releaseScan 标记扫描结束
+func releaseScan() {
+	scanMutex.Lock()
+	scanTaskID = ""
+	scanMutex.Unlock()
+}
+
+// scanRunning 是否有扫描正在运行
+func scanRunning() bool {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	return scanTaskID != ""
+}
+
+// scanSummary 一次扫描的统计
+type scanSummary struct {
+	incremental                                       bool
+	files, added, updated, unchanged, failed, removed int
+	missing                                           int // 增量扫描时源文件已不存在的曲目
+}
+
+func (s scanSummary) String() string {
+	if s.incremental {
+		return fmt.Sprintf("Incremental scan completed. Files: %d, Added: %d, Updated: %d, Unchanged: %d, Failed: %d, Missing: %d",
+			s.files, s.added, s.updated, s.unchanged, s.failed, s.missing)
+	}
+	return fmt.Sprintf("Scan completed. Files: %d, Added: %d, Updated: %d, Failed: %d, Removed: %d",
+		s.files, s.added, s.updated, s.failed, s.removed)
+}
+
+// scanLibrary 解析文件并按 file_path 写入曲目，已有曲目保留 ID 只更新标签。
+// 增量扫描跳过大小未变且上次解析成功的文件，不删除源文件已不存在的曲目；
+// 完整扫描重新解析所有文件，并删除源文件已不存在的曲目及其关联记录
+func (h *MusicHandler) scanLibrary(taskID string, client *webdav.Client, files []webdav.FileInfo, incremental bool) (scanSummary, error) {
+	summary := scanSummary{incremental: incremental, files: len(files)}
+	db := h.getDB()
+
+	var existing []models.Music
+	if err := db.Select("id", "file_path", "file_size", "scan_status", "artwork_id").Find(&existing).Error; err != nil {
+		return summary, err
+	}
+	known := make(map[string]models.Music, len(existing))
+	for _, m := range existing {
+		known[m.FilePath] = m
+	}
+
+	listed := make(map[string]bool, len(files))
+	for i, file := range files {
+		listed[file.Path] = true
+		old, ok := known[file.Path]
+		if incremental && ok && old.ScanStatus == "success" && old.FileSize == file.Size {
+			summary.unchanged++
+			continue
+		}
+
+		h.logScan(taskID, fmt.Sprintf("Processing [%d/%d]: %s", i+1, len(files), file.Name), "info")
+		data, err := client.GetFile(file.Path)
+		if err != nil {
+			summary.failed++
+			h.logScan(taskID, fmt.Sprintf("Failed to get file %s: %v", file.Name, err), "error")
+			h.saveFailedMusic(file, err.Error())
+			continue
+		}
+
+		// 使用解析器读取完整标签 (含 MusicBrainz 标识等扩展标签)
+		music, err := h.parser.Parse(data, file.Path, file.Name, file.Size)
+		if err != nil {
+			summary.failed++
+			h.logScan(taskID, fmt.Sprintf("Failed to parse %s: %v", file.Name, err), "error")
+			h.saveFailedMusic(file, err.Error())
+			continue
+		}
+
+		if ok {
+			err = db.Model(&models.Music{ID: old.ID}).Select("*").Omit(rescanOmit(old)...).Updates(music).Error
+			if err == nil {
+				summary.updated++
+			}
+		} else {
+			err = db.Create(music).Error
+			if err == nil {
+				summary.added++
+			}
+		}
+		if err != nil {
+			summary.failed++
+			h.logScan(taskID, fmt.Sprintf("Failed to save %s: %v", file.Name, err), "error")
+		}
+	}
+
+	if incremental {
+		for path := range known {
+			if !listed[path] {
+				summary.missing++
+			}
+		}
+	} else {
+		removed, err := h.removeMissingTracks(files)
+		if err != nil {
+			h.logScan(taskID, fmt.Sprintf("Failed to remove missing tracks: %v", err), "error")
+		}
+		summary.removed = removed
+	}
+
+	h.logScan(taskID, summary.String(), "info")
+	return summary, nil
+}
+
+// rescanOmit 重新扫描时不由文件决定的列：标识、歌词与响度等由其他功能维护的状态。
+// 曲目已关联封面时 has_cover 与 cover_mime 跟随该封面，不使用内嵌封面的结果
+func rescanOmit(old models.Music) []string {
+	omit := []string{"id", "created_at", "artwork_id", "has_lyrics", "loudness"}
+	if old.ArtworkID != nil {
+		omit = append(omit, "has_cover", "cover_mime")
+	}
+	return omit
+}
+
+// removeMissingTracks 删除源文件已不在列表中的曲目及其关联记录，返回删除的数量
+func (h *MusicHandler) removeMissingTracks(files []webdav.FileInfo) (int, error) {
+	// 列表为空多半是路径或权限问题，不据此清空曲库
+	if len(files) == 0 {
+		return 0, nil
+	}
+	listed := make(map[string]bool, len(files))
+	for _, file := range files {
+		listed[file.Path] = true
+	}
+	var existing []models.Music
+	if err := h.db.Select("id", "file_path").Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	var ids []uint
+	for _, m := range existing {
+		if !listed[m.FilePath] {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return deleteTrackRecords(tx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// deleteTrackRecords 删除曲目及所有按 music_id 关联的记录，应在事务中调用。
+// 曲目引用的封面由封面清理 (collectArtworkGarbage) 回收
+func deleteTrackRecords(tx *gorm.DB, ids []uint) error {
+	related := []interface{}{
+		&models.Lyrics{},
+		&models.TagChange{},
+		&models.FetchResult{},
+		&models.FetchMiss{},
+		&models.Fingerprint{},
+		&models.MusicBrainzMatch{},
+		&models.RenameOperation{},
+	}
+	for _, model := range related {
+		if err := tx.Where("music_id IN ?", ids).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.Music{}, ids).Error
 }
 
 func (h *MusicHandler) logScan(taskID, message, level string) {
@@ -429,6 +609,16 @@ func (h *MusicHandler) GetScanLogs(c *gin.Context) {
 
 // FetchLyrics 从网络获取歌词
 func (h *MusicHandler) FetchLyrics(c *gin.Context) {
+	h.fetchSingle(c, models.FetchKindLyrics)
+}
+
+// FetchCover 从网络获取封面
+func (h *MusicHandler) FetchCover(c *gin.Context) {
+	h.fetchSingle(c, models.FetchKindCover)
+}
+
+// fetchSingle 获取单首曲目的歌词或封面，总是重新获取并替换已有内容
+func (h *MusicHandler) fetchSingle(c *gin.Context, kind string) {
 	id := c.Param("id")
 
 	var music models.Music
@@ -440,63 +630,43 @@ func (h *MusicHandler) FetchLyrics(c *gin.Context) {
 		return
 	}
 
-	fetcher := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-
-	lyricsPath, _, err := fetcher.FetchAndSave(music.Artist, music.Title, music.Album)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"code":    503,
-			"message": "Failed to fetch lyrics: " + err.Error(),
-		})
-		return
+	name := "Lyrics"
+	if kind == models.FetchKindCover {
+		name = "Cover"
 	}
+	job := newFetchJob([]string{kind}, requestUser(c))
+	job.overwrite = true
+	result := h.fetchOne(job, &music, kind)
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "Lyrics fetched successfully",
-		"data": gin.H{
-			"lyrics_path": lyricsPath,
-			"has_lyrics":  true,
-		},
-	})
-}
-
-// FetchCover 从网络获取封面
-func (h *MusicHandler) FetchCover(c *gin.Context) {
-	id := c.Param("id")
-
-	var music models.Music
-	if err := h.db.First(&music, id).Error; err != nil {
+	switch result.Status {
+	case models.FetchStatusNotFound:
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
-			"message": "Music not found",
+			"message": name + " not found: " + result.Error,
 		})
 		return
-	}
-
-	fetcher := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-
-	_, coverPath, err := fetcher.FetchAndSave(music.Artist, music.Title, music.Album)
-	if err != nil {
+	case models.FetchStatusFailed:
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"code":    503,
-			"message": "Failed to fetch cover: " + err.Error(),
+			"message": "Failed to fetch " + strings.ToLower(name) + ": " + result.Error,
 		})
 		return
 	}
 
-	// 更新数据库
-	music.HasCover = true
-	music.CoverMIME = "image/jpeg"
-	h.db.Save(&music)
-
+	data := gin.H{
+		"provider":   result.Provider,
+		"confidence": result.Confidence,
+	}
+	if kind == models.FetchKindCover {
+		data["artwork_id"] = music.ArtworkID
+		data["has_cover"] = music.HasCover
+	} else {
+		data["has_lyrics"] = music.HasLyrics
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
-		"message": "Cover fetched successfully",
-		"data": gin.H{
-			"cover_path": coverPath,
-			"has_cover":  true,
-		},
+		"message": name + " fetched successfully",
+		"data":    data,
 	})
 }
 
@@ -518,7 +688,7 @@ func (h *MusicHandler) GetBatchStatus(c *gin.Context) {
 	})
 }
 
-// BatchFetchLyrics 批量获取所有音乐歌词
+// BatchFetchLyrics 批量获取所有缺少歌词的音乐
 func (h *MusicHandler) BatchFetchLyrics(c *gin.Context) {
 	var musicList []models.Music
 	// 只获取没有歌词的音乐
@@ -538,83 +708,13 @@ func (h *MusicHandler) BatchFetchLyrics(c *gin.Context) {
 		})
 		return
 	}
-	// ✅ 修复：使用锁保护状态检查
-	statusMutex.Lock()
-	// ✅ 关键修复：检查并返回
-	if batchStatus.Running {
-		c.JSON(409, gin.H{
-			"code":    409,
-			"message": "Another batch task is running",
-		})
-		return // <--- 必须加这个 return
-	}
 
-	// 初始化状态
-	batchStatus = &BatchStatus{
-		Running:   true,
-		TaskType:  "lyrics",
-		Total:     len(musicList),
-		Current:   0,
-		Success:   0,
-		Failed:    0,
-		Message:   "Starting...",
-		CreatedAt: time.Now(),
-	}
-	statusMutex.Unlock()
-
-	go func() {
-		f := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-		success := 0
-		failed := 0
-
-		for i, music := range musicList {
-			statusMutex.Lock()
-			batchStatus.Current = i + 1
-			batchStatus.Message = fmt.Sprintf("Processing: %s", music.Title)
-			batchStatus.Total = len(musicList) // 确保 Total 始终正确
-			statusMutex.Unlock()
-			lyricsPath, _, err := f.FetchAndSave(music.Artist, music.Title, music.Album)
-
-			// ✅ 修复：只要 lyricsPath 不为空，就算成功
-			if lyricsPath != "" {
-				music.HasLyrics = true
-				h.getDB().Save(&music)
-				success++
-				log.Printf("[Batch] ✅ %s: Lyrics fetched", music.Title)
-			} else {
-				failed++
-				log.Printf("[Batch] ❌ %s: Lyrics failed (%v)", music.Title, err)
-			}
-
-			// 实时更新状态
-			statusMutex.Lock()
-			batchStatus.Success = success
-			batchStatus.Failed = failed
-			statusMutex.Unlock()
-			time.Sleep(800 * time.Millisecond)
-		}
-		statusMutex.Lock()
-		batchStatus.Running = false
-		batchStatus.Message = "Completed"
-		batchStatus.Total = len(musicList)
-		batchStatus.Success = success
-		batchStatus.Failed = failed
-		statusMutex.Unlock()
-		log.Printf("[Batch] 🎉 Lyrics batch done: total=%d, success=%d, failed=%d", len(musicList), success, failed)
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "Batch fetch started",
-		"data": gin.H{
-			"total":   len(musicList),
-			"success": 0,
-			"failed":  0,
-		},
-	})
+	job := newFetchJob([]string{models.FetchKindLyrics}, requestUser(c))
+	job.useMisses = true
+	h.startBatchFetch(c, "lyrics", musicList, job)
 }
 
-// BatchFetchCovers 批量获取所有音乐封面
+// BatchFetchCovers 批量获取所有缺少封面的音乐
 func (h *MusicHandler) BatchFetchCovers(c *gin.Context) {
 	var musicList []models.Music
 	if err := h.getDB().Where("has_cover = ? OR has_cover IS NULL", false).Find(&musicList).Error; err != nil {
@@ -633,80 +733,13 @@ func (h *MusicHandler) BatchFetchCovers(c *gin.Context) {
 		})
 		return
 	}
-	statusMutex.Lock()
-	// ✅ 关键修复：检查并返回
-	if batchStatus.Running {
-		statusMutex.Unlock()
-		c.JSON(409, gin.H{
-			"code":    409,
-			"message": "Another batch task is running",
-		})
-		return // <--- 必须加这个 return
-	}
 
-	batchStatus = &BatchStatus{
-		Running:   true,
-		TaskType:  "covers",
-		Total:     len(musicList),
-		Current:   0,
-		Success:   0,
-		Failed:    0,
-		Message:   "Starting...",
-		CreatedAt: time.Now(),
-	}
-	statusMutex.Unlock()
-	go func() {
-		f := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-		success := 0
-		failed := 0
-
-		for i, music := range musicList {
-			statusMutex.Lock()
-			batchStatus.Current = i + 1
-			batchStatus.Message = fmt.Sprintf("Processing: %s", music.Title)
-			batchStatus.Total = len(musicList)
-			statusMutex.Unlock()
-			_, coverPath, err := f.FetchAndSave(music.Artist, music.Title, music.Album)
-
-			// ✅ 修复：只要 coverPath 不为空，就算成功
-			if coverPath != "" {
-				music.HasCover = true
-				music.CoverMIME = "image/jpeg"
-				h.getDB().Save(&music)
-				success++
-				log.Printf("[Batch] ✅ %s: Cover fetched", music.Title)
-			} else {
-				failed++
-				log.Printf("[Batch] ❌ %s: Cover failed (%v)", music.Title, err)
-			}
-			statusMutex.Lock()
-			batchStatus.Success = success
-			batchStatus.Failed = failed
-			statusMutex.Unlock()
-			time.Sleep(800 * time.Millisecond)
-		}
-		statusMutex.Lock()
-		batchStatus.Running = false
-		batchStatus.Message = "Completed"
-		batchStatus.Total = len(musicList) // <--- 结束前再设一次
-		batchStatus.Success = success
-		batchStatus.Failed = failed
-		statusMutex.Unlock()
-		log.Printf("[Batch] 🎉 Cover batch done: total=%d, success=%d, failed=%d", len(musicList), success, failed)
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "Batch fetch started",
-		"data": gin.H{
-			"total":   len(musicList),
-			"success": 0,
-			"failed":  0,
-		},
-	})
+	job := newFetchJob([]string{models.FetchKindCover}, requestUser(c))
+	job.useMisses = true
+	h.startBatchFetch(c, "covers", musicList, job)
 }
 
-// BatchFetchAll 批量获取歌词和封面
+// BatchFetchAll 批量获取歌词和封面，已有的内容跳过
 func (h *MusicHandler) BatchFetchAll(c *gin.Context) {
 	var musicList []models.Music
 	if err := h.getDB().Where("scan_status = ?", "success").Find(&musicList).Error; err != nil {
@@ -721,102 +754,17 @@ func (h *MusicHandler) BatchFetchAll(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
 			"message": "No music found",
-			"data":    gin.H{"total": 0, "lyrics_success": 0, "cover_success": 0},
+			"data":    gin.H{"total": 0, "success": 0, "failed": 0},
 		})
 		return
 	}
 
-	// ✅ 关键修复：检查并返回
-	if batchStatus.Running {
-		c.JSON(409, gin.H{
-			"code":    409,
-			"message": "Another batch task is running",
-		})
-		return // <--- 必须加这个 return
-	}
-
-	batchStatus = &BatchStatus{
-		Running:  true,
-		TaskType: "all",
-		Total:    len(musicList),
-		Current:  0,
-		Success:  0,
-		Failed:   0,
-		Message:  "Starting...",
-	}
-
-	go func() {
-		f := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-		lyricsSuccess := 0
-		lyricsFailed := 0
-		coverSuccess := 0
-		coverFailed := 0
-
-		for i, music := range musicList {
-			batchStatus.Current = i + 1
-			batchStatus.Message = fmt.Sprintf("Processing: %s", music.Title)
-			batchStatus.Total = len(musicList)
-
-			updated := false
-
-			// 获取歌词
-			if !music.HasLyrics {
-				lyricsPath, _, _ := f.FetchAndSave(music.Artist, music.Title, music.Album)
-				if lyricsPath != "" {
-					music.HasLyrics = true
-					lyricsSuccess++
-					updated = true
-					log.Printf("[Batch] ✅ %s: Lyrics fetched", music.Title)
-				} else {
-					lyricsFailed++
-					log.Printf("[Batch] ❌ %s: Lyrics failed", music.Title)
-				}
-			}
-
-			// 获取封面
-			if !music.HasCover {
-				_, coverPath, _ := f.FetchAndSave(music.Artist, music.Title, music.Album)
-				if coverPath != "" {
-					music.HasCover = true
-					music.CoverMIME = "image/jpeg"
-					coverSuccess++
-					updated = true
-					log.Printf("[Batch] ✅ %s: Cover fetched", music.Title)
-				} else {
-					coverFailed++
-					log.Printf("[Batch] ❌ %s: Cover failed", music.Title)
-				}
-			}
-
-			if updated {
-				h.getDB().Save(&music)
-			}
-
-			batchStatus.Success = lyricsSuccess + coverSuccess
-			batchStatus.Failed = lyricsFailed + coverFailed
-
-			time.Sleep(800 * time.Millisecond)
-		}
-
-		batchStatus.Running = false
-		batchStatus.Message = "Completed"
-		log.Printf("[Batch] 🎉 All done: Lyrics=%d/%d, Covers=%d/%d",
-			lyricsSuccess, lyricsSuccess+lyricsFailed,
-			coverSuccess, coverSuccess+coverFailed)
-	}()
-
-	c.JSON(http.StatusOK, gin.H{
-		"code":    0,
-		"message": "Batch fetch started",
-		"data": gin.H{
-			"total":   len(musicList),
-			"success": 0,
-			"failed":  0,
-		},
-	})
+	job := newFetchJob([]string{models.FetchKindLyrics, models.FetchKindCover}, requestUser(c))
+	job.useMisses = true
+	h.startBatchFetch(c, "all", musicList, job)
 }
 
-// FetchAll 批量获取所有音乐的歌词和封面
+// FetchAll 同步获取所有音乐缺少的歌词和封面，结果按任务 ID 记录
 func (h *MusicHandler) FetchAll(c *gin.Context) {
 	var musicList []models.Music
 	h.db.Where("scan_status = ?", "success").Find(&musicList)
@@ -824,23 +772,21 @@ func (h *MusicHandler) FetchAll(c *gin.Context) {
 	success := 0
 	failed := 0
 
-	fetcher := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-
-	for _, music := range musicList {
-		_, _, err := fetcher.FetchAndSave(music.Artist, music.Title, music.Album)
-		if err == nil {
+	job := newFetchJob([]string{models.FetchKindLyrics, models.FetchKindCover}, requestUser(c))
+	job.useMisses = true
+	for i := range musicList {
+		if h.fetchTrack(job, &musicList[i]) {
 			success++
 		} else {
 			failed++
 		}
-		// 避免请求过快被封
-		time.Sleep(500 * time.Millisecond)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "Batch fetch completed",
 		"data": gin.H{
+			"job_id":  job.id,
 			"success": success,
 			"failed":  failed,
 			"total":   len(musicList),
@@ -848,34 +794,9 @@ func (h *MusicHandler) FetchAll(c *gin.Context) {
 	})
 }
 
-// GetLocalCover 获取本地封面图片
+// GetLocalCover 获取本地封面图片，与 GetCover 相同
 func (h *MusicHandler) GetLocalCover(c *gin.Context) {
-	id := c.Param("id")
-
-	var music models.Music
-	if err := h.db.First(&music, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "Music not found",
-		})
-		return
-	}
-
-	fetcher := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-	coverPath := fetcher.GetLocalCoverPath(music.Artist, music.Album)
-
-	data, err := os.ReadFile(coverPath)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    404,
-			"message": "Cover not found",
-		})
-		return
-	}
-
-	c.Header("Content-Type", "image/jpeg")
-	c.Header("Cache-Control", "public, max-age=86400")
-	c.Data(http.StatusOK, "image/jpeg", data)
+	h.GetCover(c)
 }
 
 // List 列表 (确保也返回正确结构)
@@ -954,6 +875,7 @@ func (h *MusicHandler) Update(c *gin.Context) {
 		return
 	}
 
+	before := music
 	if req.Title != "" {
 		music.Title = req.Title
 	}
@@ -984,10 +906,11 @@ func (h *MusicHandler) Update(c *gin.Context) {
 	if req.Comment != "" {
 		music.Comment = req.Comment
 	}
+	applyExtendedUpdate(&music, &req)
 
 	music.UpdatedAt = time.Now()
 
-	if err := h.db.Save(&music).Error; err != nil {
+	if _, err := saveMusicWithHistory(h.db, &before, &music, models.TagSourceManual, requestUser(c), newBatchID()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "Failed to update: " + err.Error(),
@@ -1002,6 +925,51 @@ func (h *MusicHandler) Update(c *gin.Context) {
 	})
 }
 
+// applyExtendedUpdate 更新 MusicBrainz 标识和扩展标签，空值表示不修改
+func applyExtendedUpdate(music *models.Music, req *UpdateMusicRequest) {
+	setString := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	setString(&music.FeaturedArtists, req.FeaturedArtists)
+	setString(&music.MBRecordingID, req.MBRecordingID)
+	setString(&music.MBTrackID, req.MBTrackID)
+	setString(&music.MBReleaseID, req.MBReleaseID)
+	setString(&music.MBReleaseGroupID, req.MBReleaseGroupID)
+	setString(&music.MBArtistID, req.MBArtistID)
+	setString(&music.MBAlbumArtistID, req.MBAlbumArtistID)
+	setString(&music.ISRC, req.ISRC)
+	setString(&music.Label, req.Label)
+	setString(&music.CatalogNumber, req.CatalogNumber)
+	setString(&music.InitialKey, req.InitialKey)
+	setString(&music.ReleaseType, req.ReleaseType)
+	setString(&music.ArtistSort, req.ArtistSort)
+	setString(&music.AlbumSort, req.AlbumSort)
+	setString(&music.TitleSort, req.TitleSort)
+	setString(&music.AlbumArtistSort, req.AlbumArtistSort)
+	setString(&music.ComposerSort, req.ComposerSort)
+
+	if req.BPM > 0 {
+		music.BPM = req.BPM
+	}
+	if req.OriginalYear > 0 {
+		music.OriginalYear = req.OriginalYear
+	}
+	if req.ReplayGainTrackGain != nil {
+		music.ReplayGainTrackGain = req.ReplayGainTrackGain
+	}
+	if req.ReplayGainTrackPeak != nil {
+		music.ReplayGainTrackPeak = req.ReplayGainTrackPeak
+	}
+	if req.ReplayGainAlbumGain != nil {
+		music.ReplayGainAlbumGain = req.ReplayGainAlbumGain
+	}
+	if req.ReplayGainAlbumPeak != nil {
+		music.ReplayGainAlbumPeak = req.ReplayGainAlbumPeak
+	}
+}
+
 func (h *MusicHandler) BatchUpdate(c *gin.Context) {
 	var req BatchUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1022,6 +990,7 @@ func (h *MusicHandler) BatchUpdate(c *gin.Context) {
 
 	updated := 0
 	failed := 0
+	user, batchID := requestUser(c), newBatchID()
 
 	for _, id := range req.IDs {
 		var music models.Music
@@ -1029,6 +998,7 @@ func (h *MusicHandler) BatchUpdate(c *gin.Context) {
 			failed++
 			continue
 		}
+		before := music
 
 		if req.Artist != "" {
 			music.Artist = req.Artist
@@ -1044,7 +1014,7 @@ func (h *MusicHandler) BatchUpdate(c *gin.Context) {
 		}
 
 		music.UpdatedAt = time.Now()
-		if err := h.db.Save(&music).Error; err != nil {
+		if _, err := saveMusicWithHistory(h.db, &before, &music, models.TagSourceManual, user, batchID); err != nil {
 			failed++
 			continue
 		}
@@ -1061,7 +1031,7 @@ func (h *MusicHandler) BatchUpdate(c *gin.Context) {
 	})
 }
 
-// GetCover 获取封面图片
+// GetCover 获取封面图片，依次使用曲目封面、专辑封面、旧缓存和内嵌封面
 func (h *MusicHandler) GetCover(c *gin.Context) {
 	id := c.Param("id")
 
@@ -1071,23 +1041,13 @@ func (h *MusicHandler) GetCover(c *gin.Context) {
 		return
 	}
 
-	// 检查本地封面
-	f := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-	localPath := f.GetLocalCoverPath(music.Artist, music.Album)
-
-	// 修复：直接使用，不声明未使用的变量
-	if _, err := os.Stat(localPath); err == nil {
-		data, err := os.ReadFile(localPath)
-		if err == nil {
-			c.Header("Content-Type", "image/jpeg")
-			c.Header("Cache-Control", "public, max-age=86400")
-			c.Data(http.StatusOK, "image/jpeg", data)
-			return
-		}
+	art := h.resolveArtwork(music)
+	if art == nil {
+		c.Status(http.StatusNotFound)
+		return
 	}
-
-	// 没有本地封面，返回 404
-	c.Status(http.StatusNotFound)
+	// 封面可能被替换，短期缓存并用 ETag 校验
+	serveArtwork(c, art, "public, max-age=300")
 }
 
 // Play 音乐流式播放 (最终修复版：URL 编码 + HTTP 反向代理)
@@ -1129,7 +1089,12 @@ func (h *MusicHandler) Play(c *gin.Context) {
 	}
 
 	// 4. 设置认证头 (Basic Auth)
-	req.SetBasicAuth(dbConfig.Username, dbConfig.Password)
+	password, err := dbConfig.PlainPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to decrypt WebDAV password"})
+		return
+	}
+	req.SetBasicAuth(dbConfig.Username, password)
 
 	// 5. 透传 Range 头 (支持进度条拖拽)
 	if rangeHeader := c.GetHeader("Range"); rangeHeader != "" {
@@ -1161,7 +1126,19 @@ func (h *MusicHandler) Play(c *gin.Context) {
 	c.Header("Content-Range", resp.Header.Get("Content-Range"))
 	c.Header("Cache-Control", "public, max-age=3600")
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Expose-Headers", "Content-Range, Content-Length, Content-Type")
+	c.Header("Access-Control-Expose-Headers", "Content-Range, Content-Length, Content-Type, X-ReplayGain-Track-Gain, X-ReplayGain-Track-Peak, X-ReplayGain-Album-Gain, X-ReplayGain-Album-Peak")
+
+	// 客户端可据此调整音量
+	for name, v := range map[string]*float64{
+		"X-ReplayGain-Track-Gain": music.ReplayGainTrackGain,
+		"X-ReplayGain-Track-Peak": music.ReplayGainTrackPeak,
+		"X-ReplayGain-Album-Gain": music.ReplayGainAlbumGain,
+		"X-ReplayGain-Album-Peak": music.ReplayGainAlbumPeak,
+	} {
+		if v != nil {
+			c.Header(name, strconv.FormatFloat(*v, 'f', -1, 64))
+		}
+	}
 
 	// 8. 复制状态码 (200 或 206)
 	c.Status(resp.StatusCode)
@@ -1218,7 +1195,7 @@ func (h *MusicHandler) handleRangeRequest(c *gin.Context, data []byte, fileSize
 	c.Writer.Write(data[start : end+1])
 }
 
-// 修改 handlers/music.go 中的 GetLyrics 函数
+// GetLyrics 返回原文、翻译与罗马音，parsed 为按时间排序并合并后的行
 func (h *MusicHandler) GetLyrics(c *gin.Context) {
 	id := c.Param("id")
 
@@ -1231,29 +1208,8 @@ func (h *MusicHandler) GetLyrics(c *gin.Context) {
 		return
 	}
 
-	// 1. 优先读取本地歌词文件
-	fetcher := fetcher.NewFetcher("/app/data/lyrics", "/app/data/covers")
-	localPath := fetcher.GetLocalLyricsPath(music.Artist, music.Title)
-
-	var lyrics string
-	if _, err := os.Stat(localPath); err == nil {
-		data, err := os.ReadFile(localPath)
-		if err == nil {
-			lyrics = string(data)
-		}
-	}
-
-	// 2. 本地没有则尝试嵌入式歌词
-	if lyrics == "" {
-		lyrics = h.getEmbeddedLyrics(music)
-	}
-
-	// 3. 最后尝试外部 .lrc 文件
-	if lyrics == "" {
-		lyrics = h.getExternalLyrics(music)
-	}
-
-	if lyrics == "" {
+	entry := h.loadLyrics(music)
+	if entry == nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    0,
 			"message": "No lyrics available",
@@ -1265,15 +1221,27 @@ func (h *MusicHandler) GetLyrics(c *gin.Context) {
 		return
 	}
 
-	parsedLyrics := h.parseLyrics(lyrics)
+	// 翻译与罗马音按时间轴并入每一行的 translation/romanization 字段
+	parsed := lyrics.Parse(entry.Content)
+	hasTranslation := parsed.MergeLayer(lyrics.Parse(entry.Translation), lyrics.LayerTranslation)
+	hasRomanization := parsed.MergeLayer(lyrics.Parse(entry.Romanization), lyrics.LayerRomanization)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"lyrics":     lyrics,
-			"parsed":     parsedLyrics,
-			"has_lyrics": true,
+			"lyrics":           entry.Content,
+			"translation":      entry.Translation,
+			"romanization":     entry.Romanization,
+			"version":          entry.Version,
+			"source":           entry.Source,
+			"language":         entry.Language,
+			"parsed":           parsed.Lines,
+			"metadata":         parsed.Metadata,
+			"synced":           parsed.Synced,
+			"has_lyrics":       true,
+			"has_translation":  hasTranslation,
+			"has_romanization": hasRomanization,
 		},
 	})
 }
@@ -1295,8 +1263,9 @@ func (h *MusicHandler) getEmbeddedLyrics(music models.Music) string {
 		return ""
 	}
 
+	// ID3v2.3 的 USLT 帧同样可能以 ISO-8859-1 保存 GBK 歌词
 	if lyrics := md.Lyrics(); lyrics != "" {
-		return lyrics
+		return configuredCharsetDetector().RepairString(lyrics)
 	}
 
 	return ""
@@ -1315,67 +1284,8 @@ func (h *MusicHandler) getExternalLyrics(music models.Music) string {
 		return ""
 	}
 
-	return string(data)
-}
-
-// parseLyrics 解析歌词 (增强容错性)
-func (h *MusicHandler) parseLyrics(lyrics string) []gin.H {
-	var parsed []gin.H
-	lines := strings.Split(lyrics, "\n")
-
-	// 匹配时间轴 [mm:ss.xx]
-	timeRegex := regexp.MustCompile(`^\[(\d{2}):(\d{2})\.(\d{2,3})\]`)
-
-	hasTimeTag := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		matches := timeRegex.FindStringSubmatch(line)
-		if len(matches) >= 4 {
-			hasTimeTag = true
-			mins, _ := strconv.Atoi(matches[1])
-			secs, _ := strconv.Atoi(matches[2])
-			millis, _ := strconv.Atoi(matches[3])
-			if len(matches[3]) == 2 {
-				millis *= 10
-			}
-
-			seconds := float64(mins*60+secs) + float64(millis)/1000.0
-
-			text := timeRegex.ReplaceAllString(line, "")
-			text = strings.TrimSpace(text)
-
-			// 过滤掉纯音乐标记如 [00:00.00]
-			if text != "" && !strings.HasPrefix(text, "[") && !strings.HasPrefix(text, "作") && !strings.HasPrefix(text, "曲") {
-				parsed = append(parsed, gin.H{
-					"time":    seconds,
-					"text":    text,
-					"seconds": seconds,
-				})
-			}
-		} else if !hasTimeTag {
-			// 如果没有时间轴标签，尝试作为纯文本歌词处理 (每行间隔 3 秒)
-			// 这样可以防止只显示一行
-			parsed = append(parsed, gin.H{
-				"time":    float64(len(parsed)) * 3.0,
-				"text":    line,
-				"seconds": float64(len(parsed)) * 3.0,
-			})
-		}
-	}
-
-	// 如果解析后仍然为空，返回一个提示
-	if len(parsed) == 0 {
-		return []gin.H{
-			{"time": 0, "text": "暂无歌词", "seconds": 0},
-		}
-	}
-
-	return parsed
+	// 外部 .lrc 文件常为 GBK/Big5，统一转为 UTF-8
+	return configuredCharsetDetector().DecodeBytes(data).Text
 }
 
 func (h *MusicHandler) GetPlaylist(c *gin.Context) {
@@ -1607,13 +1517,19 @@ func (h *MusicHandler) SaveWebDAVConfig(c *gin.Context) {
 		config = models.WebDAVConfig{
 			URL:        req.URL,
 			Username:   req.Username,
-			Password:   req.Password,
 			RootPath:   req.RootPath, // 此时一定是 "/dav" 或用户填写的值
 			Enabled:    req.Enabled,
 			TestStatus: "pending",
 			CreatedAt:  now,
 			UpdatedAt:  now,
 		}
+		if err := config.SetPassword(req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to encrypt password: " + err.Error(),
+			})
+			return
+		}
 		if err := h.db.Create(&config).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"code":    500,
@@ -1632,9 +1548,15 @@ func (h *MusicHandler) SaveWebDAVConfig(c *gin.Context) {
 		// 更新现有记录
 		config.URL = req.URL
 		config.Username = req.Username
-		// 只有当密码不为空时才更新 (防止前端没传密码把数据库的覆盖了)
-		if req.Password != "" {
-			config.Password = req.Password
+		// 只有当密码不为空时才更新 (接口不再返回密码，前端留空即保持原密码)
+		if req.Password != "" || req.ClearPassword {
+			if err := config.SetPassword(req.Password); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "Failed to encrypt password: " + err.Error(),
+				})
+				return
+			}
 		}
 		config.RootPath = req.RootPath // ✅ 确保更新后的路径也是有效的
 		config.Enabled = req.Enabled
@@ -1688,8 +1610,33 @@ func (h *MusicHandler) TestWebDAVConfig(c *gin.Context) {
 
 	log.Printf("[WebDAV Test] Testing connection to: %s (Path: %s, User: %s)", req.URL, req.RootPath, req.Username)
 
+	// 接口不再返回密码，前端未填写时使用已保存的密码
+	var dbConfig models.WebDAVConfig
+	dbHasRecord := h.db.First(&dbConfig).Error == nil
+
+	password := req.Password
+	if password == "" && !req.ClearPassword && dbHasRecord {
+		// 已保存的密码只发给已保存的服务器和用户，避免被转发到任意地址
+		if strings.TrimRight(req.URL, "/") != strings.TrimRight(dbConfig.URL, "/") || req.Username != dbConfig.Username {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "测试其他服务器或用户时需要填写密码",
+			})
+			return
+		}
+		stored, err := dbConfig.PlainPassword()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "密码解密失败：" + err.Error(),
+			})
+			return
+		}
+		password = stored
+	}
+
 	// 2. 创建客户端 (使用传入的参数)
-	client := webdav.NewClientNoCheck(req.URL, req.Username, req.Password, req.RootPath)
+	client := webdav.NewClientNoCheck(req.URL, req.Username, password, req.RootPath)
 
 	// 3. ✅ 关键修复：使用递归查找，确保能扫到子目录下的文件 (解决显示 0 个的问题)
 	files, err := client.ListMP3FilesRecursive()
@@ -1720,9 +1667,6 @@ func (h *MusicHandler) TestWebDAVConfig(c *gin.Context) {
 	now := time.Now()
 
 	// 尝试更新数据库中的测试状态 (如果有记录的话)
-	var dbConfig models.WebDAVConfig
-	dbHasRecord := h.db.First(&dbConfig).Error == nil
-
 	if err != nil {
 		errMsg := err.Error()
 		log.Printf("[WebDAV Test] FAILED: %s", errMsg)
@@ -1834,8 +1778,8 @@ func (h *MusicHandler) RefreshTagsFromMusicBrainz(c *gin.Context) {
 	// 创建 MusicBrainz 客户端
 	mb := parser.NewMusicBrainzClient()
 
-	// 搜索标签信息
-	mbInfo, err := mb.SearchTrack(music.Artist, music.Title)
+	// 搜索标签信息，取综合排名 (分数、时长差、专辑名) 最高的候选
+	candidates, err := mb.SearchCandidates(musicBrainzQuery(&music))
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"code":    503,
@@ -1844,7 +1788,10 @@ func (h *MusicHandler) RefreshTagsFromMusicBrainz(c *gin.Context) {
 		return
 	}
 
-	// 更新数据库
+	mbInfo := candidates[0]
+
+	// 只补全空字段
+	before := music
 	updated := false
 
 	if mbInfo.Title != "" && music.Title == "" {
@@ -1874,7 +1821,7 @@ func (h *MusicHandler) RefreshTagsFromMusicBrainz(c *gin.Context) {
 	}
 
 	music.UpdatedAt = time.Now()
-	if err := h.db.Save(&music).Error; err != nil {
+	if _, err := saveMusicWithHistory(h.db, &before, &music, models.TagSourceMusicBrainz, requestUser(c), newBatchID()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "Failed to save: " + err.Error(),
@@ -0,0 +1,556 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-music-tag/models"
+	"go-music-tag/parser"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MusicBrainzApplyRequest 接受某个候选中的部分字段
+type MusicBrainzApplyRequest struct {
+	RecordingID string   `json:"recording_id" binding:"required"`
+	ReleaseID   string   `json:"release_id"`
+	Fields      []string `json:"fields"` // 为空时应用所有有差异的字段
+}
+
+// MusicBrainzBatchRequest 批量查询请求，IDs 为空时处理全部已扫描曲目
+type MusicBrainzBatchRequest struct {
+	IDs           []uint `json:"ids"`
+	OnlyUnmatched bool   `json:"only_unmatched"` // 跳过已有匹配记录的曲目
+}
+
+// TagDiff 单个字段的当前值与建议值
+type TagDiff struct {
+	Field    string      `json:"field"`
+	Current  interface{} `json:"current"`
+	Proposed interface{} `json:"proposed"`
+}
+
+// MusicBrainzCandidateView 候选及其与当前标签的差异
+type MusicBrainzCandidateView struct {
+	parser.MBCandidate
+	Diff []TagDiff `json:"diff"`
+}
+
+// musicBrainzField 候选字段与 models.Music 字段的映射
+type musicBrainzField struct {
+	name     string
+	current  func(m *models.Music) interface{}
+	proposed func(c *parser.MBCandidate) interface{}
+	apply    func(m *models.Music, c *parser.MBCandidate)
+}
+
+var musicBrainzFields = []musicBrainzField{
+	{
+		name:     "title",
+		current:  func(m *models.Music) interface{} { return m.Title },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.Title },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.Title = c.Title },
+	},
+	{
+		name:     "artist",
+		current:  func(m *models.Music) interface{} { return m.Artist },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.Artist },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.Artist = c.Artist },
+	},
+	{
+		name:     "album",
+		current:  func(m *models.Music) interface{} { return m.Album },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.Album },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.Album = c.Album },
+	},
+	{
+		name:     "year",
+		current:  func(m *models.Music) interface{} { return m.Year },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.Year },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.Year = c.Year },
+	},
+	{
+		name:     "track_number",
+		current:  func(m *models.Music) interface{} { return m.TrackNumber },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.TrackNumber },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.TrackNumber = c.TrackNumber },
+	},
+	{
+		name:     "disc_number",
+		current:  func(m *models.Music) interface{} { return m.DiscNumber },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.DiscNumber },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.DiscNumber = c.DiscNumber },
+	},
+	{
+		name:     "release_type",
+		current:  func(m *models.Music) interface{} { return m.ReleaseType },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.ReleaseType },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.ReleaseType = c.ReleaseType },
+	},
+	{
+		name:     "mb_recording_id",
+		current:  func(m *models.Music) interface{} { return m.MBRecordingID },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.RecordingID },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.MBRecordingID = c.RecordingID },
+	},
+	{
+		name:     "mb_track_id",
+		current:  func(m *models.Music) interface{} { return m.MBTrackID },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.TrackID },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.MBTrackID = c.TrackID },
+	},
+	{
+		name:     "mb_release_id",
+		current:  func(m *models.Music) interface{} { return m.MBReleaseID },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.ReleaseID },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.MBReleaseID = c.ReleaseID },
+	},
+	{
+		name:     "mb_release_group_id",
+		current:  func(m *models.Music) interface{} { return m.MBReleaseGroupID },
+		proposed: func(c *parser.MBCandidate) interface{} { return c.ReleaseGroupID },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.MBReleaseGroupID = c.ReleaseGroupID },
+	},
+	{
+		name:     "mb_artist_id",
+		current:  func(m *models.Music) interface{} { return m.MBArtistID },
+		proposed: func(c *parser.MBCandidate) interface{} { return strings.Join(c.ArtistIDs, "; ") },
+		apply:    func(m *models.Music, c *parser.MBCandidate) { m.MBArtistID = strings.Join(c.ArtistIDs, "; ") },
+	},
+}
+
+// musicBrainzQuery 用当前标签构造搜索条件
+func musicBrainzQuery(music *models.Music) parser.MBTrackQuery {
+	return parser.MBTrackQuery{
+		Artist:   music.Artist,
+		Title:    music.Title,
+		Album:    music.Album,
+		Duration: music.Duration,
+	}
+}
+
+// musicBrainzDiff 列出候选中有值且与当前标签不同的字段
+func musicBrainzDiff(music *models.Music, c *parser.MBCandidate) []TagDiff {
+	diff := []TagDiff{}
+	for _, f := range musicBrainzFields {
+		proposed := f.proposed(c)
+		if isZeroTagValue(proposed) {
+			continue
+		}
+		current := f.current(music)
+		if current == proposed {
+			continue
+		}
+		diff = append(diff, TagDiff{Field: f.name, Current: current, Proposed: proposed})
+	}
+	return diff
+}
+
+func isZeroTagValue(v interface{}) bool {
+	switch val := v.(type) {
+	case string:
+		return val == ""
+	case int:
+		return val == 0
+	}
+	return v == nil
+}
+
+func candidateViews(music *models.Music, candidates []parser.MBCandidate) []MusicBrainzCandidateView {
+	views := make([]MusicBrainzCandidateView, 0, len(candidates))
+	for i := range candidates {
+		views = append(views, MusicBrainzCandidateView{
+			MBCandidate: candidates[i],
+			Diff:        musicBrainzDiff(music, &candidates[i]),
+		})
+	}
+	return views
+}
+
+// applyMusicBrainzFields 应用候选中的指定字段，返回实际修改的字段
+func applyMusicBrainzFields(music *models.Music, c *parser.MBCandidate, fields []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, name := range fields {
+		selected[name] = true
+	}
+
+	known := make(map[string]bool)
+	for _, f := range musicBrainzFields {
+		known[f.name] = true
+	}
+	for name := range selected {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+	}
+
+	var applied []string
+	for _, d := range musicBrainzDiff(music, c) {
+		if len(selected) > 0 && !selected[d.Field] {
+			continue
+		}
+		for _, f := range musicBrainzFields {
+			if f.name == d.Field {
+				f.apply(music, c)
+				applied = append(applied, f.name)
+			}
+		}
+	}
+	return applied, nil
+}
+
+// GetMusicBrainzCandidates 查询单曲的 MusicBrainz 候选，并给出与当前标签的差异
+func (h *MusicHandler) GetMusicBrainzCandidates(c *gin.Context) {
+	id := c.Param("id")
+
+	var music models.Music
+	if err := h.db.First(&music, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	mb := parser.NewMusicBrainzClient()
+	candidates, err := mb.SearchCandidates(musicBrainzQuery(&music))
+	if err != nil && !errors.Is(err, parser.ErrNoResults) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "MusicBrainz lookup failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"music":      music.ToResponse(),
+			"candidates": candidateViews(&music, candidates),
+		},
+	})
+}
+
+// ApplyMusicBrainzCandidate 按字段接受某个候选
+func (h *MusicHandler) ApplyMusicBrainzCandidate(c *gin.Context) {
+	id := c.Param("id")
+
+	var music models.Music
+	if err := h.db.First(&music, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Music not found",
+		})
+		return
+	}
+
+	var req MusicBrainzApplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	// 重新查询录音，避免信任客户端提交的标签值
+	mb := parser.NewMusicBrainzClient()
+	candidates, err := mb.LookupCandidates(req.RecordingID, musicBrainzQuery(&music))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "MusicBrainz lookup failed: " + err.Error(),
+		})
+		return
+	}
+
+	candidate := candidates[0]
+	if req.ReleaseID != "" {
+		found := false
+		for _, cand := range candidates {
+			if cand.ReleaseID == req.ReleaseID {
+				candidate = cand
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Release does not contain this recording",
+			})
+			return
+		}
+	}
+
+	before := music
+	applied, err := applyMusicBrainzFields(&music, &candidate, req.Fields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(applied) > 0 {
+		music.UpdatedAt = time.Now()
+		if _, err := saveMusicWithHistory(h.db, &before, &music, models.TagSourceMusicBrainz, requestUser(c), newBatchID()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to save: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	h.db.Model(&models.MusicBrainzMatch{}).
+		Where("music_id = ?", music.ID).
+		Update("status", models.MBMatchAccepted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "MusicBrainz candidate applied",
+		"data": gin.H{
+			"music":   music.ToResponse(),
+			"applied": applied,
+		},
+	})
+}
+
+// BatchMusicBrainzLookup 后台批量查询候选，结果保存待用户确认
+func (h *MusicHandler) BatchMusicBrainzLookup(c *gin.Context) {
+	var req MusicBrainzBatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	query := h.getDB().Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.OnlyUnmatched {
+		query = query.Where("id NOT IN (?)", h.getDB().Model(&models.MusicBrainzMatch{}).Select("music_id"))
+	}
+
+	var musicList []models.Music
+	if err := query.Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get music list: " + err.Error(),
+		})
+		return
+	}
+
+	if len(musicList) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "No music to look up",
+			"data":    gin.H{"total": 0, "success": 0, "failed": 0},
+		})
+		return
+	}
+
+	statusMutex.Lock()
+	if batchStatus.Running {
+		statusMutex.Unlock()
+		c.JSON(StatusBusy, gin.H{
+			"code":    409,
+			"message": "Another batch task is running",
+		})
+		return
+	}
+	batchStatus = &BatchStatus{
+		Running:   true,
+		TaskType:  "musicbrainz",
+		Total:     len(musicList),
+		Message:   "Starting...",
+		CreatedAt: time.Now(),
+	}
+	statusMutex.Unlock()
+
+	go h.runMusicBrainzBatch(musicList)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "MusicBrainz lookup started",
+		"data": gin.H{
+			"total":   len(musicList),
+			"success": 0,
+			"failed":  0,
+		},
+	})
+}
+
+// runMusicBrainzBatch 逐首查询；限速由 parser 包统一保证 1 次/秒
+func (h *MusicHandler) runMusicBrainzBatch(musicList []models.Music) {
+	mb := parser.NewMusicBrainzClient()
+	success := 0
+	failed := 0
+
+	for i := range musicList {
+		music := &musicList[i]
+
+		statusMutex.Lock()
+		batchStatus.Current = i + 1
+		batchStatus.Message = fmt.Sprintf("Processing: %s", music.Title)
+		statusMutex.Unlock()
+
+		status := models.MBMatchPending
+		errMsg := ""
+		bestScore := 0.0
+		candidatesJSON := "[]"
+
+		candidates, err := mb.SearchCandidates(musicBrainzQuery(music))
+		switch {
+		case errors.Is(err, parser.ErrNoResults):
+			status = models.MBMatchNoMatch
+		case err != nil:
+			status = models.MBMatchFailed
+			errMsg = err.Error()
+		default:
+			bestScore = candidates[0].Score
+			if data, err := json.Marshal(candidates); err == nil {
+				candidatesJSON = string(data)
+			}
+		}
+
+		var match models.MusicBrainzMatch
+		saveErr := h.getDB().Where("music_id = ?", music.ID).
+			Assign(map[string]interface{}{
+				"status":     status,
+				"candidates": candidatesJSON,
+				"best_score": bestScore,
+				"error":      errMsg,
+			}).
+			FirstOrCreate(&match, models.MusicBrainzMatch{MusicID: music.ID}).Error
+
+		if status == models.MBMatchFailed || saveErr != nil {
+			failed++
+			log.Printf("[MusicBrainz] ❌ %s: %v %v", music.Title, err, saveErr)
+		} else {
+			success++
+		}
+
+		statusMutex.Lock()
+		batchStatus.Success = success
+		batchStatus.Failed = failed
+		statusMutex.Unlock()
+	}
+
+	statusMutex.Lock()
+	batchStatus.Running = false
+	batchStatus.Message = "Completed"
+	statusMutex.Unlock()
+	log.Printf("[MusicBrainz] 🎉 Batch done: total=%d, success=%d, failed=%d", len(musicList), success, failed)
+}
+
+// ListMusicBrainzMatches 列出批量查询结果及差异，默认只返回待确认的记录
+func (h *MusicHandler) ListMusicBrainzMatches(c *gin.Context) {
+	status := c.DefaultQuery("status", models.MBMatchPending)
+	page := getInt(c.DefaultQuery("page", "1"))
+	pageSize := getInt(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := h.db.Model(&models.MusicBrainzMatch{})
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var matches []models.MusicBrainzMatch
+	query.Order("best_score DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&matches)
+
+	musicIDs := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		musicIDs = append(musicIDs, m.MusicID)
+	}
+	var musicList []models.Music
+	if len(musicIDs) > 0 {
+		h.db.Where("id IN ?", musicIDs).Find(&musicList)
+	}
+	musicByID := make(map[uint]*models.Music, len(musicList))
+	for i := range musicList {
+		musicByID[musicList[i].ID] = &musicList[i]
+	}
+
+	list := make([]gin.H, 0, len(matches))
+	for _, m := range matches {
+		music, ok := musicByID[m.MusicID]
+		if !ok {
+			continue
+		}
+		var candidates []parser.MBCandidate
+		if m.Candidates != "" {
+			json.Unmarshal([]byte(m.Candidates), &candidates)
+		}
+		list = append(list, gin.H{
+			"match":      m,
+			"music":      music.ToResponse(),
+			"candidates": candidateViews(music, candidates),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":      0,
+		"message":   "success",
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"list":      list,
+	})
+}
+
+// RejectMusicBrainzMatch 拒绝某条匹配结果
+func (h *MusicHandler) RejectMusicBrainzMatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid id"})
+		return
+	}
+
+	var match models.MusicBrainzMatch
+	if err := h.db.First(&match, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Match not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	match.Status = models.MBMatchRejected
+	if err := h.db.Save(&match).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to save: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    match,
+	})
+}
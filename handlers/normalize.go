@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"go-music-tag/normalize"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NormalizeRequest 规范化请求，Rules 为空时使用配置中的规则
+type NormalizeRequest struct {
+	IDs       []uint           `json:"ids"`
+	Album     string           `json:"album"`
+	Artist    string           `json:"artist"`
+	Rules     *normalize.Rules `json:"rules"`
+	DryRun    bool             `json:"dry_run"`
+	WriteBack bool             `json:"write_back"` // 同时写回源文件标签 (仅 MP3)
+}
+
+// NormalizeResult 单个曲目的修改
+type NormalizeResult struct {
+	MusicID    uint               `json:"music_id"`
+	FilePath   string             `json:"file_path"`
+	Changes    []normalize.Change `json:"changes"`
+	WriteError string             `json:"write_error,omitempty"`
+}
+
+// NormalizeTags 按规则规范化标签，dry_run 时只返回差异
+func (h *MusicHandler) NormalizeTags(c *gin.Context) {
+	var req NormalizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	rules := req.Rules
+	if rules == nil {
+		rules = &config.GetConfig().Normalize
+	}
+	if err := rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid rules: " + err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ?", req.Artist)
+	}
+	var musicList []models.Music
+	if err := query.Order("id").Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	var results []NormalizeResult
+	var changed, originals []models.Music
+	for _, music := range musicList {
+		original := music
+		changes := rules.Apply(&music)
+		if len(changes) == 0 {
+			continue
+		}
+		results = append(results, NormalizeResult{MusicID: music.ID, FilePath: music.FilePath, Changes: changes})
+		changed = append(changed, music)
+		originals = append(originals, original)
+	}
+
+	if req.DryRun || len(changed) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data": gin.H{
+				"dry_run": req.DryRun,
+				"total":   len(musicList),
+				"changed": len(changed),
+				"results": results,
+			},
+		})
+		return
+	}
+
+	// 先写回文件，文件大小随之变化，再统一提交数据库
+	writeFailed := 0
+	if req.WriteBack {
+		for i := range changed {
+			var fields []string
+			for _, ch := range results[i].Changes {
+				fields = append(fields, ch.Field)
+			}
+			if err := h.writeTagFields(&changed[i], fields); err != nil {
+				results[i].WriteError = err.Error()
+				writeFailed++
+			}
+		}
+	}
+
+	user, batchID := requestUser(c), newBatchID()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for i := range changed {
+			changed[i].UpdatedAt = time.Now()
+			if _, err := saveMusicWithHistory(tx, &originals[i], &changed[i], models.TagSourceRule, user, batchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to update: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Normalized %d tracks", len(changed)),
+		"data": gin.H{
+			"dry_run":      false,
+			"batch_id":     batchID,
+			"total":        len(musicList),
+			"changed":      len(changed),
+			"write_failed": writeFailed,
+			"results":      results,
+		},
+	})
+}
+
+// GetNormalizeRules 返回配置中的默认规则
+func (h *MusicHandler) GetNormalizeRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"rules":  config.GetConfig().Normalize,
+			"fields": normalize.TextFields,
+		},
+	})
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"go-music-tag/parser"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PathPatternTestRequest 测试路径模式，Paths 为空时从音乐库中取样
+type PathPatternTestRequest struct {
+	Patterns []string `json:"patterns"` // 为空时使用配置中的模式
+	Paths    []string `json:"paths"`
+	Limit    int      `json:"limit"`
+}
+
+// FillFromPathRequest 批量从路径补全标签
+type FillFromPathRequest struct {
+	IDs       []uint   `json:"ids"` // 为空时处理全部曲目
+	Patterns  []string `json:"patterns"`
+	Overwrite bool     `json:"overwrite"` // 覆盖已有标签，默认只填充空字段
+	DryRun    bool     `json:"dry_run"`
+}
+
+// PathMatchResult 单个路径的解析结果
+type PathMatchResult struct {
+	MusicID uint              `json:"music_id,omitempty"`
+	Path    string            `json:"path"`
+	Pattern string            `json:"pattern,omitempty"`
+	Values  map[string]string `json:"values,omitempty"`
+	Changed []string          `json:"changed,omitempty"`
+}
+
+// configuredPathPatterns 编译配置中的路径模式，无效模式只记录日志
+func configuredPathPatterns() []*parser.PathPattern {
+	var patterns []*parser.PathPattern
+	for _, raw := range config.GetConfig().Scan.PathPatterns {
+		p, err := parser.CompilePathPattern(raw)
+		if err != nil {
+			log.Printf("[PathPattern] ⚠️ Ignoring invalid pattern %q: %v", raw, err)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// requestPathPatterns 使用请求中的模式，未提供时使用配置
+func requestPathPatterns(raw []string) ([]*parser.PathPattern, error) {
+	if len(raw) == 0 {
+		patterns := configuredPathPatterns()
+		if len(patterns) == 0 {
+			return nil, fmt.Errorf("no path patterns configured")
+		}
+		return patterns, nil
+	}
+	return parser.CompilePathPatterns(raw)
+}
+
+// TestPathPatterns 预览路径模式在样例路径上的解析结果
+func (h *MusicHandler) TestPathPatterns(c *gin.Context) {
+	var req PathPatternTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	patterns, err := requestPathPatterns(req.Patterns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	paths := req.Paths
+	if len(paths) == 0 {
+		limit := req.Limit
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		h.db.Model(&models.Music{}).Order("RANDOM()").Limit(limit).Pluck("file_path", &paths)
+	}
+
+	matched := 0
+	results := make([]PathMatchResult, 0, len(paths))
+	for _, p := range paths {
+		result := PathMatchResult{Path: p}
+		if pattern, values := parser.MatchPathPatterns(patterns, p); pattern != nil {
+			result.Pattern = pattern.String()
+			result.Values = values
+			matched++
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":   len(results),
+			"matched": matched,
+			"results": results,
+		},
+	})
+}
+
+// FillTagsFromPath 按路径模式批量补全标签，所有修改在同一事务中提交
+func (h *MusicHandler) FillTagsFromPath(c *gin.Context) {
+	var req FillFromPathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	patterns, err := requestPathPatterns(req.Patterns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Model(&models.Music{})
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	var musicList []models.Music
+	if err := query.Find(&musicList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to load music: " + err.Error(),
+		})
+		return
+	}
+
+	var results []PathMatchResult
+	var changedList, originals []models.Music
+	unmatched := 0
+	for _, music := range musicList {
+		original := music
+		pattern, values := parser.MatchPathPatterns(patterns, music.FilePath)
+		if pattern == nil {
+			unmatched++
+			continue
+		}
+		changed := parser.ApplyPathValues(&music, values, req.Overwrite)
+		if len(changed) == 0 {
+			continue
+		}
+		results = append(results, PathMatchResult{
+			MusicID: music.ID,
+			Path:    music.FilePath,
+			Pattern: pattern.String(),
+			Values:  values,
+			Changed: changed,
+		})
+		changedList = append(changedList, music)
+		originals = append(originals, original)
+	}
+
+	if !req.DryRun && len(changedList) > 0 {
+		user, batchID := requestUser(c), newBatchID()
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			for i := range changedList {
+				changedList[i].UpdatedAt = time.Now()
+				if _, err := saveMusicWithHistory(tx, &originals[i], &changedList[i], models.TagSourceRule, user, batchID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to update: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	message := fmt.Sprintf("Updated %d tracks", len(changedList))
+	if req.DryRun {
+		message = fmt.Sprintf("Would update %d tracks", len(changedList))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"dry_run":   req.DryRun,
+			"total":     len(musicList),
+			"updated":   len(changedList),
+			"unmatched": unmatched,
+			"results":   results,
+		},
+	})
+}
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"go-music-tag/renamer"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 重命名冲突类型
+const (
+	RenameConflictDuplicate = "duplicate_target" // 多个曲目生成相同的目标路径
+	RenameConflictExists    = "target_exists"    // 目标路径已被其他文件占用
+	RenameConflictInvalid   = "invalid"          // 模板渲染失败
+	RenameConflictCycle     = "rename_cycle"     // 多个曲目互相占用对方的路径 (如互换)，无法依次移动
+)
+
+// RenameRequest 按模板重命名，IDs 与 Album/Artist 至少提供一个
+type RenameRequest struct {
+	Template      string `json:"template"` // 为空时使用配置中的模板
+	IDs           []uint `json:"ids"`
+	Album         string `json:"album"`
+	Artist        string `json:"artist"`
+	SkipConflicts bool   `json:"skip_conflicts"` // 应用时跳过冲突项，否则有冲突时整体拒绝
+}
+
+// RenamePlanItem 单个曲目的重命名计划
+type RenamePlanItem struct {
+	MusicID  uint   `json:"music_id"`
+	OldPath  string `json:"old_path"`
+	NewPath  string `json:"new_path"`
+	Changed  bool   `json:"changed"`
+	Conflict string `json:"conflict,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RenameBatchSummary 重命名批次概要
+type RenameBatchSummary struct {
+	BatchID   string    `json:"batch_id"`
+	Template  string    `json:"template"`
+	Total     int       `json:"total"`
+	Undone    int       `json:"undone"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// loadRenameTargets 根据请求选择曲目
+func (h *MusicHandler) loadRenameTargets(req *RenameRequest) ([]models.Music, error) {
+	if len(req.IDs) == 0 && req.Album == "" && req.Artist == "" {
+		return nil, fmt.Errorf("ids, album or artist is required")
+	}
+
+	query := h.db.Model(&models.Music{}).Where("scan_status = ?", "success")
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.Album != "" {
+		query = query.Where("album = ?", req.Album)
+	}
+	if req.Artist != "" {
+		query = query.Where("artist = ? OR album_artist = ?", req.Artist, req.Artist)
+	}
+
+	var musicList []models.Music
+	if err := query.Order("file_path").Find(&musicList).Error; err != nil {
+		return nil, err
+	}
+	return musicList, nil
+}
+
+// planRename 渲染模板并检测冲突
+func (h *MusicHandler) planRename(tmpl *renamer.Template, musicList []models.Music, exists func(string) bool) []RenamePlanItem {
+	rootPath := "/"
+	if client, err := h.getWebDAVClient(); err == nil {
+		rootPath = client.RootPath()
+	}
+
+	items := make([]RenamePlanItem, 0, len(musicList))
+	targets := make(map[string]int) // 小写目标路径 -> 使用次数，兼容大小写不敏感的服务端
+	for i := range musicList {
+		m := &musicList[i]
+		item := RenamePlanItem{MusicID: m.ID, OldPath: m.FilePath}
+
+		rel, err := tmpl.Render(m)
+		if err != nil {
+			item.Conflict = RenameConflictInvalid
+			item.Error = err.Error()
+			items = append(items, item)
+			continue
+		}
+		item.NewPath = path.Join(rootPath, rel)
+		item.Changed = item.NewPath != item.OldPath
+		targets[strings.ToLower(item.NewPath)]++
+		items = append(items, item)
+	}
+
+	// 其他曲目 (不在本次重命名范围内) 占用的路径
+	var newPaths []string
+	for _, item := range items {
+		if item.Changed {
+			newPaths = append(newPaths, item.NewPath)
+		}
+	}
+	occupied := make(map[string]uint)
+	if len(newPaths) > 0 {
+		var others []models.Music
+		h.db.Select("id", "file_path").Where("file_path IN ?", newPaths).Find(&others)
+		for _, o := range others {
+			occupied[o.FilePath] = o.ID
+		}
+	}
+	moving := make(map[uint]bool)
+	for _, item := range items {
+		if item.Changed {
+			moving[item.MusicID] = true
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		if !item.Changed || item.Conflict != "" {
+			continue
+		}
+		if targets[strings.ToLower(item.NewPath)] > 1 {
+			item.Conflict = RenameConflictDuplicate
+			continue
+		}
+		if id, ok := occupied[item.NewPath]; ok && id != item.MusicID && !moving[id] {
+			item.Conflict = RenameConflictExists
+			continue
+		}
+		// 仅大小写不同的重命名在大小写不敏感的服务端上会"存在"，不算冲突
+		if !strings.EqualFold(item.NewPath, item.OldPath) && exists != nil && exists(item.NewPath) {
+			if _, ok := occupied[item.NewPath]; !ok {
+				item.Conflict = RenameConflictExists
+			}
+		}
+	}
+
+	markRenameDependencies(items)
+	return items
+}
+
+// renameBlockers 返回每一项的目标路径当前被本次移动的哪个曲目占用 (索引)，没有时返回 -1
+func renameBlockers(items []RenamePlanItem) []int {
+	byOldPath := make(map[string]int)
+	for i, item := range items {
+		if item.Changed {
+			byOldPath[item.OldPath] = i
+		}
+	}
+	blockers := make([]int, len(items))
+	for i, item := range items {
+		blockers[i] = -1
+		if j, ok := byOldPath[item.NewPath]; ok && item.Changed && j != i {
+			blockers[i] = j
+		}
+	}
+	return blockers
+}
+
+// markRenameDependencies 目标被其他移动中的曲目占用时，需要等对方先移走：
+// 互相占用形成循环的标记为 RenameConflictCycle，占用者因冲突不会移动的标记为 RenameConflictExists
+func markRenameDependencies(items []RenamePlanItem) {
+	blockers := renameBlockers(items)
+
+	// 每个路径最多被一个曲目占用，沿占用链走回起点即为循环
+	for i := range items {
+		if items[i].Conflict != "" || blockers[i] < 0 {
+			continue
+		}
+		j := blockers[i]
+		for steps := 0; j >= 0 && j != i && steps < len(items); steps++ {
+			j = blockers[j]
+		}
+		if j != i {
+			continue
+		}
+		for k := i; ; {
+			items[k].Conflict = RenameConflictCycle
+			if k = blockers[k]; k == i {
+				break
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range items {
+			if j := blockers[i]; j >= 0 && items[i].Conflict == "" && items[j].Conflict != "" {
+				items[i].Conflict = RenameConflictExists
+				changed = true
+			}
+		}
+	}
+}
+
+// renameOrder 返回应用重命名的顺序：占用目标路径的曲目先移动
+func renameOrder(items []RenamePlanItem) []int {
+	blockers := renameBlockers(items)
+	order := make([]int, 0, len(items))
+	visited := make([]bool, len(items))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if j := blockers[i]; j >= 0 {
+			visit(j)
+		}
+		order = append(order, i)
+	}
+	for i := range items {
+		visit(i)
+	}
+	return order
+}
+
+func renameTemplate(raw string) (*renamer.Template, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = config.GetConfig().Rename.Template
+	}
+	return renamer.Parse(raw)
+}
+
+// PreviewRename 预览模板重命名结果及冲突
+func (h *MusicHandler) PreviewRename(c *gin.Context) {
+	var req RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, err := renameTemplate(req.Template)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid template: " + err.Error(),
+		})
+		return
+	}
+
+	musicList, err := h.loadRenameTargets(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var exists func(string) bool
+	if client, err := h.getWebDAVClient(); err == nil {
+		exists = client.Exists
+	}
+	items := h.planRename(tmpl, musicList, exists)
+
+	changed, conflicts := 0, 0
+	for _, item := range items {
+		if item.Changed {
+			changed++
+		}
+		if item.Conflict != "" {
+			conflicts++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"template":  tmpl.String(),
+			"total":     len(items),
+			"changed":   changed,
+			"conflicts": conflicts,
+			"items":     items,
+		},
+	})
+}
+
+// moveMusicFile 移动源文件并在同一事务中更新 file_path 和操作记录，数据库失败时把文件移回
+func (h *MusicHandler) moveMusicFile(musicID uint, oldPath, newPath string, onSuccess func(tx *gorm.DB) error) error {
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Move(oldPath, newPath); err != nil {
+		return err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Music{}).
+			Where("id = ? AND file_path = ?", musicID, oldPath).
+			Updates(map[string]interface{}{
+				"file_path": newPath,
+				"file_name": path.Base(newPath),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("music %d was modified concurrently", musicID)
+		}
+		return onSuccess(tx)
+	})
+	if err != nil {
+		if rbErr := client.Move(newPath, oldPath); rbErr != nil {
+			log.Printf("[Rename] ❌ Failed to roll back %s -> %s: %v", newPath, oldPath, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ApplyRename 按模板移动源文件，返回批次 ID 用于撤销
+func (h *MusicHandler) ApplyRename(c *gin.Context) {
+	var req RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, err := renameTemplate(req.Template)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid template: " + err.Error(),
+		})
+		return
+	}
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	musicList, err := h.loadRenameTargets(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items := h.planRename(tmpl, musicList, client.Exists)
+	if !req.SkipConflicts {
+		for _, item := range items {
+			if item.Conflict != "" {
+				c.JSON(http.StatusConflict, gin.H{
+					"code":    409,
+					"message": "Rename plan has conflicts",
+					"data":    items,
+				})
+				return
+			}
+		}
+	}
+
+	batchID := newBatchID()
+	var results []gin.H
+	success, failed, skipped := 0, 0, 0
+	blockers := renameBlockers(items)
+	moved := make([]bool, len(items))
+	for _, i := range renameOrder(items) {
+		item := items[i]
+		if !item.Changed || item.Conflict != "" {
+			skipped++
+			continue
+		}
+		if j := blockers[i]; j >= 0 && !moved[j] {
+			failed++
+			results = append(results, gin.H{"music_id": item.MusicID, "status": "failed", "error": "Target is still occupied by " + items[j].OldPath})
+			continue
+		}
+
+		op := models.RenameOperation{
+			BatchID:  batchID,
+			MusicID:  item.MusicID,
+			OldPath:  item.OldPath,
+			NewPath:  item.NewPath,
+			Template: tmpl.String(),
+			Status:   models.RenameApplied,
+		}
+		err := h.moveMusicFile(item.MusicID, item.OldPath, item.NewPath, func(tx *gorm.DB) error {
+			return tx.Create(&op).Error
+		})
+		if err != nil {
+			failed++
+			log.Printf("[Rename] ❌ %s -> %s: %v", item.OldPath, item.NewPath, err)
+			results = append(results, gin.H{"music_id": item.MusicID, "status": "failed", "error": err.Error()})
+			continue
+		}
+		success++
+		moved[i] = true
+		results = append(results, gin.H{"music_id": item.MusicID, "status": "success", "new_path": item.NewPath})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Renamed %d files, %d failed, %d skipped", success, failed, skipped),
+		"data": gin.H{
+			"batch_id": batchID,
+			"success":  success,
+			"failed":   failed,
+			"skipped":  skipped,
+			"results":  results,
+		},
+	})
+}
+
+// UndoRename 撤销一个重命名批次，按相反顺序把文件移回原路径
+func (h *MusicHandler) UndoRename(c *gin.Context) {
+	batchID := c.Param("batch_id")
+
+	var ops []models.RenameOperation
+	h.db.Where("batch_id = ? AND status = ?", batchID, models.RenameApplied).
+		Order("id DESC").Find(&ops)
+	if len(ops) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "No undoable operations in batch",
+		})
+		return
+	}
+
+	var results []gin.H
+	success, failed := 0, 0
+	for _, op := range ops {
+		// 重新扫描后曲目 ID 会变化，按当前路径查找
+		var music models.Music
+		if err := h.db.Where("file_path = ?", op.NewPath).First(&music).Error; err != nil {
+			failed++
+			results = append(results, gin.H{"operation_id": op.ID, "status": "failed", "error": "File is no longer at " + op.NewPath})
+			continue
+		}
+
+		op := op
+		err := h.moveMusicFile(music.ID, op.NewPath, op.OldPath, func(tx *gorm.DB) error {
+			now := time.Now()
+			return tx.Model(&op).Updates(map[string]interface{}{
+				"status":    models.RenameUndone,
+				"undone_at": &now,
+			}).Error
+		})
+		if err != nil {
+			failed++
+			results = append(results, gin.H{"operation_id": op.ID, "status": "failed", "error": err.Error()})
+			continue
+		}
+		success++
+		results = append(results, gin.H{"operation_id": op.ID, "status": "success", "path": op.OldPath})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": fmt.Sprintf("Restored %d files, %d failed", success, failed),
+		"data": gin.H{
+			"batch_id": batchID,
+			"success":  success,
+			"failed":   failed,
+			"results":  results,
+		},
+	})
+}
+
+// ListRenameBatches 重命名历史
+func (h *MusicHandler) ListRenameBatches(c *gin.Context) {
+	var batches []RenameBatchSummary
+	h.db.Model(&models.RenameOperation{}).
+		Select("batch_id, MAX(template) AS template, COUNT(*) AS total, " +
+			"SUM(CASE WHEN status = 'undone' THEN 1 ELSE 0 END) AS undone, MIN(created_at) AS created_at").
+		Group("batch_id").
+		Order("batch_id DESC").
+		Limit(50).
+		Scan(&batches)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"default_template": config.GetConfig().Rename.Template,
+			"fields":           renamer.Fields,
+			"batches":          batches,
+		},
+	})
+}
+
+// GetRenameBatch 查看批次中的操作
+func (h *MusicHandler) GetRenameBatch(c *gin.Context) {
+	var ops []models.RenameOperation
+	h.db.Where("batch_id = ?", c.Param("batch_id")).Order("id").Find(&ops)
+	if len(ops) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Batch not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    ops,
+	})
+}
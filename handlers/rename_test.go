@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"go-music-tag/models"
+	"go-music-tag/renamer"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestHandler(t *testing.T) *MusicHandler {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Music{}, &models.WebDAVConfig{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return &MusicHandler{db: db}
+}
+
+func TestPlanRenameChainsAndCycles(t *testing.T) {
+	h := newTestHandler(t)
+	// 标题即新文件名：A→B→C 为链，X↔Y 为互换，D→E→F 的 F 被范围外的曲目占用
+	tracks := []models.Music{
+		{FilePath: "/A.mp3", Title: "B"},
+		{FilePath: "/B.mp3", Title: "C"},
+		{FilePath: "/X.mp3", Title: "Y"},
+		{FilePath: "/Y.mp3", Title: "X"},
+		{FilePath: "/P.mp3", Title: "Q"},
+		{FilePath: "/Q.mp3", Title: "R"},
+		{FilePath: "/R.mp3", Title: "P"},
+		{FilePath: "/D.mp3", Title: "E"},
+		{FilePath: "/E.mp3", Title: "F"},
+		{FilePath: "/F.mp3", Title: "Other"},
+	}
+	for i := range tracks {
+		tracks[i].ScanStatus = "success"
+		if err := h.db.Create(&tracks[i]).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	tmpl, err := renamer.Parse("{title}.{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := h.planRename(tmpl, tracks[:9], nil)
+
+	want := map[string]string{
+		"/A.mp3": "",
+		"/B.mp3": "",
+		"/X.mp3": RenameConflictCycle,
+		"/Y.mp3": RenameConflictCycle,
+		"/P.mp3": RenameConflictCycle,
+		"/Q.mp3": RenameConflictCycle,
+		"/R.mp3": RenameConflictCycle,
+		"/D.mp3": RenameConflictExists,
+		"/E.mp3": RenameConflictExists,
+	}
+	for _, item := range items {
+		if item.Conflict != want[item.OldPath] {
+			t.Errorf("%s -> %s: conflict %q, want %q", item.OldPath, item.NewPath, item.Conflict, want[item.OldPath])
+		}
+	}
+
+	// 链中占用目标路径的曲目必须先移动
+	pos := make(map[string]int)
+	for n, i := range renameOrder(items) {
+		pos[items[i].OldPath] = n
+	}
+	if len(pos) != len(items) {
+		t.Fatalf("order covers %d of %d items", len(pos), len(items))
+	}
+	if pos["/B.mp3"] > pos["/A.mp3"] {
+		t.Errorf("B must move before A, order %v", pos)
+	}
+}
+
+func TestRenameOrderLongChain(t *testing.T) {
+	items := []RenamePlanItem{
+		{OldPath: "/1", NewPath: "/2", Changed: true},
+		{OldPath: "/2", NewPath: "/3", Changed: true},
+		{OldPath: "/3", NewPath: "/4", Changed: true},
+		{OldPath: "/5", NewPath: "/5"},
+	}
+	markRenameDependencies(items)
+	for _, item := range items {
+		if item.Conflict != "" {
+			t.Errorf("%s: unexpected conflict %q", item.OldPath, item.Conflict)
+		}
+	}
+	got := renameOrder(items)
+	want := []int{2, 1, 0, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
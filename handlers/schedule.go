@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-music-tag/config"
+	"go-music-tag/models"
+	"go-music-tag/scheduler"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scheduleUser 定时任务产生的修改记录的操作人
+const scheduleUser = "scheduler"
+
+// CreateScheduleRequest 新建定时任务
+type CreateScheduleRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Cron    string `json:"cron" binding:"required"` // 5 段 cron 表达式或 @daily 等简写
+	Task    string `json:"task" binding:"required"`
+	Enabled *bool  `json:"enabled"` // 未设置时启用
+}
+
+// UpdateScheduleRequest 修改定时任务，未设置的字段保持不变
+type UpdateScheduleRequest struct {
+	Cron    *string `json:"cron"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// ScheduleResponse 定时任务及其当前运行状态
+type ScheduleResponse struct {
+	models.Schedule
+	Running bool `json:"running"`
+	Queued  bool `json:"queued"`
+}
+
+// StartScheduler 同步配置中的定时任务并启动调度，服务启动时调用一次
+func (h *MusicHandler) StartScheduler() error {
+	db := h.getDB()
+	h.sched = scheduler.New(db, h.scheduleTasks())
+	syncConfigSchedules(db, h.sched, config.GetConfig().Scheduler.Schedules)
+	return h.sched.Start()
+}
+
+// scheduleTasks 可定时执行的任务
+func (h *MusicHandler) scheduleTasks() map[string]scheduler.Task {
+	return map[string]scheduler.Task{
+		models.ScheduleTaskScan: h.runIncrementalScan,
+		models.ScheduleTaskFetchLyrics: func() (string, error) {
+			return h.runScheduledFetch("lyrics", []string{models.FetchKindLyrics}, "has_lyrics = ?", false)
+		},
+		models.ScheduleTaskFetchCovers: func() (string, error) {
+			return h.runScheduledFetch("covers", []string{models.FetchKindCover}, "has_cover = ?", false)
+		},
+		models.ScheduleTaskFetchAll: func() (string, error) {
+			return h.runScheduledFetch("all", []string{models.FetchKindLyrics, models.FetchKindCover},
+				"has_lyrics = ? OR has_cover = ?", false, false)
+		},
+		models.ScheduleTaskArtworkGC: func() (string, error) {
+			result, err := collectArtworkGarbage(h.getDB(), false)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Removed %d artwork, %d stray files, %d album entries (%d bytes)",
+				len(result.Removed), len(result.StrayFiles), result.AlbumEntries, result.Bytes), nil
+		},
+	}
+}
+
+// syncConfigSchedules 把配置中的定时任务写入数据库：不存在时按配置创建，
+// 已存在时以配置的 cron 和任务为准，启用状态保留接口中的修改
+func syncConfigSchedules(db *gorm.DB, sched *scheduler.Scheduler, list []config.ScheduleConfig) {
+	for _, sc := range list {
+		if err := validateSchedule(sched, sc.Name, sc.Cron, sc.Task); err != nil {
+			log.Printf("[Scheduler] ⚠️ Ignoring schedule %q from config: %v", sc.Name, err)
+			continue
+		}
+		var existing models.Schedule
+		err := db.Where("name = ?", sc.Name).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = db.Create(&models.Schedule{
+				Name:    sc.Name,
+				Cron:    sc.Cron,
+				Task:    sc.Task,
+				Enabled: sc.Enabled,
+				Source:  models.ScheduleSourceConfig,
+			}).Error
+		} else if err == nil {
+			err = db.Model(&existing).Updates(map[string]interface{}{
+				"cron":   sc.Cron,
+				"task":   sc.Task,
+				"source": models.ScheduleSourceConfig,
+			}).Error
+		}
+		if err != nil {
+			log.Printf("[Scheduler] ⚠️ Failed to sync schedule %q: %v", sc.Name, err)
+		}
+	}
+}
+
+// validateSchedule 检查名称、任务和 cron 表达式，cron 必须有下一次运行时间
+func validateSchedule(sched *scheduler.Scheduler, name, cronExpr, task string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !sched.HasTask(task) {
+		return fmt.Errorf("unknown task: %s", task)
+	}
+	return validateCron(cronExpr)
+}
+
+func validateCron(cronExpr string) error {
+	c, err := scheduler.ParseCron(cronExpr)
+	if err != nil {
+		return fmt.Errorf("invalid cron: %w", err)
+	}
+	if c.Next(time.Now()).IsZero() {
+		return fmt.Errorf("invalid cron: %q never runs", cronExpr)
+	}
+	return nil
+}
+
+// runScheduledFetch 获取缺少歌词或封面的曲目，与手动批量任务共用批量任务状态，
+// 有扫描或批量任务在运行时跳过
+func (h *MusicHandler) runScheduledFetch(taskType string, kinds []string, missing string, args ...interface{}) (string, error) {
+	if scanRunning() {
+		return "", fmt.Errorf("%w: scan in progress", scheduler.ErrBusy)
+	}
+	var musicList []models.Music
+	if err := h.getDB().Where("scan_status = ?", "success").Where(missing, args...).
+		Find(&musicList).Error; err != nil {
+		return "", err
+	}
+	if len(musicList) == 0 {
+		return "No music needs fetching", nil
+	}
+
+	job := newFetchJob(kinds, scheduleUser)
+	job.useMisses = true
+	if !claimBatchStatus(taskType, len(musicList), job.id) {
+		return "", fmt.Errorf("%w: batch task in progress", scheduler.ErrBusy)
+	}
+	success, failed := h.runBatchFetch(job, musicList)
+	return fmt.Sprintf("Job %s: total %d, success %d, failed %d", job.id, len(musicList), success, failed), nil
+}
+
+// runIncrementalScan 增量扫描：只解析新增、大小改变或上次失败的文件，未改变的曲目
+// 及其歌词、封面和修改历史保持不变；源文件已不存在的曲目只统计不删除
+func (h *MusicHandler) runIncrementalScan() (string, error) {
+	statusMutex.Lock()
+	busy := batchStatus.Running
+	statusMutex.Unlock()
+	if busy {
+		return "", fmt.Errorf("%w: batch task in progress", scheduler.ErrBusy)
+	}
+
+	taskID := time.Now().Format("20060102150405")
+	if !claimScan(taskID) {
+		return "", fmt.Errorf("%w: scan in progress", scheduler.ErrBusy)
+	}
+	defer releaseScan()
+
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		return "", err
+	}
+	h.logScan(taskID, "Incremental scan started", "info")
+	files, err := client.ListMP3FilesRecursive()
+	if err != nil {
+		h.logScan(taskID, fmt.Sprintf("Failed to list files: %v", err), "error")
+		return "", err
+	}
+
+	summary, err := h.scanLibrary(taskID, client, files, true)
+	if err != nil {
+		return "", err
+	}
+	return summary.String(), nil
+}
+
+// requireScheduler 返回调度器，服务未启动调度时返回 503 和 nil
+func (h *MusicHandler) requireScheduler(c *gin.Context) *scheduler.Scheduler {
+	if h.sched == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "Scheduler is not running",
+		})
+	}
+	return h.sched
+}
+
+func (h *MusicHandler) scheduleResponse(s models.Schedule) ScheduleResponse {
+	running, queued := h.sched.State(s.ID)
+	return ScheduleResponse{Schedule: s, Running: running, Queued: queued}
+}
+
+// ListSchedules 列出定时任务及可用的任务类型
+func (h *MusicHandler) ListSchedules(c *gin.Context) {
+	sched := h.requireScheduler(c)
+	if sched == nil {
+		return
+	}
+	var schedules []models.Schedule
+	if err := h.getDB().Order("id").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to list schedules: " + err.Error(),
+		})
+		return
+	}
+	list := make([]ScheduleResponse, 0, len(schedules))
+	for _, s := range schedules {
+		list = append(list, h.scheduleResponse(s))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"tasks": []string{
+				models.ScheduleTaskScan,
+				models.ScheduleTaskFetchLyrics,
+				models.ScheduleTaskFetchCovers,
+				models.ScheduleTaskFetchAll,
+				models.ScheduleTaskArtworkGC,
+			},
+			"list": list,
+		},
+	})
+}
+
+// CreateSchedule 新建定时任务
+func (h *MusicHandler) CreateSchedule(c *gin.Context) {
+	sched := h.requireScheduler(c)
+	if sched == nil {
+		return
+	}
+	var req CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := validateSchedule(sched, req.Name, req.Cron, req.Task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	db := h.getDB()
+	var count int64
+	db.Model(&models.Schedule{}).Where("name = ?", req.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "Schedule name already exists",
+		})
+		return
+	}
+
+	schedule := models.Schedule{
+		Name:    strings.TrimSpace(req.Name),
+		Cron:    req.Cron,
+		Task:    req.Task,
+		Enabled: req.Enabled == nil || *req.Enabled,
+		Source:  models.ScheduleSourceAPI,
+	}
+	if err := db.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to create schedule: " + err.Error(),
+		})
+		return
+	}
+	sched.Reschedule(&schedule, time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Schedule created",
+		"data":    h.scheduleResponse(schedule),
+	})
+}
+
+// UpdateSchedule 修改 cron 表达式或启用状态。配置文件中的任务重启后 cron 恢复为配置值
+func (h *MusicHandler) UpdateSchedule(c *gin.Context) {
+	sched := h.requireScheduler(c)
+	if sched == nil {
+		return
+	}
+	var schedule models.Schedule
+	if err := h.getDB().First(&schedule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Schedule not found",
+		})
+		return
+	}
+	var req UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Cron != nil {
+		if err := validateCron(*req.Cron); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		updates["cron"] = *req.Cron
+		schedule.Cron = *req.Cron
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+		schedule.Enabled = *req.Enabled
+	}
+	if len(updates) > 0 {
+		if err := h.getDB().Model(&schedule).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "Failed to update schedule: " + err.Error(),
+			})
+			return
+		}
+	}
+	sched.Reschedule(&schedule, time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Schedule updated",
+		"data":    h.scheduleResponse(schedule),
+	})
+}
+
+// DeleteSchedule 删除通过接口创建的定时任务；配置文件中的任务重启后会重新创建，只能停用
+func (h *MusicHandler) DeleteSchedule(c *gin.Context) {
+	if h.requireScheduler(c) == nil {
+		return
+	}
+	var schedule models.Schedule
+	if err := h.getDB().First(&schedule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Schedule not found",
+		})
+		return
+	}
+	if schedule.Source == models.ScheduleSourceConfig {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Schedule is defined in the config file; disable it instead",
+		})
+		return
+	}
+	if err := h.getDB().Delete(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to delete schedule: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Schedule deleted",
+	})
+}
+
+// RunSchedule 立即执行定时任务，结果记录在 last_* 字段中
+func (h *MusicHandler) RunSchedule(c *gin.Context) {
+	sched := h.requireScheduler(c)
+	if sched == nil {
+		return
+	}
+	var schedule models.Schedule
+	if err := h.getDB().First(&schedule, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Schedule not found",
+		})
+		return
+	}
+	if err := sched.Trigger(schedule.ID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, scheduler.ErrAlreadyQueued) {
+			status = StatusBusy
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Schedule triggered",
+		"data":    h.scheduleResponse(schedule),
+	})
+}
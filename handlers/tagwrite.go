@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"go-music-tag/tagwriter"
+	"strconv"
+	"strings"
+)
+
+// writableFormats 支持写回标签的格式
+var writableFormats = map[string]bool{"MP3": true}
+
+// tagFieldValue 取曲目字段的字符串形式，数值为 0 时返回空字符串 (删除该帧)
+func tagFieldValue(music *models.Music, field string) (string, bool) {
+	itoa := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+
+	switch field {
+	case "title":
+		return music.Title, true
+	case "artist":
+		return music.Artist, true
+	case "album":
+		return music.Album, true
+	case "album_artist":
+		return music.AlbumArtist, true
+	case "composer":
+		return music.Composer, true
+	case "genre":
+		return music.Genre, true
+	case "year":
+		return itoa(music.Year), true
+	case "track":
+		return itoa(music.TrackNumber), true
+	case "disc":
+		return itoa(music.DiscNumber), true
+	case "bpm":
+		return itoa(music.BPM), true
+	case "isrc":
+		return music.ISRC, true
+	case "label":
+		return music.Label, true
+	case "initial_key":
+		return music.InitialKey, true
+	}
+	return "", false
+}
+
+// canonicalTagField 把 API 中的字段名统一为 tagwriter 使用的名称
+func canonicalTagField(field string) string {
+	switch field {
+	case "track_number":
+		return "track"
+	case "disc_number":
+		return "disc"
+	}
+	return field
+}
+
+// isWritableTagField 判断字段能否写回文件标签
+func isWritableTagField(field string) bool {
+	field = canonicalTagField(field)
+	if field == "featured_artists" || field == "comment" {
+		return true
+	}
+	_, ok := tagFieldValue(&models.Music{}, field)
+	return ok
+}
+
+// hasTagFieldValue 字段可以写回且曲目中有值
+func hasTagFieldValue(music *models.Music, field string) bool {
+	switch field = canonicalTagField(field); field {
+	case "featured_artists":
+		return music.FeaturedArtists != ""
+	case "comment":
+		return music.Comment != ""
+	}
+	value, ok := tagFieldValue(music, field)
+	return ok && value != ""
+}
+
+// writeTagFields 将曲目的指定字段写回源文件，目前仅支持 MP3 (ID3v2)
+func (h *MusicHandler) writeTagFields(music *models.Music, fields []string) error {
+	if !writableFormats[strings.ToUpper(music.Format)] {
+		return fmt.Errorf("writing tags is not supported for %s files", music.Format)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return h.rewriteTag(music, func(tag *tagwriter.Tag) error {
+		for _, field := range fields {
+			field = canonicalTagField(field)
+			switch field {
+			case "featured_artists":
+				tag.SetUserText("FEATURED ARTISTS", music.FeaturedArtists)
+			case "comment":
+				tag.SetComment("XXX", music.Comment)
+			default:
+				value, ok := tagFieldValue(music, field)
+				if !ok {
+					return fmt.Errorf("unsupported field: %s", field)
+				}
+				if err := tag.SetField(field, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// rewriteTag 下载源文件，修改 ID3v2 标签后上传覆盖，并更新内存中的文件大小
+func (h *MusicHandler) rewriteTag(music *models.Music, edit func(tag *tagwriter.Tag) error) error {
+	client, err := h.getWebDAVClient()
+	if err != nil {
+		return err
+	}
+
+	data, err := client.GetFile(music.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+
+	tag, err := tagwriter.Read(data)
+	if err != nil {
+		return err
+	}
+	if err := edit(tag); err != nil {
+		return err
+	}
+
+	out := tagwriter.Apply(data, tag)
+	if err := client.PutFile(music.FilePath, out); err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	music.FileSize = int64(len(out))
+	return nil
+}
@@ -0,0 +1,180 @@
+package loudness
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ReferenceLUFS ReplayGain 2.0 的参考响度
+const ReferenceLUFS = -18.0
+
+// Result 一段音频的 EBU R128 测量结果
+type Result struct {
+	Integrated float64 `json:"integrated"` // 综合响度 (LUFS)
+	Range      float64 `json:"range"`      // 响度范围 (LU)
+	TruePeak   float64 `json:"true_peak"`  // 真峰值 (dBTP)
+}
+
+// Gain 达到参考响度所需的增益 (dB)
+func (r Result) Gain() float64 {
+	return ReferenceLUFS - r.Integrated
+}
+
+// Peak 以线性幅度表示的峰值，1.0 为满刻度
+func (r Result) Peak() float64 {
+	return math.Pow(10, r.TruePeak/20)
+}
+
+// Analyzer 调用 ffmpeg 的 ebur128 滤镜测量响度
+type Analyzer struct {
+	ffmpegPath string
+	timeout    time.Duration
+}
+
+// NewAnalyzer 创建响度分析器
+func NewAnalyzer(ffmpegPath string) *Analyzer {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	return &Analyzer{
+		ffmpegPath: ffmpegPath,
+		timeout:    5 * time.Minute,
+	}
+}
+
+// Available 检查 ffmpeg 是否可用
+func (a *Analyzer) Available() error {
+	if _, err := exec.LookPath(a.ffmpegPath); err != nil {
+		return fmt.Errorf("ffmpeg not found (%s): %w", a.ffmpegPath, err)
+	}
+	return nil
+}
+
+// Analyze 测量音频数据的响度；ext 为原文件扩展名，帮助解码器识别格式
+func (a *Analyzer) Analyze(data []byte, ext string) (*Result, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty audio data")
+	}
+
+	tmp, err := os.CreateTemp("", "loudness-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	return a.AnalyzeFile(tmp.Name())
+}
+
+// AnalyzeFile 测量本地文件的响度
+func (a *Analyzer) AnalyzeFile(path string) (*Result, error) {
+	cmd := exec.Command(a.ffmpegPath, "-hide_banner", "-nostats", "-i", path,
+		"-map", "0:a:0", "-af", "ebur128=peak=true:framelog=verbose", "-f", "null", "-")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, lastLine(stderr.Bytes()))
+		}
+	case <-time.After(a.timeout):
+		cmd.Process.Kill()
+		<-done
+		return nil, fmt.Errorf("ffmpeg timed out after %s", a.timeout)
+	}
+
+	return parseSummary(stderr.Bytes())
+}
+
+var (
+	summaryIntegrated = regexp.MustCompile(`(?m)^\s*I:\s+(-?[\d.]+|-inf) LUFS`)
+	summaryRange      = regexp.MustCompile(`(?m)^\s*LRA:\s+(-?[\d.]+) LU`)
+	summaryPeak       = regexp.MustCompile(`(?m)^\s*Peak:\s+(-?[\d.]+|-inf) dBFS`)
+)
+
+// parseSummary 解析 ebur128 滤镜输出的 Summary 部分
+func parseSummary(out []byte) (*Result, error) {
+	idx := bytes.LastIndex(out, []byte("Summary:"))
+	if idx < 0 {
+		return nil, fmt.Errorf("no ebur128 summary in ffmpeg output")
+	}
+	summary := out[idx:]
+
+	value := func(re *regexp.Regexp) (float64, bool) {
+		m := re.FindSubmatch(summary)
+		if m == nil {
+			return 0, false
+		}
+		if string(m[1]) == "-inf" {
+			return math.Inf(-1), true
+		}
+		v, err := strconv.ParseFloat(string(m[1]), 64)
+		return v, err == nil
+	}
+
+	integrated, ok := value(summaryIntegrated)
+	if !ok {
+		return nil, fmt.Errorf("integrated loudness missing from ebur128 summary")
+	}
+	if math.IsInf(integrated, -1) {
+		return nil, fmt.Errorf("audio is silent")
+	}
+	r := &Result{Integrated: integrated}
+	r.Range, _ = value(summaryRange)
+	if peak, ok := value(summaryPeak); ok && !math.IsInf(peak, -1) {
+		r.TruePeak = peak
+	}
+	return r, nil
+}
+
+// AlbumResult 按时长加权的能量平均合并多首曲目的响度，峰值取最大值
+func AlbumResult(tracks []Result, durations []float64) Result {
+	if len(tracks) == 0 {
+		return Result{}
+	}
+	var energy, weight float64
+	album := Result{TruePeak: math.Inf(-1)}
+	for i, t := range tracks {
+		w := 1.0
+		if i < len(durations) && durations[i] > 0 {
+			w = durations[i]
+		}
+		energy += w * math.Pow(10, t.Integrated/10)
+		weight += w
+		album.TruePeak = math.Max(album.TruePeak, t.TruePeak)
+	}
+	if weight > 0 {
+		album.Integrated = 10 * math.Log10(energy/weight)
+	}
+	return album
+}
+
+func lastLine(b []byte) []byte {
+	b = bytes.TrimSpace(b)
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		return b[i+1:]
+	}
+	return b
+}
@@ -0,0 +1,212 @@
+package lyrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 导出格式
+const (
+	FormatLRC  = "lrc"
+	FormatSRT  = "srt"
+	FormatVTT  = "vtt"
+	FormatTTML = "ttml"
+)
+
+// formatMIME 导出格式的 MIME 类型
+var formatMIME = map[string]string{
+	FormatLRC:  "text/plain; charset=utf-8",
+	FormatSRT:  "application/x-subrip; charset=utf-8",
+	FormatVTT:  "text/vtt; charset=utf-8",
+	FormatTTML: "application/ttml+xml; charset=utf-8",
+}
+
+// Formats 返回支持的导出格式
+func Formats() []string {
+	return []string{FormatLRC, FormatSRT, FormatVTT, FormatTTML}
+}
+
+// Export 按格式导出，返回内容和 MIME 类型；字幕格式要求歌词已同步
+func (l *Lyrics) Export(format string) (string, string, error) {
+	format = strings.ToLower(format)
+	mime, ok := formatMIME[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported format: %s", format)
+	}
+	if format != FormatLRC && !l.Synced {
+		return "", "", fmt.Errorf("%s export requires synced lyrics", format)
+	}
+	switch format {
+	case FormatSRT:
+		return l.SRT(), mime, nil
+	case FormatVTT:
+		return l.WebVTT(), mime, nil
+	case FormatTTML:
+		return l.TTML(), mime, nil
+	default:
+		return l.LRC(), mime, nil
+	}
+}
+
+// splitSeconds 把秒数拆为时、分、秒、毫秒
+func splitSeconds(t float64) (h, m, s, ms int) {
+	total := int(math.Round(math.Max(0, t) * 1000))
+	return total / 3600000, total / 60000 % 60, total / 1000 % 60, total % 1000
+}
+
+func lrcTime(t float64) string {
+	h, m, s, ms := splitSeconds(t)
+	return fmt.Sprintf("%02d:%02d.%02d", h*60+m, s, ms/10)
+}
+
+func clockTime(t float64, sep string) string {
+	h, m, s, ms := splitSeconds(t)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, sep, ms)
+}
+
+// subtitleText 字幕中的一个条目，翻译和罗马音作为后续行
+func (line *Line) subtitleText() []string {
+	texts := []string{line.Text}
+	if line.Translation != "" {
+		texts = append(texts, line.Translation)
+	}
+	if line.Romanization != "" {
+		texts = append(texts, line.Romanization)
+	}
+	return texts
+}
+
+// LRC 输出标准 LRC，偏移已应用到时间轴因此不再写 [offset:]；逐字时间使用 Enhanced LRC 格式
+func (l *Lyrics) LRC() string {
+	var sb strings.Builder
+	meta := []struct{ tag, value string }{
+		{"ti", l.Metadata.Title},
+		{"ar", l.Metadata.Artist},
+		{"al", l.Metadata.Album},
+		{"au", l.Metadata.Author},
+		{"by", l.Metadata.By},
+		{"la", l.Metadata.Language},
+	}
+	for _, m := range meta {
+		if m.value != "" {
+			fmt.Fprintf(&sb, "[%s:%s]\n", m.tag, m.value)
+		}
+	}
+	if l.Metadata.Length > 0 {
+		fmt.Fprintf(&sb, "[length:%s]\n", lrcTime(l.Metadata.Length))
+	}
+
+	for i, line := range l.Lines {
+		if !l.Synced {
+			sb.WriteString(line.Text + "\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "[%s]", lrcTime(line.Time))
+		if len(line.Words) == 0 {
+			sb.WriteString(line.Text)
+		} else {
+			for _, w := range line.Words {
+				fmt.Fprintf(&sb, "<%s>%s", lrcTime(w.Time), w.Text)
+			}
+			fmt.Fprintf(&sb, "<%s>", lrcTime(line.Words[len(line.Words)-1].End))
+		}
+		sb.WriteString("\n")
+		// 与下一行之间有间隔时写空行标记结束时间
+		if i+1 < len(l.Lines) && l.Lines[i+1].Time-line.End > 0.001 {
+			fmt.Fprintf(&sb, "[%s]\n", lrcTime(line.End))
+		}
+	}
+	return sb.String()
+}
+
+// SRT 输出 SubRip 字幕
+func (l *Lyrics) SRT() string {
+	var sb strings.Builder
+	for i, line := range l.Lines {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1,
+			clockTime(line.Time, ","), clockTime(line.End, ","), strings.Join(line.subtitleText(), "\n"))
+	}
+	return sb.String()
+}
+
+// WebVTT 输出 WebVTT 字幕，逐字时间写为 cue 内的时间标签
+func (l *Lyrics) WebVTT() string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	var note []string
+	for _, v := range []string{l.Metadata.Artist, l.Metadata.Title} {
+		if v != "" {
+			note = append(note, v)
+		}
+	}
+	if len(note) > 0 {
+		sb.WriteString("\nNOTE " + strings.Join(note, " - ") + "\n")
+	}
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, line := range l.Lines {
+		fmt.Fprintf(&sb, "\n%s --> %s\n", clockTime(line.Time, "."), clockTime(line.End, "."))
+		if len(line.Words) == 0 {
+			sb.WriteString(escape.Replace(line.Text))
+		} else {
+			for i, w := range line.Words {
+				// 第一个字与 cue 同时开始，不需要时间标签
+				if i > 0 {
+					fmt.Fprintf(&sb, "<%s>", clockTime(w.Time, "."))
+				}
+				sb.WriteString(escape.Replace(w.Text))
+			}
+		}
+		for _, extra := range line.subtitleText()[1:] {
+			sb.WriteString("\n" + escape.Replace(extra))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// TTML 输出 TTML (Timed Text Markup Language)，逐字时间写为带 begin/end 的 span
+func (l *Lyrics) TTML() string {
+	escape := func(s string) string {
+		var sb strings.Builder
+		xml.EscapeText(&sb, []byte(s))
+		return sb.String()
+	}
+	ttmlTime := func(t float64) string { return clockTime(t, ".") }
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttm="http://www.w3.org/ns/ttml#metadata"`)
+	if l.Metadata.Language != "" {
+		fmt.Fprintf(&sb, ` xml:lang="%s"`, escape(l.Metadata.Language))
+	}
+	sb.WriteString(">\n")
+	if l.Metadata.Title != "" || l.Metadata.Artist != "" {
+		sb.WriteString("  <head>\n    <metadata>\n")
+		if l.Metadata.Title != "" {
+			fmt.Fprintf(&sb, "      <ttm:title>%s</ttm:title>\n", escape(l.Metadata.Title))
+		}
+		if l.Metadata.Artist != "" {
+			fmt.Fprintf(&sb, "      <ttm:agent type=\"person\"><ttm:name type=\"full\">%s</ttm:name></ttm:agent>\n", escape(l.Metadata.Artist))
+		}
+		sb.WriteString("    </metadata>\n  </head>\n")
+	}
+	sb.WriteString("  <body>\n    <div>\n")
+	for _, line := range l.Lines {
+		fmt.Fprintf(&sb, `      <p begin="%s" end="%s">`, ttmlTime(line.Time), ttmlTime(line.End))
+		if len(line.Words) == 0 {
+			sb.WriteString(escape(line.Text))
+		} else {
+			for _, w := range line.Words {
+				fmt.Fprintf(&sb, `<span begin="%s" end="%s">%s</span>`, ttmlTime(w.Time), ttmlTime(w.End), escape(w.Text))
+			}
+		}
+		for _, extra := range line.subtitleText()[1:] {
+			sb.WriteString("<br/>" + escape(extra))
+		}
+		sb.WriteString("</p>\n")
+	}
+	sb.WriteString("    </div>\n  </body>\n</tt>\n")
+	return sb.String()
+}
@@ -0,0 +1,314 @@
+// Package lyrics 解析 LRC / Enhanced LRC 歌词并导出为 SRT、WebVTT 与 TTML。
+//
+// 支持一行多个时间标签 ([00:12.00][01:30.00]副歌)、<mm:ss.xx> 逐字时间、
+// [offset:] 偏移以及 [ar:] [ti:] [al:] [by:] [length:] 等元数据。
+package lyrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLastLineDuration 最后一行没有后续时间标签且缺少 [length:] 时的显示时长 (秒)
+const defaultLastLineDuration = 5.0
+
+// Word Enhanced LRC 中的一个逐字时间片段
+type Word struct {
+	Time float64 `json:"time"`
+	End  float64 `json:"end"`
+	Text string  `json:"text"`
+}
+
+// Line 一行歌词，Time/End 为应用偏移后的秒数；未同步歌词的 Time 与 End 为 0
+type Line struct {
+	Time         float64 `json:"time"`
+	End          float64 `json:"end"`
+	Text         string  `json:"text"`
+	Words        []Word  `json:"words,omitempty"`
+	Translation  string  `json:"translation,omitempty"`
+	Romanization string  `json:"romanization,omitempty"`
+}
+
+// Metadata LRC 头部的 ID 标签，Offset 为毫秒，正值表示歌词提前显示
+type Metadata struct {
+	Title    string            `json:"title,omitempty"`
+	Artist   string            `json:"artist,omitempty"`
+	Album    string            `json:"album,omitempty"`
+	Author   string            `json:"author,omitempty"` // [au:] 词作者
+	By       string            `json:"by,omitempty"`     // [by:] 歌词制作者
+	Language string            `json:"language,omitempty"`
+	Length   float64           `json:"length,omitempty"` // 秒
+	Offset   int               `json:"offset,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"` // 其余标签，如 re、ve
+}
+
+// Lyrics 解析结果，Synced 为 false 时 Lines 只有文本
+type Lyrics struct {
+	Metadata Metadata `json:"metadata"`
+	Synced   bool     `json:"synced"`
+	Lines    []Line   `json:"lines"`
+}
+
+var (
+	// 行首的方括号标签，时间或元数据
+	bracketRegex = regexp.MustCompile(`^\[([^\[\]]*)\]`)
+	timeRegex    = regexp.MustCompile(`^(\d+):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	metaRegex    = regexp.MustCompile(`^([A-Za-z#]+):(.*)$`)
+	wordRegex    = regexp.MustCompile(`<(\d+):(\d{1,2})(?:[.:](\d{1,3}))?>`)
+)
+
+// parseTime 解析 mm:ss、mm:ss.x、mm:ss.xx、mm:ss.xxx (也接受 mm:ss:xx)
+func parseTime(m []string) float64 {
+	mins, _ := strconv.Atoi(m[1])
+	secs, _ := strconv.Atoi(m[2])
+	millis := 0
+	if m[3] != "" {
+		frac := (m[3] + "00")[:3]
+		millis, _ = strconv.Atoi(frac)
+	}
+	return float64(mins*60+secs) + float64(millis)/1000
+}
+
+// parseLength 解析 [length:] 的 mm:ss(.xx)，也接受纯秒数
+func parseLength(v string) float64 {
+	v = strings.TrimSpace(v)
+	if m := timeRegex.FindStringSubmatch(v); m != nil {
+		return parseTime(m)
+	}
+	f, _ := strconv.ParseFloat(v, 64)
+	return f
+}
+
+// rawLine 解析过程中的一行，words 的时间相对于文件 (未应用偏移)
+type rawLine struct {
+	time  float64
+	text  string
+	words []Word
+}
+
+// Parse 解析 LRC 文本。带时间标签的行按时间排序，同一时间保持原顺序；
+// 全文没有时间标签时作为未同步歌词逐行返回
+func Parse(text string) *Lyrics {
+	l := &Lyrics{}
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var timed []rawLine
+	var plain []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var times []float64
+		rest := line
+		isMeta := false
+		for {
+			m := bracketRegex.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+			tag := strings.TrimSpace(m[1])
+			if tm := timeRegex.FindStringSubmatch(tag); tm != nil {
+				times = append(times, parseTime(tm))
+			} else if mm := metaRegex.FindStringSubmatch(tag); mm != nil && len(times) == 0 {
+				l.Metadata.set(mm[1], mm[2])
+				isMeta = true
+			} else {
+				break // 普通文本中的方括号，如 [Chorus]
+			}
+			rest = strings.TrimSpace(rest[len(m[0]):])
+		}
+
+		if len(times) == 0 {
+			if !isMeta && rest != "" {
+				plain = append(plain, rest)
+			}
+			continue
+		}
+
+		lineText, words := parseWords(rest)
+		for _, t := range times {
+			shifted := make([]Word, len(words))
+			for i, w := range words {
+				// 重复的行按第一个时间标签的相对位置平移逐字时间
+				shifted[i] = w
+				shifted[i].Time += t - times[0]
+				if w.End > 0 {
+					shifted[i].End += t - times[0]
+				}
+			}
+			timed = append(timed, rawLine{time: t, text: lineText, words: shifted})
+		}
+	}
+
+	if len(timed) == 0 {
+		for _, t := range plain {
+			l.Lines = append(l.Lines, Line{Text: t})
+		}
+		return l
+	}
+
+	l.Synced = true
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].time < timed[j].time })
+
+	offset := float64(l.Metadata.Offset) / 1000
+	adjust := func(t float64) float64 { return math.Max(0, t-offset) }
+
+	for i, r := range timed {
+		// 空行只用来标记上一行的结束时间
+		if r.text == "" {
+			continue
+		}
+		end := r.time + defaultLastLineDuration
+		if i+1 < len(timed) {
+			end = timed[i+1].time
+		} else if l.Metadata.Length > r.time {
+			end = l.Metadata.Length
+		}
+		line := Line{Time: adjust(r.time), End: adjust(end), Text: r.text}
+		for j, w := range r.words {
+			word := Word{Time: adjust(w.Time), Text: w.Text}
+			switch {
+			case w.End > 0:
+				word.End = adjust(w.End)
+			case j+1 < len(r.words):
+				word.End = adjust(r.words[j+1].Time)
+			default:
+				word.End = line.End
+			}
+			line.Words = append(line.Words, word)
+		}
+		l.Lines = append(l.Lines, line)
+	}
+	return l
+}
+
+// parseWords 拆分 Enhanced LRC 的 <mm:ss.xx> 逐字时间，返回去掉标签的文本。
+// 后面没有文字的时间标签作为前一个字的结束时间，其余字的 End 由 Parse 补全
+func parseWords(s string) (string, []Word) {
+	locs := wordRegex.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s[:locs[0][0]])
+	var words []Word
+	for i, loc := range locs {
+		m := []string{s[loc[0]:loc[1]], s[loc[2]:loc[3]], s[loc[4]:loc[5]], ""}
+		if loc[6] >= 0 {
+			m[3] = s[loc[6]:loc[7]]
+		}
+		t := parseTime(m)
+
+		next := len(s)
+		if i+1 < len(locs) {
+			next = locs[i+1][0]
+		}
+		segment := s[loc[1]:next]
+		sb.WriteString(segment)
+
+		if strings.TrimSpace(segment) == "" {
+			if len(words) > 0 {
+				words[len(words)-1].End = t
+			}
+			continue
+		}
+		words = append(words, Word{Time: t, Text: segment})
+	}
+	return strings.TrimSpace(sb.String()), words
+}
+
+func (m *Metadata) set(key, value string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	switch key {
+	case "ti":
+		m.Title = value
+	case "ar":
+		m.Artist = value
+	case "al":
+		m.Album = value
+	case "au":
+		m.Author = value
+	case "by":
+		m.By = value
+	case "la", "lang":
+		m.Language = value
+	case "length":
+		m.Length = parseLength(value)
+	case "offset":
+		m.Offset, _ = strconv.Atoi(strings.TrimPrefix(value, "+"))
+	default:
+		if m.Extra == nil {
+			m.Extra = make(map[string]string)
+		}
+		m.Extra[key] = value
+	}
+}
+
+// 附加层类型
+const (
+	LayerTranslation  = "translation"
+	LayerRomanization = "romanization"
+)
+
+// MergeLayer 把翻译或罗马音按时间轴并入对应的行，返回是否有行匹配。
+// 两份歌词的时间精度可能不同 (如 .12 与 .120)，取 0.1 秒内最接近的一行
+func (l *Lyrics) MergeLayer(layer *Lyrics, kind string) bool {
+	if layer == nil || !layer.Synced || !l.Synced {
+		return false
+	}
+	matched := false
+	for i := range l.Lines {
+		line := &l.Lines[i]
+		best, bestDiff := -1, 0.1
+		for j, c := range layer.Lines {
+			if d := math.Abs(c.Time - line.Time); d <= bestDiff {
+				best, bestDiff = j, d
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		switch kind {
+		case LayerTranslation:
+			line.Translation = layer.Lines[best].Text
+		case LayerRomanization:
+			line.Romanization = layer.Lines[best].Text
+		default:
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// shiftRegex 方括号行时间和尖括号逐字时间，不匹配 [offset:] 等元数据
+var shiftRegex = regexp.MustCompile(`([\[<])(\d+):(\d{1,2})(?:([.:])(\d{1,3}))?([\]>])`)
+
+// ShiftText 把 LRC 文本中所有时间标签平移 seconds 秒 (负数提前，最早为 0)，
+// 只改写时间本身，元数据、空行和小数位数保持不变
+func ShiftText(text string, seconds float64) string {
+	return shiftRegex.ReplaceAllStringFunc(text, func(tag string) string {
+		m := shiftRegex.FindStringSubmatch(tag)
+		t := parseTime([]string{m[0], m[2], m[3], m[5]}) + seconds
+		total := int(math.Round(math.Max(0, t) * 1000))
+
+		digits := len(m[5])
+		if digits == 0 {
+			digits = 2
+		}
+		sep := m[4]
+		if sep == "" {
+			sep = "."
+		}
+		frac := fmt.Sprintf("%03d", total%1000)[:digits]
+		return fmt.Sprintf("%s%02d:%02d%s%s%s", m[1], total/60000, total/1000%60, sep, frac, m[6])
+	})
+}
@@ -0,0 +1,245 @@
+package lyrics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParseTimeFormats(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want float64
+	}{
+		{"01:02", 62},
+		{"01:02.5", 62.5},
+		{"01:02.50", 62.5},
+		{"01:02.500", 62.5},
+		{"01:02:50", 62.5},
+		{"100:00.01", 6000.01},
+	}
+	for _, tt := range tests {
+		l := Parse("[" + tt.tag + "]x")
+		if len(l.Lines) != 1 || !approx(l.Lines[0].Time, tt.want) {
+			t.Errorf("[%s]: lines = %+v, want time %v", tt.tag, l.Lines, tt.want)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		synced bool
+		want   []Line
+	}{
+		{
+			name:   "sorted with end from next line",
+			text:   "[00:05.00]second\n[00:01.00]first",
+			synced: true,
+			want: []Line{
+				{Time: 1, End: 5, Text: "first"},
+				{Time: 5, End: 10, Text: "second"},
+			},
+		},
+		{
+			name:   "repeated time tags",
+			text:   "[00:01.00][00:09.00]chorus\n[00:04.00]verse",
+			synced: true,
+			want: []Line{
+				{Time: 1, End: 4, Text: "chorus"},
+				{Time: 4, End: 9, Text: "verse"},
+				{Time: 9, End: 14, Text: "chorus"},
+			},
+		},
+		{
+			name:   "empty line marks end",
+			text:   "[00:01.00]a\n[00:03.00]\n[00:10.00]b",
+			synced: true,
+			want: []Line{
+				{Time: 1, End: 3, Text: "a"},
+				{Time: 10, End: 15, Text: "b"},
+			},
+		},
+		{
+			name:   "length ends last line",
+			text:   "[length:01:00]\n[00:50.00]last",
+			synced: true,
+			want:   []Line{{Time: 50, End: 60, Text: "last"}},
+		},
+		{
+			name:   "offset shifts earlier and clamps at zero",
+			text:   "[offset:+1500]\n[00:01.00]a\n[00:04.00]b",
+			synced: true,
+			want: []Line{
+				{Time: 0, End: 2.5, Text: "a"},
+				{Time: 2.5, End: 7.5, Text: "b"},
+			},
+		},
+		{
+			name:   "negative offset delays",
+			text:   "[offset:-500]\n[00:01.00]a",
+			synced: true,
+			want:   []Line{{Time: 1.5, End: 6.5, Text: "a"}},
+		},
+		{
+			name:   "brackets in text are kept",
+			text:   "[00:01.00][Chorus] la la",
+			synced: true,
+			want:   []Line{{Time: 1, End: 6, Text: "[Chorus] la la"}},
+		},
+		{
+			name:   "unsynced",
+			text:   "\ufeff[ti:Song]\r\nfirst line\r\n\r\n[Chorus]\r\n",
+			synced: false,
+			want:   []Line{{Text: "first line"}, {Text: "[Chorus]"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Parse(tt.text)
+			if l.Synced != tt.synced {
+				t.Fatalf("Synced = %v, want %v", l.Synced, tt.synced)
+			}
+			if len(l.Lines) != len(tt.want) {
+				t.Fatalf("lines = %+v, want %+v", l.Lines, tt.want)
+			}
+			for i, want := range tt.want {
+				got := l.Lines[i]
+				if got.Text != want.Text || !approx(got.Time, want.Time) || !approx(got.End, want.End) {
+					t.Errorf("line %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	l := Parse("[ti: Title ]\n[AR:Artist]\n[al:Album]\n[au:Writer]\n[by:Maker]\n[la:ja]\n[length:3:05.5]\n[offset:200]\n[re:Editor]\n[00:01.00]x")
+	want := Metadata{
+		Title:    "Title",
+		Artist:   "Artist",
+		Album:    "Album",
+		Author:   "Writer",
+		By:       "Maker",
+		Language: "ja",
+		Length:   185.5,
+		Offset:   200,
+		Extra:    map[string]string{"re": "Editor"},
+	}
+	if !reflect.DeepEqual(l.Metadata, want) {
+		t.Errorf("metadata = %+v, want %+v", l.Metadata, want)
+	}
+
+	if got := Parse("[length:215]").Metadata.Length; got != 215 {
+		t.Errorf("length in seconds = %v, want 215", got)
+	}
+}
+
+func TestParseWords(t *testing.T) {
+	l := Parse("[00:01.00]<00:01.00>Hel<00:01.50>lo <00:02.00>world<00:02.80>\n[00:04.00]<00:04.00>next")
+	if len(l.Lines) != 2 {
+		t.Fatalf("lines = %+v", l.Lines)
+	}
+	first := l.Lines[0]
+	if first.Text != "Hello world" {
+		t.Errorf("text = %q", first.Text)
+	}
+	want := []Word{
+		{Time: 1, End: 1.5, Text: "Hel"},
+		{Time: 1.5, End: 2, Text: "lo "},
+		{Time: 2, End: 2.8, Text: "world"},
+	}
+	if len(first.Words) != len(want) {
+		t.Fatalf("words = %+v", first.Words)
+	}
+	for i, w := range want {
+		got := first.Words[i]
+		if got.Text != w.Text || !approx(got.Time, w.Time) || !approx(got.End, w.End) {
+			t.Errorf("word %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// 没有结束标签的最后一个字持续到行尾
+	last := l.Lines[1].Words
+	if len(last) != 1 || !approx(last[0].End, l.Lines[1].End) {
+		t.Errorf("last words = %+v, line end %v", last, l.Lines[1].End)
+	}
+}
+
+func TestParseWordsRepeatedLine(t *testing.T) {
+	l := Parse("[00:01.00][00:10.00]<00:01.00>a<00:01.50>b")
+	if len(l.Lines) != 2 {
+		t.Fatalf("lines = %+v", l.Lines)
+	}
+	words := l.Lines[1].Words
+	if len(words) != 2 || !approx(words[0].Time, 10) || !approx(words[1].Time, 10.5) {
+		t.Errorf("repeated words = %+v, want shifted by 9s", words)
+	}
+}
+
+func TestMergeLayer(t *testing.T) {
+	l := Parse("[00:01.00]hello\n[00:05.00]world\n[00:09.00]alone")
+	tr := Parse("[00:01.05]你好\n[00:05.0]世界\n[00:09.50]too far")
+
+	if !l.MergeLayer(tr, LayerTranslation) {
+		t.Fatal("MergeLayer returned false")
+	}
+	got := []string{l.Lines[0].Translation, l.Lines[1].Translation, l.Lines[2].Translation}
+	want := []string{"你好", "世界", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("translations = %q, want %q", got, want)
+	}
+
+	if l.MergeLayer(Parse("plain text"), LayerRomanization) {
+		t.Error("unsynced layer should not merge")
+	}
+	if l.MergeLayer(tr, "unknown") {
+		t.Error("unknown layer kind should not merge")
+	}
+}
+
+func TestShiftText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		seconds float64
+		want    string
+	}{
+		{"forward", "[00:01.00]a", 1.5, "[00:02.50]a"},
+		{"backward clamps", "[00:01.00]a", -3, "[00:00.00]a"},
+		{"minute carry", "[00:59.90]a", 0.2, "[01:00.10]a"},
+		{"keeps precision", "[00:01.5]a\n[00:02.250]b", 1, "[00:02.5]a\n[00:03.250]b"},
+		{"keeps separator", "[00:01:00]a", 1, "[00:02:00]a"},
+		{"word tags", "[00:01.00]<00:01.00>a<00:01.50>b", 2, "[00:03.00]<00:03.00>a<00:03.50>b"},
+		{"metadata untouched", "[offset:500]\n[ti:01:02]\n\n[00:01.00]a", 1, "[offset:500]\n[ti:01:02]\n\n[00:02.00]a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShiftText(tt.text, tt.seconds); got != tt.want {
+				t.Errorf("ShiftText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportSRT(t *testing.T) {
+	l := Parse("[00:01.00]hello\n[01:02.50]world")
+	l.Lines[0].Translation = "你好"
+	got, mime, err := l.Export("SRT")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:01:02,500\nhello\n你好\n\n2\n00:01:02,500 --> 00:01:07,500\nworld\n\n"
+	if got != want || mime != formatMIME[FormatSRT] {
+		t.Errorf("SRT = %q (%s), want %q", got, mime, want)
+	}
+
+	if _, _, err := Parse("plain").Export(FormatVTT); err == nil {
+		t.Error("expected error exporting unsynced lyrics as VTT")
+	}
+}
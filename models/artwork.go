@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 封面图片来源
+const (
+	ArtworkSourceFetch    = "fetch"    // 网络获取
+	ArtworkSourceUpload   = "upload"   // 手动上传
+	ArtworkSourceEmbedded = "embedded" // 音频文件内嵌
+	ArtworkSourceCache    = "cache"    // 从旧的 md5 缓存文件导入
+)
+
+// Artwork 按内容 SHA-256 去重的封面图片，文件保存在 artwork.Store 中
+type Artwork struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Hash      string    `gorm:"size:64;uniqueIndex;not null" json:"hash"`
+	MIME      string    `gorm:"column:mime;size:50" json:"mime"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Size      int64     `json:"size"`
+	Source    string    `gorm:"size:20" json:"source"`
+	SourceURL string    `gorm:"column:source_url;size:1000" json:"source_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Artwork) TableName() string {
+	return "artwork"
+}
+
+// AlbumArtwork 专辑 (目录 + 专辑名，与专辑检查的分组一致) 使用的封面，
+// 曲目没有单独设置封面时使用
+type AlbumArtwork struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Folder    string    `gorm:"size:1000;uniqueIndex:idx_album_artwork" json:"folder"`
+	Album     string    `gorm:"size:500;uniqueIndex:idx_album_artwork" json:"album"`
+	ArtworkID uint      `gorm:"index;not null" json:"artwork_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AlbumArtwork) TableName() string {
+	return "album_artwork"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// 获取内容类型
+const (
+	FetchKindLyrics = "lyrics"
+	FetchKindCover  = "cover"
+)
+
+// 单首曲目的获取结果
+const (
+	FetchStatusSuccess  = "success"
+	FetchStatusNotFound = "not_found" // 没有达到置信度要求的结果
+	FetchStatusFailed   = "failed"    // 网络或保存出错，可以重试
+	FetchStatusSkipped  = "skipped"   // 已有内容且未要求覆盖，或最近未找到且标签未改变
+)
+
+// FetchResult 批量获取任务中一首曲目一种内容的结果，同一任务的记录共享 JobID
+type FetchResult struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      string    `gorm:"size:36;index;not null" json:"job_id"`
+	MusicID    uint      `gorm:"index;not null" json:"music_id"`
+	Kind       string    `gorm:"size:10;not null" json:"kind"`
+	Status     string    `gorm:"size:20;index;not null" json:"status"`
+	Provider   string    `gorm:"size:20" json:"provider"`
+	Confidence float64   `json:"confidence"`
+	Error      string    `gorm:"size:500" json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (FetchResult) TableName() string {
+	return "fetch_results"
+}
+
+// FetchMiss 曲目某种内容最近一次没有找到，在过期前或标签改变前批量获取时跳过。
+// TagKey 是获取时所用标签的摘要，标签改变后记录自动失效
+type FetchMiss struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MusicID   uint      `gorm:"uniqueIndex:idx_fetch_miss;not null" json:"music_id"`
+	Kind      string    `gorm:"size:10;uniqueIndex:idx_fetch_miss;not null" json:"kind"`
+	TagKey    string    `gorm:"size:64;not null" json:"tag_key"`
+	Reason    string    `gorm:"size:500" json:"reason"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (FetchMiss) TableName() string {
+	return "fetch_misses"
+}
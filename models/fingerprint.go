@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// 指纹识别状态
+const (
+	FingerprintMatched = "matched"
+	FingerprintNoMatch = "no_match"
+	FingerprintFailed  = "failed"
+)
+
+// Fingerprint 曲目的 Chromaprint 指纹及 AcoustID 查询结果
+type Fingerprint struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MusicID     uint      `gorm:"uniqueIndex;not null" json:"music_id"`
+	Fingerprint string    `gorm:"type:text" json:"fingerprint"`
+	Duration    int       `gorm:"default:0" json:"duration"`
+	AcoustID    string    `gorm:"column:acoustid;size:36;index" json:"acoustid"`
+	RecordingID string    `gorm:"size:36" json:"recording_id"`
+	Title       string    `gorm:"size:255" json:"title"`
+	Artist      string    `gorm:"size:255" json:"artist"`
+	Album       string    `gorm:"size:255" json:"album"`
+	Score       float64   `gorm:"default:0" json:"score"`
+	Status      string    `gorm:"size:20;index" json:"status"`
+	Error       string    `gorm:"size:500" json:"error"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Fingerprint) TableName() string {
+	return "fingerprints"
+}
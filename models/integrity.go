@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// 完整性检查结论
+const (
+	IntegrityOK      = "ok"
+	IntegrityWarning = "warning" // 可播放，但文件结构不规范
+	IntegrityCorrupt = "corrupt" // 音频数据损坏，建议替换
+)
+
+// 完整性问题
+const (
+	IntegrityIssueNoFrames       = "no_frames"
+	IntegrityIssueSyncLoss       = "sync_loss"
+	IntegrityIssueTruncated      = "truncated_final_frame"
+	IntegrityIssueCRC            = "crc_error"
+	IntegrityIssueMixedRates     = "mixed_sample_rates"
+	IntegrityIssueGarbage        = "garbage_between_frames"
+	IntegrityIssueDuplicateID3v2 = "duplicate_id3v2"
+	IntegrityIssueDuplicateID3v1 = "duplicate_id3v1"
+)
+
+// IntegrityReport MP3 帧级完整性检查结果，以 JSON 保存在 music 表中
+type IntegrityReport struct {
+	Status         string    `json:"status"`
+	Issues         []string  `json:"issues"`
+	Frames         int       `json:"frames"`
+	SyncErrors     int       `json:"sync_errors"`
+	GarbageBytes   int64     `json:"garbage_bytes"`
+	GarbageRegions int       `json:"garbage_regions"`
+	TruncatedBytes int       `json:"truncated_bytes"`
+	CRCChecked     int       `json:"crc_checked"`
+	CRCErrors      int       `json:"crc_errors"`
+	SampleRates    []int     `json:"sample_rates"`
+	ID3v2Tags      int       `json:"id3v2_tags"`
+	ID3v1Tags      int       `json:"id3v1_tags"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
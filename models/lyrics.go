@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 歌词版本来源
+const (
+	LyricsSourceManual = "manual" // 手动编辑
+	LyricsSourceFetch  = "fetch"  // 网络获取
+	LyricsSourceCache  = "cache"  // 从旧的 md5 缓存文件导入
+	LyricsSourceAdjust = "adjust" // 时间轴整体平移
+	LyricsSourceRevert = "revert" // 恢复历史版本
+)
+
+// Lyrics 曲目的一个歌词版本，每次修改新增一行，Current 标记当前使用的版本。
+// Content 为空的版本表示歌词被清除
+type Lyrics struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	MusicID      uint      `gorm:"index;not null" json:"music_id"`
+	Version      int       `gorm:"not null" json:"version"`
+	Content      string    `gorm:"type:text" json:"content"`
+	Translation  string    `gorm:"type:text" json:"translation"`
+	Romanization string    `gorm:"type:text" json:"romanization"`
+	Source       string    `gorm:"size:20;not null" json:"source"`
+	Language     string    `gorm:"size:20" json:"language"`
+	Synced       bool      `json:"synced"`
+	Current      bool      `gorm:"index" json:"current"`
+	User         string    `gorm:"size:100" json:"user"`
+	Note         string    `gorm:"size:255" json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (Lyrics) TableName() string {
+	return "lyrics"
+}
@@ -2,36 +2,79 @@ package models
 
 import (
 	"fmt"
+	"go-music-tag/secret"
 	"time"
 )
 
 type Music struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	FilePath    string     `gorm:"uniqueIndex;size:500;not null" json:"file_path"`
-	FileName    string     `gorm:"size:255;not null" json:"file_name"`
-	FileSize    int64      `gorm:"not null" json:"file_size"`
-	Title       string     `gorm:"size:255" json:"title"`
-	Artist      string     `gorm:"size:255" json:"artist"`
-	Album       string     `gorm:"size:255" json:"album"`
-	AlbumArtist string     `gorm:"size:255;column:album_artist" json:"album_artist"`
-	Composer    string     `gorm:"size:255" json:"composer"`
-	Genre       string     `gorm:"size:100" json:"genre"`
-	Year        int        `gorm:"default:0" json:"year"`
-	TrackNumber int        `gorm:"column:track_number;default:0" json:"track_number"`
-	DiscNumber  int        `gorm:"column:disc_number;default:0" json:"disc_number"`
-	Duration    int        `gorm:"default:0" json:"duration"`
-	BitRate     int        `gorm:"column:bit_rate;default:0" json:"bit_rate"`
-	SampleRate  int        `gorm:"column:sample_rate;default:0" json:"sample_rate"`
-	Format      string     `gorm:"size:50" json:"format"`
-	HasLyrics   bool       `gorm:"column:has_lyrics;default:false" json:"has_lyrics"`
-	HasCover    bool       `gorm:"column:has_cover;default:false" json:"has_cover"`
-	CoverMIME   string     `gorm:"column:cover_mime;size:50" json:"cover_mime"`
-	Comment     string     `gorm:"size:500" json:"comment"`
-	ScanStatus  string     `gorm:"size:20;default:pending" json:"scan_status"`
-	ScanError   string     `gorm:"size:500" json:"scan_error"`
-	ScannedAt   *time.Time `json:"scanned_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	FilePath    string `gorm:"uniqueIndex;size:500;not null" json:"file_path"`
+	FileName    string `gorm:"size:255;not null" json:"file_name"`
+	FileSize    int64  `gorm:"not null" json:"file_size"`
+	Title       string `gorm:"size:255" json:"title"`
+	Artist      string `gorm:"size:255" json:"artist"`
+	Album       string `gorm:"size:255" json:"album"`
+	AlbumArtist string `gorm:"size:255;column:album_artist" json:"album_artist"`
+	Composer    string `gorm:"size:255" json:"composer"`
+	Genre       string `gorm:"size:100" json:"genre"`
+	Year        int    `gorm:"default:0" json:"year"`
+	TrackNumber int    `gorm:"column:track_number;default:0" json:"track_number"`
+	DiscNumber  int    `gorm:"column:disc_number;default:0" json:"disc_number"`
+	Duration    int    `gorm:"default:0" json:"duration"`
+	BitRate     int    `gorm:"column:bit_rate;default:0" json:"bit_rate"`
+	SampleRate  int    `gorm:"column:sample_rate;default:0" json:"sample_rate"`
+	Format      string `gorm:"size:50" json:"format"`
+	AudioHash   string `gorm:"column:audio_hash;size:64;index" json:"audio_hash"` // 去除标签后音频数据的 SHA-256
+	HasLyrics   bool   `gorm:"column:has_lyrics;default:false" json:"has_lyrics"`
+	HasCover    bool   `gorm:"column:has_cover;default:false" json:"has_cover"`
+	CoverMIME   string `gorm:"column:cover_mime;size:50" json:"cover_mime"`
+	ArtworkID   *uint  `gorm:"column:artwork_id;index" json:"artwork_id"` // 曲目单独设置的封面，为空时使用专辑封面
+	Comment     string `gorm:"size:500" json:"comment"`
+
+	// MusicBrainz 标识 (Picard 写入的 TXXX/UFID 帧或 Vorbis 注释)
+	MBRecordingID    string `gorm:"column:mb_recording_id;size:36;index" json:"mb_recording_id"`
+	MBTrackID        string `gorm:"column:mb_track_id;size:36" json:"mb_track_id"`
+	MBReleaseID      string `gorm:"column:mb_release_id;size:36;index" json:"mb_release_id"`
+	MBReleaseGroupID string `gorm:"column:mb_release_group_id;size:36" json:"mb_release_group_id"`
+	MBArtistID       string `gorm:"column:mb_artist_id;size:500" json:"mb_artist_id"`
+	MBAlbumArtistID  string `gorm:"column:mb_album_artist_id;size:500" json:"mb_album_artist_id"`
+
+	// 扩展标签
+	FeaturedArtists     string   `gorm:"column:featured_artists;size:500" json:"featured_artists"` // 从标题/艺术家中提取的 feat. 艺术家，以 "; " 分隔
+	ISRC                string   `gorm:"column:isrc;size:20" json:"isrc"`
+	Label               string   `gorm:"size:255" json:"label"`
+	CatalogNumber       string   `gorm:"column:catalog_number;size:100" json:"catalog_number"`
+	BPM                 int      `gorm:"column:bpm;default:0" json:"bpm"`
+	InitialKey          string   `gorm:"column:initial_key;size:20" json:"initial_key"`
+	ReleaseType         string   `gorm:"column:release_type;size:50" json:"release_type"`
+	OriginalYear        int      `gorm:"column:original_year;default:0" json:"original_year"`
+	ArtistSort          string   `gorm:"column:artist_sort;size:255" json:"artist_sort"`
+	AlbumSort           string   `gorm:"column:album_sort;size:255" json:"album_sort"`
+	TitleSort           string   `gorm:"column:title_sort;size:255" json:"title_sort"`
+	AlbumArtistSort     string   `gorm:"column:album_artist_sort;size:255" json:"album_artist_sort"`
+	ComposerSort        string   `gorm:"column:composer_sort;size:255" json:"composer_sort"`
+	ReplayGainTrackGain *float64 `gorm:"column:replaygain_track_gain" json:"replaygain_track_gain"`
+	ReplayGainTrackPeak *float64 `gorm:"column:replaygain_track_peak" json:"replaygain_track_peak"`
+	ReplayGainAlbumGain *float64 `gorm:"column:replaygain_album_gain" json:"replaygain_album_gain"`
+	ReplayGainAlbumPeak *float64 `gorm:"column:replaygain_album_peak" json:"replaygain_album_peak"`
+	Loudness            *float64 `gorm:"column:loudness" json:"loudness"` // 响度分析得到的 EBU R128 综合响度 (LUFS)
+
+	// 音频流信息
+	DurationEstimated bool             `gorm:"column:duration_estimated;default:false" json:"duration_estimated"` // 无法解析音频帧，时长按文件大小估算
+	FrameCount        int              `gorm:"column:frame_count;default:0" json:"frame_count"`
+	BitRateMode       string           `gorm:"column:bit_rate_mode;size:10" json:"bit_rate_mode"` // CBR | VBR | ABR
+	Encoder           string           `gorm:"size:50" json:"encoder"`                            // 如 LAME3.100
+	EncoderPreset     string           `gorm:"column:encoder_preset;size:50" json:"encoder_preset"`
+	EncoderDelay      int              `gorm:"column:encoder_delay;default:0" json:"encoder_delay"`           // 无缝播放需跳过的开头采样数
+	EncoderPadding    int              `gorm:"column:encoder_padding;default:0" json:"encoder_padding"`       // 无缝播放需丢弃的末尾采样数
+	IntegrityStatus   string           `gorm:"column:integrity_status;size:20;index" json:"integrity_status"` // ok | warning | corrupt，空表示未检查
+	Integrity         *IntegrityReport `gorm:"column:integrity;serializer:json" json:"integrity"`
+
+	ScanStatus string     `gorm:"size:20;default:pending" json:"scan_status"`
+	ScanError  string     `gorm:"size:500" json:"scan_error"`
+	ScannedAt  *time.Time `json:"scanned_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 func (Music) TableName() string {
@@ -39,32 +82,70 @@ func (Music) TableName() string {
 }
 
 type MusicResponse struct {
-	ID          uint       `json:"id"`
-	FilePath    string     `json:"file_path"`
-	FileName    string     `json:"file_name"`
-	FileSize    int64      `json:"file_size"`
-	FileSizeStr string     `json:"file_size_str"`
-	Title       string     `json:"title"`
-	Artist      string     `json:"artist"`
-	Album       string     `json:"album"`
-	AlbumArtist string     `json:"album_artist"`
-	Composer    string     `json:"composer"`
-	Genre       string     `json:"genre"`
-	Year        int        `json:"year"`
-	TrackNumber int        `json:"track_number"`
-	DiscNumber  int        `json:"disc_number"`
-	Duration    int        `json:"duration"`
-	DurationStr string     `json:"duration_str"`
-	BitRate     int        `json:"bit_rate"`
-	SampleRate  int        `json:"sample_rate"`
-	Format      string     `json:"format"`
-	HasCover    bool       `json:"has_cover"`
-	Comment     string     `json:"comment"`
-	ScanStatus  string     `json:"scan_status"`
-	ScanError   string     `json:"scan_error"`
-	ScannedAt   *time.Time `json:"scanned_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID          uint   `json:"id"`
+	FilePath    string `json:"file_path"`
+	FileName    string `json:"file_name"`
+	FileSize    int64  `json:"file_size"`
+	FileSizeStr string `json:"file_size_str"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Album       string `json:"album"`
+	AlbumArtist string `json:"album_artist"`
+	Composer    string `json:"composer"`
+	Genre       string `json:"genre"`
+	Year        int    `json:"year"`
+	TrackNumber int    `json:"track_number"`
+	DiscNumber  int    `json:"disc_number"`
+	Duration    int    `json:"duration"`
+	DurationStr string `json:"duration_str"`
+	BitRate     int    `json:"bit_rate"`
+	SampleRate  int    `json:"sample_rate"`
+	Format      string `json:"format"`
+	AudioHash   string `json:"audio_hash"`
+	HasCover    bool   `json:"has_cover"`
+	Comment     string `json:"comment"`
+
+	MBRecordingID    string `json:"mb_recording_id"`
+	MBTrackID        string `json:"mb_track_id"`
+	MBReleaseID      string `json:"mb_release_id"`
+	MBReleaseGroupID string `json:"mb_release_group_id"`
+	MBArtistID       string `json:"mb_artist_id"`
+	MBAlbumArtistID  string `json:"mb_album_artist_id"`
+
+	FeaturedArtists     string   `json:"featured_artists"`
+	ISRC                string   `json:"isrc"`
+	Label               string   `json:"label"`
+	CatalogNumber       string   `json:"catalog_number"`
+	BPM                 int      `json:"bpm"`
+	InitialKey          string   `json:"initial_key"`
+	ReleaseType         string   `json:"release_type"`
+	OriginalYear        int      `json:"original_year"`
+	ArtistSort          string   `json:"artist_sort"`
+	AlbumSort           string   `json:"album_sort"`
+	TitleSort           string   `json:"title_sort"`
+	AlbumArtistSort     string   `json:"album_artist_sort"`
+	ComposerSort        string   `json:"composer_sort"`
+	ReplayGainTrackGain *float64 `json:"replaygain_track_gain"`
+	ReplayGainTrackPeak *float64 `json:"replaygain_track_peak"`
+	ReplayGainAlbumGain *float64 `json:"replaygain_album_gain"`
+	ReplayGainAlbumPeak *float64 `json:"replaygain_album_peak"`
+	Loudness            *float64 `json:"loudness"`
+
+	DurationEstimated bool             `json:"duration_estimated"`
+	FrameCount        int              `json:"frame_count"`
+	BitRateMode       string           `json:"bit_rate_mode"`
+	Encoder           string           `json:"encoder"`
+	EncoderPreset     string           `json:"encoder_preset"`
+	EncoderDelay      int              `json:"encoder_delay"`
+	EncoderPadding    int              `json:"encoder_padding"`
+	IntegrityStatus   string           `json:"integrity_status"`
+	Integrity         *IntegrityReport `json:"integrity"`
+
+	ScanStatus string     `json:"scan_status"`
+	ScanError  string     `json:"scan_error"`
+	ScannedAt  *time.Time `json:"scanned_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
 func (m *Music) ToResponse() MusicResponse {
@@ -88,13 +169,51 @@ func (m *Music) ToResponse() MusicResponse {
 		BitRate:     m.BitRate,
 		SampleRate:  m.SampleRate,
 		Format:      m.Format,
+		AudioHash:   m.AudioHash,
 		HasCover:    m.HasCover,
 		Comment:     m.Comment,
-		ScanStatus:  m.ScanStatus,
-		ScanError:   m.ScanError,
-		ScannedAt:   m.ScannedAt,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
+
+		MBRecordingID:    m.MBRecordingID,
+		MBTrackID:        m.MBTrackID,
+		MBReleaseID:      m.MBReleaseID,
+		MBReleaseGroupID: m.MBReleaseGroupID,
+		MBArtistID:       m.MBArtistID,
+		MBAlbumArtistID:  m.MBAlbumArtistID,
+
+		FeaturedArtists:     m.FeaturedArtists,
+		ISRC:                m.ISRC,
+		Label:               m.Label,
+		CatalogNumber:       m.CatalogNumber,
+		BPM:                 m.BPM,
+		InitialKey:          m.InitialKey,
+		ReleaseType:         m.ReleaseType,
+		OriginalYear:        m.OriginalYear,
+		ArtistSort:          m.ArtistSort,
+		AlbumSort:           m.AlbumSort,
+		TitleSort:           m.TitleSort,
+		AlbumArtistSort:     m.AlbumArtistSort,
+		ComposerSort:        m.ComposerSort,
+		ReplayGainTrackGain: m.ReplayGainTrackGain,
+		ReplayGainTrackPeak: m.ReplayGainTrackPeak,
+		ReplayGainAlbumGain: m.ReplayGainAlbumGain,
+		ReplayGainAlbumPeak: m.ReplayGainAlbumPeak,
+		Loudness:            m.Loudness,
+
+		DurationEstimated: m.DurationEstimated,
+		FrameCount:        m.FrameCount,
+		BitRateMode:       m.BitRateMode,
+		Encoder:           m.Encoder,
+		EncoderPreset:     m.EncoderPreset,
+		EncoderDelay:      m.EncoderDelay,
+		EncoderPadding:    m.EncoderPadding,
+		IntegrityStatus:   m.IntegrityStatus,
+		Integrity:         m.Integrity,
+
+		ScanStatus: m.ScanStatus,
+		ScanError:  m.ScanError,
+		ScannedAt:  m.ScannedAt,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
 	}
 }
 
@@ -114,7 +233,7 @@ type WebDAVConfig struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
 	URL        string     `gorm:"size:500;not null" json:"url"`
 	Username   string     `gorm:"size:255" json:"username"`
-	Password   string     `gorm:"size:255" json:"password"`
+	Password   string     `gorm:"size:500" json:"-"` // AES-GCM 密文，见 SetPassword
 	RootPath   string     `gorm:"size:500;default:/" json:"root_path"`
 	Enabled    bool       `gorm:"default:true" json:"enabled"`
 	LastTest   *time.Time `json:"last_test"`
@@ -129,33 +248,48 @@ func (WebDAVConfig) TableName() string {
 }
 
 type WebDAVConfigResponse struct {
-	ID         uint       `json:"id"`
-	URL        string     `json:"url"`
-	Username   string     `json:"username"`
-	Password   string     `json:"password"`
-	RootPath   string     `json:"root_path"`
-	Enabled    bool       `json:"enabled"`
-	LastTest   *time.Time `json:"last_test"`
-	TestStatus string     `json:"test_status"`
-	TestError  string     `json:"test_error"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID          uint       `json:"id"`
+	URL         string     `json:"url"`
+	Username    string     `json:"username"`
+	HasPassword bool       `json:"has_password"`
+	RootPath    string     `json:"root_path"`
+	Enabled     bool       `json:"enabled"`
+	LastTest    *time.Time `json:"last_test"`
+	TestStatus  string     `json:"test_status"`
+	TestError   string     `json:"test_error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 func (c *WebDAVConfig) ToResponse() WebDAVConfigResponse {
 	return WebDAVConfigResponse{
-		ID:         c.ID,
-		URL:        c.URL,
-		Username:   c.Username,
-		Password:   c.Password,
-		RootPath:   c.RootPath,
-		Enabled:    c.Enabled,
-		LastTest:   c.LastTest,
-		TestStatus: c.TestStatus,
-		TestError:  c.TestError,
-		CreatedAt:  c.CreatedAt,
-		UpdatedAt:  c.UpdatedAt,
+		ID:          c.ID,
+		URL:         c.URL,
+		Username:    c.Username,
+		HasPassword: c.Password != "",
+		RootPath:    c.RootPath,
+		Enabled:     c.Enabled,
+		LastTest:    c.LastTest,
+		TestStatus:  c.TestStatus,
+		TestError:   c.TestError,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// SetPassword 加密后保存密码，空字符串表示清除
+func (c *WebDAVConfig) SetPassword(plain string) error {
+	encrypted, err := secret.Encrypt(plain)
+	if err != nil {
+		return err
 	}
+	c.Password = encrypted
+	return nil
+}
+
+// PlainPassword 返回解密后的密码
+func (c *WebDAVConfig) PlainPassword() (string, error) {
+	return secret.Decrypt(c.Password)
 }
 
 func formatFileSize(size int64) string {
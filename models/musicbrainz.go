@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MusicBrainz 匹配状态
+const (
+	MBMatchPending  = "pending"
+	MBMatchAccepted = "accepted"
+	MBMatchRejected = "rejected"
+	MBMatchNoMatch  = "no_match"
+	MBMatchFailed   = "failed"
+)
+
+// MusicBrainzMatch 批量查询产生的候选结果，等待用户逐字段确认
+type MusicBrainzMatch struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	MusicID    uint      `gorm:"uniqueIndex;not null" json:"music_id"`
+	Status     string    `gorm:"size:20;index;default:pending" json:"status"`
+	Candidates string    `gorm:"type:text" json:"-"` // JSON 编码的 []parser.MBCandidate
+	BestScore  float64   `gorm:"default:0" json:"best_score"`
+	Error      string    `gorm:"size:500" json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (MusicBrainzMatch) TableName() string {
+	return "musicbrainz_matches"
+}
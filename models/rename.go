@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 重命名操作状态
+const (
+	RenameApplied = "applied"
+	RenameUndone  = "undone"
+)
+
+// RenameOperation 一次文件移动记录，同一批次共享 BatchID，用于撤销
+type RenameOperation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	BatchID   string     `gorm:"size:36;index;not null" json:"batch_id"`
+	MusicID   uint       `gorm:"index;not null" json:"music_id"`
+	OldPath   string     `gorm:"size:500;not null" json:"old_path"`
+	NewPath   string     `gorm:"size:500;not null" json:"new_path"`
+	Template  string     `gorm:"size:500" json:"template"`
+	Status    string     `gorm:"size:20;index;default:applied" json:"status"`
+	UndoneAt  *time.Time `json:"undone_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (RenameOperation) TableName() string {
+	return "rename_operations"
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// 定时任务可执行的操作
+const (
+	ScheduleTaskScan        = "scan"         // 增量扫描：只解析新增、大小改变或上次失败的文件
+	ScheduleTaskFetchLyrics = "fetch_lyrics" // 获取缺少的歌词
+	ScheduleTaskFetchCovers = "fetch_covers" // 获取缺少的封面
+	ScheduleTaskFetchAll    = "fetch_all"    // 获取缺少的歌词和封面
+	ScheduleTaskArtworkGC   = "artwork_gc"   // 清理未引用的封面
+)
+
+// 定时任务最近一次执行的结果
+const (
+	ScheduleStatusSuccess = "success"
+	ScheduleStatusFailed  = "failed"
+	ScheduleStatusSkipped = "skipped" // 有其他任务正在运行，本次跳过
+)
+
+// 定时任务的来源
+const (
+	ScheduleSourceConfig = "config" // 启动时从配置文件同步，Cron 和 Task 以配置为准
+	ScheduleSourceAPI    = "api"
+)
+
+// Schedule 按 cron 表达式定时执行的任务
+type Schedule struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Cron         string     `gorm:"size:100;not null" json:"cron"`
+	Task         string     `gorm:"size:30;not null" json:"task"`
+	Enabled      bool       `json:"enabled"`
+	Source       string     `gorm:"size:10" json:"source"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	LastStatus   string     `gorm:"size:20" json:"last_status"`
+	LastMessage  string     `gorm:"size:500" json:"last_message"`
+	LastDuration int64      `json:"last_duration_ms"`
+	NextRunAt    *time.Time `json:"next_run_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (Schedule) TableName() string {
+	return "schedules"
+}
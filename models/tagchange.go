@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// 标签修改来源
+const (
+	TagSourceManual      = "manual"
+	TagSourceMusicBrainz = "musicbrainz"
+	TagSourceRule        = "rule"
+	TagSourceFetch       = "fetch"
+	TagSourceRevert      = "revert"
+	TagSourceAnalysis    = "analysis"
+	TagSourceFingerprint = "fingerprint"
+)
+
+// TagChange 一次字段修改记录，同一次操作产生的记录共享 BatchID
+type TagChange struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	MusicID    uint       `gorm:"index;not null" json:"music_id"`
+	BatchID    string     `gorm:"size:36;index;not null" json:"batch_id"`
+	Field      string     `gorm:"size:50;not null" json:"field"`
+	OldValue   string     `gorm:"type:text" json:"old_value"`
+	NewValue   string     `gorm:"type:text" json:"new_value"`
+	Source     string     `gorm:"size:20;index;not null" json:"source"`
+	User       string     `gorm:"size:100" json:"user"`
+	RevertedAt *time.Time `json:"reverted_at"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (TagChange) TableName() string {
+	return "tag_changes"
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tagField 可审计的标签字段，readOnly 的字段只记录修改历史，不能批量编辑
+type tagField struct {
+	name     string
+	get      func(m *Music) string
+	set      func(m *Music, v string) error
+	readOnly bool
+}
+
+func stringField(name string, ptr func(m *Music) *string) tagField {
+	return tagField{
+		name: name,
+		get:  func(m *Music) string { return *ptr(m) },
+		set:  func(m *Music, v string) error { *ptr(m) = v; return nil },
+	}
+}
+
+func intField(name string, ptr func(m *Music) *int) tagField {
+	return tagField{
+		name: name,
+		get: func(m *Music) string {
+			if *ptr(m) == 0 {
+				return ""
+			}
+			return strconv.Itoa(*ptr(m))
+		},
+		set: func(m *Music, v string) error {
+			if strings.TrimSpace(v) == "" {
+				*ptr(m) = 0
+				return nil
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || n < 0 {
+				return fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			*ptr(m) = n
+			return nil
+		},
+	}
+}
+
+func floatField(name string, ptr func(m *Music) **float64) tagField {
+	return tagField{
+		name: name,
+		get: func(m *Music) string {
+			if *ptr(m) == nil {
+				return ""
+			}
+			return strconv.FormatFloat(**ptr(m), 'f', -1, 64)
+		},
+		set: func(m *Music, v string) error {
+			if strings.TrimSpace(v) == "" {
+				*ptr(m) = nil
+				return nil
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("%s must be a number", name)
+			}
+			*ptr(m) = &f
+			return nil
+		},
+	}
+}
+
+// boolField 由扫描和歌词/封面操作维护的状态字段，只读
+func boolField(name string, ptr func(m *Music) *bool) tagField {
+	return tagField{
+		name:     name,
+		readOnly: true,
+		get:      func(m *Music) string { return strconv.FormatBool(*ptr(m)) },
+		set: func(m *Music, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s must be true or false", name)
+			}
+			*ptr(m) = b
+			return nil
+		},
+	}
+}
+
+// tagFields 字段名与 JSON 字段名一致
+var tagFields = []tagField{
+	stringField("title", func(m *Music) *string { return &m.Title }),
+	stringField("artist", func(m *Music) *string { return &m.Artist }),
+	stringField("featured_artists", func(m *Music) *string { return &m.FeaturedArtists }),
+	stringField("album", func(m *Music) *string { return &m.Album }),
+	stringField("album_artist", func(m *Music) *string { return &m.AlbumArtist }),
+	stringField("composer", func(m *Music) *string { return &m.Composer }),
+	stringField("genre", func(m *Music) *string { return &m.Genre }),
+	intField("year", func(m *Music) *int { return &m.Year }),
+	intField("track_number", func(m *Music) *int { return &m.TrackNumber }),
+	intField("disc_number", func(m *Music) *int { return &m.DiscNumber }),
+	stringField("comment", func(m *Music) *string { return &m.Comment }),
+	stringField("mb_recording_id", func(m *Music) *string { return &m.MBRecordingID }),
+	stringField("mb_track_id", func(m *Music) *string { return &m.MBTrackID }),
+	stringField("mb_release_id", func(m *Music) *string { return &m.MBReleaseID }),
+	stringField("mb_release_group_id", func(m *Music) *string { return &m.MBReleaseGroupID }),
+	stringField("mb_artist_id", func(m *Music) *string { return &m.MBArtistID }),
+	stringField("mb_album_artist_id", func(m *Music) *string { return &m.MBAlbumArtistID }),
+	stringField("isrc", func(m *Music) *string { return &m.ISRC }),
+	stringField("label", func(m *Music) *string { return &m.Label }),
+	stringField("catalog_number", func(m *Music) *string { return &m.CatalogNumber }),
+	intField("bpm", func(m *Music) *int { return &m.BPM }),
+	stringField("initial_key", func(m *Music) *string { return &m.InitialKey }),
+	stringField("release_type", func(m *Music) *string { return &m.ReleaseType }),
+	intField("original_year", func(m *Music) *int { return &m.OriginalYear }),
+	stringField("artist_sort", func(m *Music) *string { return &m.ArtistSort }),
+	stringField("album_sort", func(m *Music) *string { return &m.AlbumSort }),
+	stringField("title_sort", func(m *Music) *string { return &m.TitleSort }),
+	stringField("album_artist_sort", func(m *Music) *string { return &m.AlbumArtistSort }),
+	stringField("composer_sort", func(m *Music) *string { return &m.ComposerSort }),
+	floatField("replaygain_track_gain", func(m *Music) **float64 { return &m.ReplayGainTrackGain }),
+	floatField("replaygain_track_peak", func(m *Music) **float64 { return &m.ReplayGainTrackPeak }),
+	floatField("replaygain_album_gain", func(m *Music) **float64 { return &m.ReplayGainAlbumGain }),
+	floatField("replaygain_album_peak", func(m *Music) **float64 { return &m.ReplayGainAlbumPeak }),
+	boolField("has_lyrics", func(m *Music) *bool { return &m.HasLyrics }),
+	boolField("has_cover", func(m *Music) *bool { return &m.HasCover }),
+}
+
+// TagFieldNames 返回所有可编辑字段名
+func TagFieldNames() []string {
+	var names []string
+	for _, f := range tagFields {
+		if !f.readOnly {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func findTagField(name string) (*tagField, bool) {
+	for i := range tagFields {
+		if tagFields[i].name == name {
+			return &tagFields[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsTagField 判断字段名是否可编辑
+func IsTagField(name string) bool {
+	f, ok := findTagField(name)
+	return ok && !f.readOnly
+}
+
+// TagValue 以字符串形式读取字段，数值为 0 或未设置时返回空字符串
+func (m *Music) TagValue(name string) (string, error) {
+	f, ok := findTagField(name)
+	if !ok {
+		return "", fmt.Errorf("unknown field: %s", name)
+	}
+	return f.get(m), nil
+}
+
+// SetTagValue 以字符串形式设置字段，空字符串表示清空
+func (m *Music) SetTagValue(name, value string) error {
+	f, ok := findTagField(name)
+	if !ok {
+		return fmt.Errorf("unknown field: %s", name)
+	}
+	return f.set(m, value)
+}
+
+// TagFieldChange 一个字段的新旧值
+type TagFieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// DiffTags 比较两个版本的曲目，返回有变化的字段
+func DiffTags(before, after *Music) []TagFieldChange {
+	var changes []TagFieldChange
+	for _, f := range tagFields {
+		if o, n := f.get(before), f.get(after); o != n {
+			changes = append(changes, TagFieldChange{Field: f.name, OldValue: o, NewValue: n})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,258 @@
+// Package normalize 按可配置规则清理曲目标签：去空白、全角转半角、大小写、
+// 提取 feat. 艺术家、流派映射和繁简转换。
+package normalize
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-music-tag/models"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 大小写风格
+const (
+	CaseNone  = ""
+	CaseTitle = "title" // 每个单词首字母大写，其余保持不变
+	CaseUpper = "upper"
+	CaseLower = "lower"
+)
+
+// 繁简转换方向
+const (
+	ChineseNone          = ""
+	ChineseToSimplified  = "t2s"
+	ChineseToTraditional = "s2t"
+)
+
+// TextFields 规则可作用的文本字段
+var TextFields = []string{"title", "artist", "album", "album_artist", "composer", "genre"}
+
+// Rules 标签规范化规则，字段为空时使用默认作用范围
+type Rules struct {
+	Trim            bool              `mapstructure:"trim" json:"trim"`                         // 去掉首尾空白并合并连续空白
+	FullWidth       bool              `mapstructure:"full_width" json:"full_width"`             // 全角字母、数字和标点转半角
+	CaseStyle       string            `mapstructure:"case_style" json:"case_style"`             // title | upper | lower
+	CaseFields      []string          `mapstructure:"case_fields" json:"case_fields"`           // 默认 title, artist, album, album_artist
+	ExtractFeatured bool              `mapstructure:"extract_featured" json:"extract_featured"` // 从标题/艺术家中提取 feat. 艺术家
+	GenreMap        map[string]string `mapstructure:"genre_map" json:"genre_map"`               // 键不区分大小写
+	Chinese         string            `mapstructure:"chinese" json:"chinese"`                   // t2s | s2t
+	ChineseFields   []string          `mapstructure:"chinese_fields" json:"chinese_fields"`     // 默认全部文本字段
+}
+
+// Change 单个字段的修改
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+var (
+	// bracketFeatRe 匹配 "Title (feat. A & B)" 或 "[ft. A]"
+	bracketFeatRe = regexp.MustCompile(`(?i)\s*[\(\[（【]\s*(?:feat\.?|ft\.?|featuring)\s+([^\)\]）】]+)[\)\]）】]`)
+	// trailingFeatRe 匹配 "Artist feat. A, B"
+	trailingFeatRe = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+(.+)$`)
+	// featSplitRe 分隔多个 feat. 艺术家
+	featSplitRe = regexp.MustCompile(`\s*(?:,|&|、|/|;|\s+and\s+|\s+x\s+)\s*`)
+)
+
+// UnmarshalJSON 解析请求中的规则，流派映射的键转为小写以便不区分大小写地匹配。
+// 配置文件中的键已由 viper 转为小写
+func (r *Rules) UnmarshalJSON(data []byte) error {
+	type plain Rules
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	if len(r.GenreMap) > 0 {
+		genreMap := make(map[string]string, len(r.GenreMap))
+		for k, v := range r.GenreMap {
+			genreMap[strings.ToLower(k)] = v
+		}
+		r.GenreMap = genreMap
+	}
+	return nil
+}
+
+// Validate 校验规则取值
+func (r *Rules) Validate() error {
+	switch r.CaseStyle {
+	case CaseNone, CaseTitle, CaseUpper, CaseLower:
+	default:
+		return fmt.Errorf("invalid case_style: %s", r.CaseStyle)
+	}
+	switch r.Chinese {
+	case ChineseNone, ChineseToSimplified, ChineseToTraditional:
+	default:
+		return fmt.Errorf("invalid chinese conversion: %s", r.Chinese)
+	}
+	for _, fields := range [][]string{r.CaseFields, r.ChineseFields} {
+		for _, f := range fields {
+			if !contains(TextFields, f) {
+				return fmt.Errorf("unknown field: %s", f)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply 对曲目应用规则并返回修改列表，music 会被原地修改
+func (r *Rules) Apply(music *models.Music) []Change {
+	before := snapshot(music)
+
+	caseFields := r.CaseFields
+	if len(caseFields) == 0 {
+		caseFields = []string{"title", "artist", "album", "album_artist"}
+	}
+	chineseFields := r.ChineseFields
+	if len(chineseFields) == 0 {
+		chineseFields = TextFields
+	}
+
+	for _, field := range TextFields {
+		ptr := fieldPtr(music, field)
+		v := *ptr
+		if r.FullWidth {
+			v = toHalfWidth(v)
+		}
+		if r.Trim {
+			v = strings.Join(strings.Fields(v), " ")
+		}
+		if contains(chineseFields, field) {
+			switch r.Chinese {
+			case ChineseToSimplified:
+				v = ToSimplified(v)
+			case ChineseToTraditional:
+				v = ToTraditional(v)
+			}
+		}
+		if contains(caseFields, field) {
+			v = applyCase(v, r.CaseStyle)
+		}
+		*ptr = v
+	}
+
+	if r.ExtractFeatured {
+		var featured []string
+		music.Title, featured = extractFeatured(music.Title, featured, false)
+		music.Artist, featured = extractFeatured(music.Artist, featured, true)
+		music.FeaturedArtists = mergeArtists(music.FeaturedArtists, featured)
+	}
+
+	if len(r.GenreMap) > 0 && music.Genre != "" {
+		if mapped, ok := r.GenreMap[strings.ToLower(music.Genre)]; ok {
+			music.Genre = mapped
+		}
+	}
+
+	var changes []Change
+	after := snapshot(music)
+	for _, field := range TextFields {
+		if before[field] != after[field] {
+			changes = append(changes, Change{Field: field, Old: before[field], New: after[field]})
+		}
+	}
+	if before["featured_artists"] != after["featured_artists"] {
+		changes = append(changes, Change{Field: "featured_artists", Old: before["featured_artists"], New: after["featured_artists"]})
+	}
+	return changes
+}
+
+func snapshot(m *models.Music) map[string]string {
+	s := make(map[string]string, len(TextFields)+1)
+	for _, f := range TextFields {
+		s[f] = *fieldPtr(m, f)
+	}
+	s["featured_artists"] = m.FeaturedArtists
+	return s
+}
+
+func fieldPtr(m *models.Music, field string) *string {
+	switch field {
+	case "title":
+		return &m.Title
+	case "artist":
+		return &m.Artist
+	case "album":
+		return &m.Album
+	case "album_artist":
+		return &m.AlbumArtist
+	case "composer":
+		return &m.Composer
+	default:
+		return &m.Genre
+	}
+}
+
+// toHalfWidth 全角 ASCII (U+FF01-U+FF5E) 和全角空格转为半角，中日韩文字不受影响
+func toHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+func applyCase(s, style string) string {
+	switch style {
+	case CaseUpper:
+		return strings.ToUpper(s)
+	case CaseLower:
+		return strings.ToLower(s)
+	case CaseTitle:
+		runes := []rune(s)
+		start := true
+		for i, r := range runes {
+			if start && unicode.IsLetter(r) {
+				runes[i] = unicode.ToUpper(r)
+			}
+			start = unicode.IsSpace(r) || strings.ContainsRune("([-/\"", r)
+		}
+		return string(runes)
+	}
+	return s
+}
+
+// extractFeatured 从文本中移除 feat. 部分，trailing 为 true 时同时处理不带括号的形式
+func extractFeatured(s string, featured []string, trailing bool) (string, []string) {
+	if m := bracketFeatRe.FindStringSubmatch(s); m != nil {
+		featured = append(featured, featSplitRe.Split(strings.TrimSpace(m[1]), -1)...)
+		s = strings.TrimSpace(bracketFeatRe.ReplaceAllString(s, ""))
+	}
+	if trailing {
+		if m := trailingFeatRe.FindStringSubmatch(s); m != nil {
+			featured = append(featured, featSplitRe.Split(strings.TrimSpace(m[1]), -1)...)
+			s = strings.TrimSpace(trailingFeatRe.ReplaceAllString(s, ""))
+		}
+	}
+	return s, featured
+}
+
+// mergeArtists 合并并去重艺术家列表
+func mergeArtists(existing string, added []string) string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range append(strings.Split(existing, ";"), added...) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return strings.Join(result, "; ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+)
+
+// AudioHash 计算去除 ID3v2/APEv2/Lyrics3/ID3v1 标签后音频数据的 SHA-256，
+// 标签不同但音频内容相同的文件得到相同的哈希
+func AudioHash(data []byte) string {
+	layout := LocateTags(data)
+	if layout.AudioStart >= layout.AudioEnd {
+		return ""
+	}
+	sum := sha256.Sum256(data[layout.AudioStart:layout.AudioEnd])
+	return hex.EncodeToString(sum[:])
+}
+
+// 标签类型
+const (
+	TagID3v2   = "id3v2"
+	TagID3v1   = "id3v1"
+	TagAPE     = "ape"
+	TagLyrics3 = "lyrics3"
+)
+
+// TagSpan 一个标签在文件中占据的区间 [Start, End)
+type TagSpan struct {
+	Kind  string
+	Start int
+	End   int
+}
+
+// TagLayout 文件中标签与音频数据的位置，[AudioStart, AudioEnd) 为音频数据
+type TagLayout struct {
+	AudioStart int
+	AudioEnd   int
+	Leading    []TagSpan // 开头的 ID3v2 标签 (可能有多个)
+	Trailing   []TagSpan // 音频之后的标签，按在文件中的顺序
+}
+
+// LocateTags 定位开头的 ID3v2 标签，以及末尾的 ID3v1、Lyrics3v2、APEv2 和追加的 ID3v2 标签
+func LocateTags(data []byte) TagLayout {
+	var l TagLayout
+	start, end := 0, len(data)
+
+	for start+10 <= end && bytes.Equal(data[start:start+3], []byte("ID3")) {
+		size := id3v2TagSize(data[start : start+10])
+		if size <= 0 || start+size > end {
+			break
+		}
+		l.Leading = append(l.Leading, TagSpan{Kind: TagID3v2, Start: start, End: start + size})
+		start += size
+	}
+
+	// 末尾的标签从后往前识别，顺序不固定
+	var trailing []TagSpan
+	for {
+		size, kind := trailingTagSize(data[start:end])
+		if size == 0 {
+			break
+		}
+		trailing = append(trailing, TagSpan{Kind: kind, Start: end - size, End: end})
+		end -= size
+	}
+	for i := len(trailing) - 1; i >= 0; i-- {
+		l.Trailing = append(l.Trailing, trailing[i])
+	}
+
+	l.AudioStart, l.AudioEnd = start, end
+	return l
+}
+
+// trailingTagSize 识别 data 末尾的一个标签，返回其长度和类型，没有标签时返回 0
+func trailingTagSize(data []byte) (int, string) {
+	end := len(data)
+	switch {
+	case end >= 128 && bytes.Equal(data[end-128:end-125], []byte("TAG")):
+		return 128, TagID3v1
+	case end >= 15 && bytes.Equal(data[end-9:end], []byte("LYRICS200")):
+		n, err := strconv.Atoi(string(data[end-15 : end-9]))
+		if err == nil && n >= 0 && n+15 <= end {
+			return n + 15, TagLyrics3
+		}
+	case end >= 32 && bytes.Equal(data[end-32:end-24], []byte("APETAGEX")):
+		size := int(binary.LittleEndian.Uint32(data[end-20 : end-16]))
+		if binary.LittleEndian.Uint32(data[end-12:end-8])&(1<<31) != 0 {
+			size += 32 // 带头部
+		}
+		if size > 0 && size <= end {
+			return size, TagAPE
+		}
+	case end >= 10 && bytes.Equal(data[end-10:end-7], []byte("3DI")):
+		// 追加在末尾的 ID3v2 标签以 footer 结束，footer 除标识外与头部相同
+		header := append([]byte("ID3"), data[end-7:end]...)
+		if size := id3v2TagSize(header); size > 0 && size <= end {
+			return size, TagID3v2
+		}
+	}
+	return 0, ""
+}
+
+// id3v2TagSize 根据 10 字节头部计算整个 ID3v2 标签的大小 (含头部和可选尾部)
+func id3v2TagSize(header []byte) int {
+	if len(header) < 10 {
+		return 0
+	}
+	for _, b := range header[6:10] {
+		if b&0x80 != 0 {
+			return 0 // 非法的 syncsafe 整数
+		}
+	}
+	size := int(header[6])<<21 | int(header[7])<<14 | int(header[8])<<7 | int(header[9])
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	return size
+}
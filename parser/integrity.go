@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"go-music-tag/models"
+	"time"
+)
+
+// ValidateMP3 逐帧检查 MP3 数据：同步丢失、末帧截断、CRC 错误、采样率混用、帧间垃圾数据和重复的 ID3 标签
+func ValidateMP3(data []byte) *models.IntegrityReport {
+	return integrityFromScan(scanFrames(data))
+}
+
+func integrityFromScan(s frameScan) *models.IntegrityReport {
+	r := &models.IntegrityReport{
+		Issues:         []string{},
+		Frames:         s.frames,
+		SyncErrors:     s.syncErrors,
+		GarbageBytes:   s.garbageBytes,
+		GarbageRegions: s.garbageRegions,
+		TruncatedBytes: s.truncatedBytes,
+		CRCChecked:     s.crcChecked,
+		CRCErrors:      s.crcErrors,
+		SampleRates:    s.sampleRates,
+		ID3v2Tags:      s.id3v2Tags,
+		ID3v1Tags:      s.id3v1Tags,
+		CheckedAt:      time.Now(),
+	}
+	if r.SampleRates == nil {
+		r.SampleRates = []int{}
+	}
+
+	corrupt := false
+	add := func(issue string, isCorrupt bool) {
+		r.Issues = append(r.Issues, issue)
+		corrupt = corrupt || isCorrupt
+	}
+	if s.frames == 0 {
+		add(models.IntegrityIssueNoFrames, true)
+	}
+	if s.syncErrors > 0 {
+		add(models.IntegrityIssueSyncLoss, true)
+	}
+	if s.truncatedBytes > 0 {
+		add(models.IntegrityIssueTruncated, true)
+	}
+	if s.crcErrors > 0 {
+		add(models.IntegrityIssueCRC, true)
+	}
+	if len(s.sampleRates) > 1 {
+		add(models.IntegrityIssueMixedRates, true)
+	}
+	if s.garbageBytes > 0 {
+		add(models.IntegrityIssueGarbage, false)
+	}
+	if s.id3v2Tags > 1 {
+		add(models.IntegrityIssueDuplicateID3v2, false)
+	}
+	if s.id3v1Tags > 1 {
+		add(models.IntegrityIssueDuplicateID3v1, false)
+	}
+
+	switch {
+	case corrupt:
+		r.Status = models.IntegrityCorrupt
+	case len(r.Issues) > 0:
+		r.Status = models.IntegrityWarning
+	default:
+		r.Status = models.IntegrityOK
+	}
+	return r
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bytes"
+	"go-music-tag/charset"
 	"go-music-tag/models"
 	"path/filepath"
 	"regexp"
@@ -9,15 +10,36 @@ import (
 	"time"
 
 	"github.com/dhowden/tag"
-	"github.com/tcolgate/mp3" // ✅ 新增：用于精确计算时长
 )
 
-type MP3Parser struct{}
+type MP3Parser struct {
+	pathPatterns []*PathPattern    // 从完整路径提取标签的模式，按顺序尝试
+	charset      *charset.Detector // 修复以 ISO-8859-1 保存的 GBK/Big5/Shift-JIS 文本，nil 时保持原样
+}
 
 func NewMP3Parser() *MP3Parser {
 	return &MP3Parser{}
 }
 
+// NewMP3ParserWithPatterns 创建带路径模式的解析器，标签缺失时从文件路径补全
+func NewMP3ParserWithPatterns(patterns []*PathPattern) *MP3Parser {
+	return &MP3Parser{pathPatterns: patterns}
+}
+
+// WithCharset 设置标签文本的编码检测器
+func (p *MP3Parser) WithCharset(d *charset.Detector) *MP3Parser {
+	p.charset = d
+	return p
+}
+
+// repairText 修复标签中被当作 ISO-8859-1 读取的本地编码文本
+func (p *MP3Parser) repairText(s string) string {
+	if p.charset == nil {
+		return s
+	}
+	return p.charset.RepairString(s)
+}
+
 func (p *MP3Parser) Parse(data []byte, filePath string, fileName string, fileSize int64) (*models.Music, error) {
 	reader := bytes.NewReader(data)
 
@@ -67,6 +89,18 @@ func (p *MP3Parser) Parse(data []byte, filePath string, fileName string, fileSiz
 			music.Comment = comment
 		}
 
+		// MusicBrainz 标识、ISRC、厂牌、ReplayGain 等扩展标签
+		extended := readExtendedTags(md)
+		for k, v := range extended {
+			extended[k] = p.repairText(v)
+		}
+		applyExtendedTags(music, extended)
+
+		// ID3v1 与 ID3v2.3 的 ISO-8859-1 帧常被写入 GBK 等本地编码
+		for _, field := range []*string{&music.Title, &music.Artist, &music.Album, &music.AlbumArtist, &music.Composer, &music.Genre, &music.Comment} {
+			*field = p.repairText(*field)
+		}
+
 		// 提取封面
 		if artwork := md.Picture(); artwork != nil {
 			music.HasCover = true
@@ -77,41 +111,33 @@ func (p *MP3Parser) Parse(data []byte, filePath string, fileName string, fileSiz
 		// dhowden/tag 不直接支持 Duration，所以这里通常是 0
 	}
 
-	// 3. ✅ 关键修复：使用 tcolgate/mp3 精确计算时长和比特率
-	// 重新创建一个 reader，因为上面的 ReadFrom 可能已经读到了文件末尾
-	mp3Reader := bytes.NewReader(data)
-	frameReader, err := mp3.NewFrameReader(mp3Reader)
-	if err != nil {
-		// 如果无法解析帧，降级使用估算
+	// 3. 逐帧检查完整性；时长和比特率优先取自 Xing/Info/VBRI 头，没有时使用逐帧统计
+	scan := scanFrames(data)
+	music.Integrity = integrityFromScan(scan)
+	music.IntegrityStatus = music.Integrity.Status
+
+	if info := readStreamInfo(data, scan); info.exact && info.duration > 0 {
+		music.Duration = int(info.duration + 0.5)
+		music.BitRate = info.bitRate
+		music.SampleRate = info.sampleRate
+		music.FrameCount = info.frames
+		music.BitRateMode = info.mode
+		music.Encoder = info.encoder
+		music.EncoderPreset = info.preset
+		music.EncoderDelay = info.delay
+		music.EncoderPadding = info.padding
+	} else {
+		// 无法解析帧，降级使用估算
 		music.BitRate = p.estimateBitRate(fileSize)
 		music.Duration = p.estimateDuration(fileSize, music.BitRate)
-	} else {
-		frames, err := frameReader.ReadFrames()
-		if err == nil && len(frames) > 0 {
-			var totalDuration float64
-			var totalBits int64
-
-			for _, frame := range frames {
-				totalDuration += frame.Duration().Seconds()
-				totalBits += int64(frame.Size()) * 8
-			}
-
-			music.Duration = int(totalDuration)
-			if totalDuration > 0 {
-				// 计算平均比特率 (kbps)
-				music.BitRate = int((totalBits / totalDuration) / 1000)
-			}
-
-			// 获取采样率 (取第一帧的即可，通常整首歌不变)
-			music.SampleRate = int(frames[0].SampleRate())
-		} else {
-			// 降级处理
-			music.BitRate = p.estimateBitRate(fileSize)
-			music.Duration = p.estimateDuration(fileSize, music.BitRate)
-		}
+		music.DurationEstimated = true
 	}
 
-	// 4. 如果标题仍为空，尝试从文件名解析
+	// 音频内容哈希，用于查找标签不同的重复文件
+	music.AudioHash = AudioHash(data)
+
+	// 4. 用路径模式补全缺失的标签，标题仍为空时再尝试从文件名解析
+	p.fillFromPath(filePath, music)
 	if music.Title == "" {
 		p.parseFromFileName(fileName, music)
 	}
@@ -138,18 +164,25 @@ func (p *MP3Parser) parseFromFileNameOnly(filePath string, fileName string, file
 		Format:     "MP3",
 	}
 
-	// 同样尝试计算时长
-	mp3Reader := bytes.NewReader(nil) // 这里没有数据，只能估算
-	// 由于没有 data，只能估算
+	// 没有文件数据，只能估算时长
 	music.BitRate = p.estimateBitRate(fileSize)
 	music.Duration = p.estimateDuration(fileSize, music.BitRate)
 	music.SampleRate = 44100
+	music.DurationEstimated = true
 
+	p.fillFromPath(filePath, music)
 	p.parseFromFileName(fileName, music)
 	return music, nil
 }
 
-func (p *MP3Parser) parseFromFileName(fileName string, music *Music) {
+// fillFromPath 使用第一个匹配的路径模式填充空字段
+func (p *MP3Parser) fillFromPath(filePath string, music *models.Music) {
+	if _, values := MatchPathPatterns(p.pathPatterns, filePath); values != nil {
+		ApplyPathValues(music, values, false)
+	}
+}
+
+func (p *MP3Parser) parseFromFileName(fileName string, music *models.Music) {
 	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
 	name = regexp.MustCompile(`^\d+[\.\-\s]+`).ReplaceAllString(name, "")
 
@@ -0,0 +1,285 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// MPEG 音频帧头，参考 ISO/IEC 11172-3 与 13818-3
+type frameHeader struct {
+	version     int // 1, 2 或 25 (MPEG 2.5)
+	layer       int // 1, 2, 3
+	protected   bool
+	bitRate     int // kbps
+	sampleRate  int
+	padding     bool
+	channelMode int // 3 表示单声道
+	size        int // 含帧头的整帧字节数
+	samples     int // 每帧采样数
+}
+
+var bitRateTable = [2][3][15]int{
+	{ // MPEG 1
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+	{ // MPEG 2 / 2.5
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+}
+
+var sampleRateTable = map[int][3]int{
+	1:  {44100, 48000, 32000},
+	2:  {22050, 24000, 16000},
+	25: {11025, 12000, 8000},
+}
+
+// parseFrameHeader 解析 4 字节帧头，不支持 free format (比特率索引 0)
+func parseFrameHeader(b []byte) (frameHeader, bool) {
+	var h frameHeader
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return h, false
+	}
+
+	switch (b[1] >> 3) & 0x03 {
+	case 0:
+		h.version = 25
+	case 2:
+		h.version = 2
+	case 3:
+		h.version = 1
+	default:
+		return h, false
+	}
+	switch (b[1] >> 1) & 0x03 {
+	case 1:
+		h.layer = 3
+	case 2:
+		h.layer = 2
+	case 3:
+		h.layer = 1
+	default:
+		return h, false
+	}
+	h.protected = b[1]&0x01 == 0
+
+	bitRateIndex := int(b[2] >> 4)
+	sampleRateIndex := int((b[2] >> 2) & 0x03)
+	if bitRateIndex == 0 || bitRateIndex == 15 || sampleRateIndex == 3 {
+		return h, false
+	}
+	table := 0
+	if h.version != 1 {
+		table = 1
+	}
+	h.bitRate = bitRateTable[table][h.layer-1][bitRateIndex]
+	h.sampleRate = sampleRateTable[h.version][sampleRateIndex]
+	h.padding = b[2]&0x02 != 0
+	h.channelMode = int(b[3] >> 6)
+	// emphasis 2 为保留值
+	if b[3]&0x03 == 2 {
+		return h, false
+	}
+
+	pad := 0
+	if h.padding {
+		pad = 1
+	}
+	switch {
+	case h.layer == 1:
+		h.samples = 384
+		h.size = (12*h.bitRate*1000/h.sampleRate + pad) * 4
+	case h.layer == 2 || h.version == 1:
+		h.samples = 1152
+		h.size = 144*h.bitRate*1000/h.sampleRate + pad
+	default:
+		h.samples = 576
+		h.size = 72*h.bitRate*1000/h.sampleRate + pad
+	}
+	return h, h.size > 4
+}
+
+// sideInfoSize Layer III 帧头 (及 CRC) 之后的 side information 长度
+func (h frameHeader) sideInfoSize() int {
+	mono := h.channelMode == 3
+	switch {
+	case h.version == 1 && mono:
+		return 17
+	case h.version == 1:
+		return 32
+	case mono:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// crc16 MPEG 音频使用的 CRC-16 (多项式 0x8005，初值 0xFFFF)
+func crc16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// checkFrameCRC 校验 Layer III 帧的 CRC，ok 为 false 表示无法校验
+func checkFrameCRC(frame []byte, h frameHeader) (valid, ok bool) {
+	if !h.protected || h.layer != 3 {
+		return false, false
+	}
+	end := 6 + h.sideInfoSize()
+	if len(frame) < end {
+		return false, false
+	}
+	crc := crc16(0xFFFF, frame[2:4])
+	crc = crc16(crc, frame[6:end])
+	return crc == binary.BigEndian.Uint16(frame[4:6]), true
+}
+
+// frameScan 逐帧遍历的结果
+type frameScan struct {
+	first          frameHeader
+	firstPos       int
+	frames         int
+	samples        int64 // 所有完整帧的采样数之和
+	audioBytes     int64
+	sampleRates    []int
+	bitRateVaries  bool
+	syncErrors     int   // 帧长度与下一帧位置不符 (帧被截断) 的次数
+	garbageBytes   int64 // 帧之间及首帧之前无法识别的字节
+	garbageRegions int
+	truncatedBytes int // 最后一帧缺失的字节
+	crcChecked     int
+	crcErrors      int
+	id3v2Tags      int
+	id3v1Tags      int
+}
+
+// confirmFrame 判断 pos 处是否为可信的帧：其后连续两帧也有效，或正好到达音频结尾
+func confirmFrame(data []byte, pos, end int) (frameHeader, bool) {
+	h, ok := parseFrameHeader(data[pos:end])
+	if !ok {
+		return h, false
+	}
+	next := pos + h.size
+	for i := 0; i < 2; i++ {
+		if next >= end {
+			return h, true
+		}
+		nh, ok := parseFrameHeader(data[next:end])
+		if !ok || nh.layer != h.layer || nh.version != h.version {
+			return h, false
+		}
+		next += nh.size
+	}
+	return h, true
+}
+
+// resync 从 from 开始查找下一个可信帧，返回其位置，找不到时返回 -1
+func resync(data []byte, from, end int) int {
+	for i := from; i+4 <= end; i++ {
+		if data[i] != 0xFF {
+			continue
+		}
+		if _, ok := confirmFrame(data, i, end); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// scanFrames 遍历整个 MP3 数据，统计帧信息并记录流中的损坏
+func scanFrames(data []byte) frameScan {
+	var s frameScan
+	// 与 AudioHash 和标签改写使用同一套标签定位
+	layout := LocateTags(data)
+	s.id3v2Tags = len(layout.Leading)
+	for _, t := range layout.Trailing {
+		switch t.Kind {
+		case TagID3v2:
+			s.id3v2Tags++
+		case TagID3v1:
+			s.id3v1Tags++
+		}
+	}
+	pos, end := layout.AudioStart, layout.AudioEnd
+
+	rates := make(map[int]bool)
+	lastPos := -1
+	var last frameHeader
+	for pos+4 <= end {
+		h, ok := parseFrameHeader(data[pos:end])
+		if ok && s.frames == 0 {
+			h, ok = confirmFrame(data, pos, end)
+		}
+		if !ok {
+			// 流中再次出现的 ID3v2 标签 (常见于拼接的文件)
+			if n := id3v2TagSize(data[pos:end]); n > 0 && bytes.HasPrefix(data[pos:end], []byte("ID3")) {
+				s.id3v2Tags++
+				pos += n
+				continue
+			}
+
+			from := pos + 1
+			if lastPos >= 0 {
+				from = lastPos + 4
+			}
+			next := resync(data, from, end)
+			if next < 0 {
+				s.garbageBytes += int64(end - pos)
+				s.garbageRegions++
+				break
+			}
+			if next < pos {
+				// 下一帧出现在上一帧结束之前：上一帧数据缺失
+				s.syncErrors++
+				s.samples -= int64(last.samples)
+				s.audioBytes -= int64(pos - lastPos)
+				s.audioBytes += int64(next - lastPos)
+			} else {
+				s.garbageBytes += int64(next - pos)
+				s.garbageRegions++
+			}
+			pos = next
+			continue
+		}
+
+		if s.frames == 0 {
+			s.first, s.firstPos = h, pos
+		} else if h.bitRate != s.first.bitRate {
+			s.bitRateVaries = true
+		}
+		if !rates[h.sampleRate] {
+			rates[h.sampleRate] = true
+			s.sampleRates = append(s.sampleRates, h.sampleRate)
+		}
+		if pos+h.size > end {
+			s.truncatedBytes = pos + h.size - end
+			s.frames++
+			break
+		}
+		if valid, checked := checkFrameCRC(data[pos:pos+h.size], h); checked {
+			s.crcChecked++
+			if !valid {
+				s.crcErrors++
+			}
+		}
+
+		s.frames++
+		s.samples += int64(h.samples)
+		s.audioBytes += int64(h.size)
+		last, lastPos = h, pos
+		pos += h.size
+	}
+	return s
+}
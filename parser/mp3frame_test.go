@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// id3v2Tag 构造指定正文长度的 ID3v2 标签，footer 为 true 时带尾部
+func id3v2Tag(bodySize int, footer bool) []byte {
+	header := []byte{'I', 'D', '3', 4, 0, 0,
+		byte(bodySize >> 21 & 0x7F), byte(bodySize >> 14 & 0x7F), byte(bodySize >> 7 & 0x7F), byte(bodySize & 0x7F)}
+	tag := append(header, make([]byte, bodySize)...)
+	if footer {
+		tag[5] = 0x10
+		tag = append(tag, '3', 'D', 'I')
+		tag = append(tag, header[3:]...)
+		tag[len(tag)-5] = 0x10
+	}
+	return tag
+}
+
+func apeTag() []byte {
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:12], 2000)
+	binary.LittleEndian.PutUint32(footer[12:16], 32)
+	return footer
+}
+
+func TestScanFramesTags(t *testing.T) {
+	var frames []byte
+	for i := 0; i < 10; i++ {
+		frames = append(frames, mp3Frame(testFrameHeader, nil)...)
+	}
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	join := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		frames       int
+		id3v2, id3v1 int
+	}{
+		{"no tags", frames, 10, 0, 0},
+		{"leading tags", join(id3v2Tag(100, false), id3v2Tag(20, false), frames), 10, 2, 0},
+		{"trailing tags", join(frames, apeTag(), id3v1), 10, 0, 1},
+		{"appended id3v2", join(id3v2Tag(50, false), frames, id3v2Tag(30, true), id3v1), 10, 2, 1},
+		{"tag between frames", join(frames[:417*5], id3v2Tag(40, false), frames[417*5:]), 10, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scanFrames(tt.data)
+			if s.frames != tt.frames || s.id3v2Tags != tt.id3v2 || s.id3v1Tags != tt.id3v1 {
+				t.Errorf("frames %d, id3v2 %d, id3v1 %d; want %d, %d, %d", s.frames, s.id3v2Tags, s.id3v1Tags, tt.frames, tt.id3v2, tt.id3v1)
+			}
+			if s.garbageBytes != 0 || s.syncErrors != 0 || s.truncatedBytes != 0 {
+				t.Errorf("unexpected damage: %+v", s)
+			}
+
+			// 与哈希使用的音频范围一致
+			layout := LocateTags(tt.data)
+			if got := s.firstPos; got != layout.AudioStart {
+				t.Errorf("first frame at %d, audio starts at %d", got, layout.AudioStart)
+			}
+		})
+	}
+}
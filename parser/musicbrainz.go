@@ -2,15 +2,36 @@ package parser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-music-tag/models"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	mbBaseURL   = "https://musicbrainz.org/ws/2"
+	mbUserAgent = "MusicTagManager/1.0 ( https://github.com/your-repo )"
+
+	// MusicBrainz 要求每个客户端每秒最多 1 个请求
+	mbMinInterval = time.Second
+)
+
+// ErrNoResults 搜索没有任何结果
+var ErrNoResults = errors.New("no results found")
+
+// 所有 MusicBrainzClient 实例共享同一个限速器
+var (
+	mbRateMutex   sync.Mutex
+	mbLastRequest time.Time
+)
+
 // MusicBrainzClient MusicBrainz API 客户端
 type MusicBrainzClient struct {
 	client *http.Client
@@ -18,20 +39,94 @@ type MusicBrainzClient struct {
 
 // MBSearchResult MusicBrainz 搜索结果
 type MBSearchResult struct {
-	Recordings []struct {
-		ID           string `json:"id"`
-		Title        string `json:"title"`
-		ArtistCredit []struct {
-			Name string `json:"name"`
-		} `json:"artist-credit"`
-		Releases []struct {
-			ID    string `json:"id"`
-			Title string `json:"title"`
-			Date  string `json:"date"`
-		} `json:"releases"`
-	} `json:"recordings"`
+	Recordings []mbRecording `json:"recordings"`
+}
+
+type mbArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+type mbTrack struct {
+	ID     string `json:"id"`
+	Number string `json:"number"`
+	Title  string `json:"title"`
+	Length int    `json:"length"`
+}
+
+type mbMedium struct {
+	Position   int    `json:"position"`
+	Format     string `json:"format"`
+	TrackCount int    `json:"track-count"`
+	// 搜索接口返回 track，查询接口返回 tracks
+	Track  []mbTrack `json:"track"`
+	Tracks []mbTrack `json:"tracks"`
+}
+
+type mbRelease struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Date         string `json:"date"`
+	Status       string `json:"status"`
+	Country      string `json:"country"`
+	ReleaseGroup struct {
+		ID          string `json:"id"`
+		PrimaryType string `json:"primary-type"`
+	} `json:"release-group"`
+	Media []mbMedium `json:"media"`
 }
 
+type mbRecording struct {
+	ID           string           `json:"id"`
+	Score        int              `json:"score"`
+	Title        string           `json:"title"`
+	Length       int              `json:"length"`
+	ArtistCredit []mbArtistCredit `json:"artist-credit"`
+	Releases     []mbRelease      `json:"releases"`
+}
+
+// MBCandidate 一个可供用户确认的匹配候选 (录音 + 发行版本)
+type MBCandidate struct {
+	RecordingID    string   `json:"recording_id"`
+	ReleaseID      string   `json:"release_id"`
+	ReleaseGroupID string   `json:"release_group_id"`
+	TrackID        string   `json:"track_id"`
+	ReleaseType    string   `json:"release_type"`
+	Title          string   `json:"title"`
+	Artist         string   `json:"artist"`
+	Artists        []string `json:"artists"`
+	ArtistIDs      []string `json:"artist_ids"`
+	Album          string   `json:"album"`
+	Date           string   `json:"date"`
+	Year           int      `json:"year"`
+	Country        string   `json:"country"`
+	TrackNumber    int      `json:"track_number"`
+	TrackCount     int      `json:"track_count"`
+	DiscNumber     int      `json:"disc_number"`
+	Length         int      `json:"length"`         // 秒
+	DurationDelta  int      `json:"duration_delta"` // 与本地时长相差的秒数，-1 表示未知
+	MBScore        int      `json:"mb_score"`
+	Score          float64  `json:"score"`
+}
+
+// MBTrackQuery 搜索候选时使用的本地标签
+type MBTrackQuery struct {
+	Artist   string
+	Title    string
+	Album    string
+	Duration int
+}
+
+// 每个录音最多展开的发行版本数，以及返回的候选总数
+const (
+	mbReleasesPerRecording = 3
+	mbMaxCandidates        = 10
+)
+
 // NewMusicBrainzClient 创建客户端
 func NewMusicBrainzClient() *MusicBrainzClient {
 	return &MusicBrainzClient{
@@ -41,69 +136,81 @@ func NewMusicBrainzClient() *MusicBrainzClient {
 	}
 }
 
-// SearchTrack 搜索曲目信息
+// SearchTrack 搜索曲目信息，返回排名最高的候选
 func (mb *MusicBrainzClient) SearchTrack(artist, title string) (*models.Music, error) {
-	if artist == "" && title == "" {
-		return nil, fmt.Errorf("no search query")
-	}
-
-	query := url.QueryEscape(fmt.Sprintf(`artist:"%s" AND recording:"%s"`, artist, title))
-	apiURL := fmt.Sprintf("https://musicbrainz.org/ws/2/recording/?query=%s&fmt=json&limit=5", query)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
+	candidates, err := mb.SearchCandidates(MBTrackQuery{Artist: artist, Title: title})
 	if err != nil {
 		return nil, err
 	}
 
-	// MusicBrainz 要求 User-Agent
-	req.Header.Set("User-Agent", "MusicTagManager/1.0 ( https://github.com/your-repo )")
+	best := candidates[0]
+	return &models.Music{
+		Title:       best.Title,
+		Artist:      best.Artist,
+		Album:       best.Album,
+		Year:        best.Year,
+		TrackNumber: best.TrackNumber,
+		DiscNumber:  best.DiscNumber,
+	}, nil
+}
 
-	resp, err := mb.client.Do(req)
-	if err != nil {
-		return nil, err
+// SearchCandidates 搜索录音并按匹配度排序返回候选
+func (mb *MusicBrainzClient) SearchCandidates(q MBTrackQuery) ([]MBCandidate, error) {
+	if q.Artist == "" && q.Title == "" {
+		return nil, fmt.Errorf("no search query")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed: %s", resp.Status)
+	var terms []string
+	if q.Title != "" {
+		terms = append(terms, fmt.Sprintf(`recording:"%s"`, luceneEscape(q.Title)))
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if q.Artist != "" {
+		terms = append(terms, fmt.Sprintf(`artist:"%s"`, luceneEscape(q.Artist)))
 	}
 
+	apiURL := fmt.Sprintf("%s/recording/?query=%s&fmt=json&limit=10", mbBaseURL, url.QueryEscape(strings.Join(terms, " AND ")))
+
 	var result MBSearchResult
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := mb.getJSON(apiURL, &result); err != nil {
 		return nil, err
 	}
 
-	if len(result.Recordings) == 0 {
-		return nil, fmt.Errorf("no results found")
+	var candidates []MBCandidate
+	for _, rec := range result.Recordings {
+		candidates = append(candidates, buildCandidates(rec, q)...)
 	}
 
-	rec := result.Recordings[0]
-	music := &models.Music{}
+	if len(candidates) == 0 {
+		return nil, ErrNoResults
+	}
 
-	// 使用 MusicBrainz 数据补充标签
-	if rec.Title != "" {
-		music.Title = rec.Title
+	rankCandidates(candidates)
+	if len(candidates) > mbMaxCandidates {
+		candidates = candidates[:mbMaxCandidates]
 	}
+	return candidates, nil
+}
 
-	if len(rec.ArtistCredit) > 0 {
-		music.Artist = rec.ArtistCredit[0].Name
+// LookupCandidates 通过录音 MBID 查询，每个发行版本生成一个候选
+func (mb *MusicBrainzClient) LookupCandidates(recordingID string, q MBTrackQuery) ([]MBCandidate, error) {
+	if recordingID == "" {
+		return nil, fmt.Errorf("no MBID provided")
 	}
 
-	if len(rec.Releases) > 0 {
-		music.Album = rec.Releases[0].Title
-		if rec.Releases[0].Date != "" && len(rec.Releases[0].Date) >= 4 {
-			if year, err := strconv.Atoi(rec.Releases[0].Date[:4]); err == nil {
-				music.Year = year
-			}
-		}
+	apiURL := fmt.Sprintf("%s/recording/%s?fmt=json&inc=releases+artist-credits+media+release-groups", mbBaseURL, url.PathEscape(recordingID))
+
+	var rec mbRecording
+	if err := mb.getJSON(apiURL, &rec); err != nil {
+		return nil, err
 	}
+	rec.Score = 100
 
-	return music, nil
+	candidates := buildCandidatesLimit(rec, q, len(rec.Releases))
+	if len(candidates) == 0 {
+		return nil, ErrNoResults
+	}
+	rankCandidates(candidates)
+	return candidates, nil
 }
 
 // SearchArtist 搜索艺术家信息
@@ -113,25 +220,7 @@ func (mb *MusicBrainzClient) SearchArtist(artistName string) (string, error) {
 	}
 
 	query := url.QueryEscape(artistName)
-	apiURL := fmt.Sprintf("https://musicbrainz.org/ws/2/artist/?query=%s&fmt=json&limit=1", query)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("User-Agent", "MusicTagManager/1.0")
-
-	resp, err := mb.client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	apiURL := fmt.Sprintf("%s/artist/?query=%s&fmt=json&limit=1", mbBaseURL, query)
 
 	var result struct {
 		Artists []struct {
@@ -140,7 +229,7 @@ func (mb *MusicBrainzClient) SearchArtist(artistName string) (string, error) {
 		} `json:"artists"`
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
+	if err := mb.getJSON(apiURL, &result); err != nil {
 		return "", err
 	}
 
@@ -153,56 +242,186 @@ func (mb *MusicBrainzClient) SearchArtist(artistName string) (string, error) {
 
 // LookupByMBID 通过 MusicBrainz ID 查询
 func (mb *MusicBrainzClient) LookupByMBID(mbid string) (*models.Music, error) {
-	if mbid == "" {
-		return nil, fmt.Errorf("no MBID provided")
+	candidates, err := mb.LookupCandidates(mbid, MBTrackQuery{})
+	if err != nil {
+		return nil, err
 	}
 
-	apiURL := fmt.Sprintf("https://musicbrainz.org/ws/2/recording/%s?fmt=json&inc=releases+artists", mbid)
+	best := candidates[0]
+	return &models.Music{
+		Title:       best.Title,
+		Artist:      best.Artist,
+		Album:       best.Album,
+		Year:        best.Year,
+		TrackNumber: best.TrackNumber,
+		DiscNumber:  best.DiscNumber,
+	}, nil
+}
 
-	resp, err := mb.client.Get(apiURL)
-	if err != nil {
-		return nil, err
+// getJSON 发送限速后的 GET 请求并解析 JSON；遇到 503 限流时等待后重试一次
+func (mb *MusicBrainzClient) getJSON(apiURL string, v interface{}) error {
+	for attempt := 0; ; attempt++ {
+		waitForMusicBrainz()
+
+		req, err := http.NewRequest("GET", apiURL, nil)
+		if err != nil {
+			return err
+		}
+		// MusicBrainz 要求 User-Agent
+		req.Header.Set("User-Agent", mbUserAgent)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := mb.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusServiceUnavailable && attempt == 0 {
+			time.Sleep(2 * mbMinInterval)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("API request failed: %s", resp.Status)
+		}
+
+		return json.Unmarshal(body, v)
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// waitForMusicBrainz 阻塞直到距上一次请求至少间隔 mbMinInterval
+func waitForMusicBrainz() {
+	mbRateMutex.Lock()
+	defer mbRateMutex.Unlock()
+
+	if wait := mbMinInterval - time.Since(mbLastRequest); wait > 0 {
+		time.Sleep(wait)
 	}
+	mbLastRequest = time.Now()
+}
 
-	var result struct {
-		ID           string `json:"id"`
-		Title        string `json:"title"`
-		ArtistCredit []struct {
-			Name string `json:"name"`
-		} `json:"artist-credit"`
-		Releases []struct {
-			ID    string `json:"id"`
-			Title string `json:"title"`
-			Date  string `json:"date"`
-		} `json:"releases"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+func buildCandidates(rec mbRecording, q MBTrackQuery) []MBCandidate {
+	return buildCandidatesLimit(rec, q, mbReleasesPerRecording)
+}
+
+func buildCandidatesLimit(rec mbRecording, q MBTrackQuery, maxReleases int) []MBCandidate {
+	base := MBCandidate{
+		RecordingID:   rec.ID,
+		Title:         rec.Title,
+		Length:        rec.Length / 1000,
+		DurationDelta: -1,
+		MBScore:       rec.Score,
+	}
+
+	var artist strings.Builder
+	for _, credit := range rec.ArtistCredit {
+		artist.WriteString(credit.Name)
+		artist.WriteString(credit.JoinPhrase)
+		base.Artists = append(base.Artists, credit.Name)
+		base.ArtistIDs = append(base.ArtistIDs, credit.Artist.ID)
 	}
+	base.Artist = artist.String()
 
-	music := &models.Music{
-		Title: result.Title,
+	if base.Length > 0 && q.Duration > 0 {
+		base.DurationDelta = absInt(base.Length - q.Duration)
 	}
 
-	if len(result.ArtistCredit) > 0 {
-		music.Artist = result.ArtistCredit[0].Name
+	if len(rec.Releases) == 0 {
+		base.Score = scoreCandidate(base, q)
+		return []MBCandidate{base}
 	}
 
-	if len(result.Releases) > 0 {
-		music.Album = result.Releases[0].Title
-		if result.Releases[0].Date != "" && len(result.Releases[0].Date) >= 4 {
-			if year, err := strconv.Atoi(result.Releases[0].Date[:4]); err == nil {
-				music.Year = year
+	var candidates []MBCandidate
+	for i, rel := range rec.Releases {
+		if i >= maxReleases {
+			break
+		}
+		c := base
+		c.ReleaseID = rel.ID
+		c.ReleaseGroupID = rel.ReleaseGroup.ID
+		c.ReleaseType = strings.ToLower(rel.ReleaseGroup.PrimaryType)
+		c.Album = rel.Title
+		c.Date = rel.Date
+		c.Country = rel.Country
+		c.Year = parseYear(rel.Date)
+
+		if len(rel.Media) > 0 {
+			medium := rel.Media[0]
+			tracks := medium.Track
+			if len(tracks) == 0 {
+				tracks = medium.Tracks
 			}
+			c.DiscNumber = medium.Position
+			c.TrackCount = medium.TrackCount
+			if len(tracks) > 0 {
+				c.TrackID = tracks[0].ID
+				c.TrackNumber, _ = strconv.Atoi(tracks[0].Number)
+				// 曲目时长比录音时长更贴近具体发行版本
+				if tracks[0].Length > 0 && q.Duration > 0 {
+					c.DurationDelta = absInt(tracks[0].Length/1000 - q.Duration)
+				}
+			}
+		}
+
+		c.Score = scoreCandidate(c, q)
+		if rel.Status == "Official" {
+			c.Score += 2
 		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// scoreCandidate 以 MusicBrainz 分数为基础，根据时长差和专辑名调整
+func scoreCandidate(c MBCandidate, q MBTrackQuery) float64 {
+	score := float64(c.MBScore)
+
+	if c.DurationDelta >= 0 {
+		delta := c.DurationDelta
+		if delta > 30 {
+			delta = 30
+		}
+		score -= float64(delta) * 1.5
+	}
+
+	if q.Album != "" && c.Album != "" && strings.EqualFold(strings.TrimSpace(q.Album), strings.TrimSpace(c.Album)) {
+		score += 10
+	}
+	if q.Title != "" && strings.EqualFold(strings.TrimSpace(q.Title), strings.TrimSpace(c.Title)) {
+		score += 5
 	}
+	return score
+}
 
-	return music, nil
+func rankCandidates(candidates []MBCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+func parseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+func luceneEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
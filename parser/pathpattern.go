@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pathFieldRe 匹配 %artist% 形式的字段
+var pathFieldRe = regexp.MustCompile(`%([a-z_]+)%`)
+
+// pathFieldPatterns 各字段对应的正则，%ignore% 匹配任意一段不关心的内容
+var pathFieldPatterns = map[string]string{
+	"artist":       `[^/]+?`,
+	"album":        `[^/]+?`,
+	"album_artist": `[^/]+?`,
+	"title":        `[^/]+?`,
+	"genre":        `[^/]+?`,
+	"composer":     `[^/]+?`,
+	"year":         `\d{4}`,
+	"track":        `\d{1,3}`,
+	"disc":         `\d{1,2}`,
+	"ignore":       `[^/]*?`,
+}
+
+// PathPattern 将 "%artist%/%album% (%year%)/%track%. %title%" 这样的模式
+// 编译为正则，从文件路径末尾 (不含扩展名) 开始匹配
+type PathPattern struct {
+	raw    string
+	re     *regexp.Regexp
+	fields []string
+}
+
+// CompilePathPattern 编译路径模式
+func CompilePathPattern(pattern string) (*PathPattern, error) {
+	pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+
+	var expr strings.Builder
+	var fields []string
+	last := 0
+	for _, m := range pathFieldRe.FindAllStringSubmatchIndex(pattern, -1) {
+		field := pattern[m[2]:m[3]]
+		fieldExpr, ok := pathFieldPatterns[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %%%s%%", field)
+		}
+		expr.WriteString(regexp.QuoteMeta(pattern[last:m[0]]))
+		expr.WriteString("(" + fieldExpr + ")")
+		fields = append(fields, field)
+		last = m[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("pattern contains no fields")
+	}
+
+	re, err := regexp.Compile(`(?:^|/)` + expr.String() + `$`)
+	if err != nil {
+		return nil, err
+	}
+	return &PathPattern{raw: pattern, re: re, fields: fields}, nil
+}
+
+// CompilePathPatterns 依次编译多个模式，任一模式无效时返回错误
+func CompilePathPatterns(patterns []string) ([]*PathPattern, error) {
+	var compiled []*PathPattern
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		pp, err := CompilePathPattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, pp)
+	}
+	return compiled, nil
+}
+
+// String 返回原始模式
+func (p *PathPattern) String() string {
+	return p.raw
+}
+
+// Match 匹配文件路径，返回字段值；不匹配时返回 nil
+func (p *PathPattern) Match(filePath string) map[string]string {
+	filePath = strings.TrimSuffix(filePath, path.Ext(filePath))
+	m := p.re.FindStringSubmatch(filePath)
+	if m == nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for i, field := range p.fields {
+		value := strings.TrimSpace(m[i+1])
+		if field == "ignore" || value == "" {
+			continue
+		}
+		if _, ok := values[field]; !ok {
+			values[field] = value
+		}
+	}
+	return values
+}
+
+// MatchPathPatterns 使用第一个匹配的模式解析路径
+func MatchPathPatterns(patterns []*PathPattern, filePath string) (*PathPattern, map[string]string) {
+	for _, p := range patterns {
+		if values := p.Match(filePath); values != nil {
+			return p, values
+		}
+	}
+	return nil, nil
+}
+
+// ApplyPathValues 把路径解析结果写入曲目，overwrite 为 false 时只填充空字段，返回被修改的字段
+func ApplyPathValues(music *models.Music, values map[string]string, overwrite bool) []string {
+	var changed []string
+
+	setString := func(field string, dst *string) {
+		v, ok := values[field]
+		if !ok || (*dst != "" && !overwrite) || *dst == v {
+			return
+		}
+		*dst = v
+		changed = append(changed, field)
+	}
+	setInt := func(field string, dst *int) {
+		n, err := strconv.Atoi(values[field])
+		if err != nil || n <= 0 || (*dst > 0 && !overwrite) || *dst == n {
+			return
+		}
+		*dst = n
+		changed = append(changed, field)
+	}
+
+	setString("title", &music.Title)
+	setString("artist", &music.Artist)
+	setString("album", &music.Album)
+	setString("album_artist", &music.AlbumArtist)
+	setString("genre", &music.Genre)
+	setString("composer", &music.Composer)
+	setInt("year", &music.Year)
+	setInt("track", &music.TrackNumber)
+	setInt("disc", &music.DiscNumber)
+
+	return changed
+}
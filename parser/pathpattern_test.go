@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"go-music-tag/models"
+	"reflect"
+	"testing"
+)
+
+func TestCompilePathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		raw     string
+		ok      bool
+	}{
+		{"%artist%/%album%/%track% - %title%", "%artist%/%album%/%track% - %title%", true},
+		{" /%artist% - %title%/ ", "%artist% - %title%", true},
+		{"%ignore%/%title%", "%ignore%/%title%", true},
+		{"", "", false},
+		{"no fields", "", false},
+		{"%artist%/%bogus%", "", false},
+	}
+	for _, tt := range tests {
+		p, err := CompilePathPattern(tt.pattern)
+		if (err == nil) != tt.ok {
+			t.Errorf("CompilePathPattern(%q) error = %v, want ok %v", tt.pattern, err, tt.ok)
+			continue
+		}
+		if tt.ok && p.String() != tt.raw {
+			t.Errorf("String() = %q, want %q", p.String(), tt.raw)
+		}
+	}
+
+	if _, err := CompilePathPatterns([]string{"%title%", "", "%nope%"}); err == nil {
+		t.Error("CompilePathPatterns: expected error for invalid pattern")
+	}
+	if ps, err := CompilePathPatterns([]string{"%title%", "  "}); err != nil || len(ps) != 1 {
+		t.Errorf("CompilePathPatterns skipped blanks = %d, %v", len(ps), err)
+	}
+}
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    map[string]string
+	}{
+		{
+			name:    "artist album track title",
+			pattern: "%artist%/%album% (%year%)/%track%. %title%",
+			path:    "/dav/music/周杰伦/叶惠美 (2003)/03. 晴天.mp3",
+			want:    map[string]string{"artist": "周杰伦", "album": "叶惠美", "year": "2003", "track": "03", "title": "晴天"},
+		},
+		{
+			name:    "matches from the end of the path",
+			pattern: "%artist% - %title%",
+			path:    "/a/b/c/Artist - Song.flac",
+			want:    map[string]string{"artist": "Artist", "title": "Song"},
+		},
+		{
+			name:    "dash inside title",
+			pattern: "%track% - %title%",
+			path:    "/x/01 - Song - Live.mp3",
+			want:    map[string]string{"track": "01", "title": "Song - Live"},
+		},
+		{
+			name:    "ignore and disc",
+			pattern: "%ignore%/CD%disc%/%track% %title%",
+			path:    "/m/Album [FLAC]/CD2/05 Name.mp3",
+			want:    map[string]string{"disc": "2", "track": "05", "title": "Name"},
+		},
+		{
+			name:    "first occurrence wins",
+			pattern: "%artist%/%artist% - %title%",
+			path:    "/A/B - C.mp3",
+			want:    map[string]string{"artist": "A", "title": "C"},
+		},
+		{
+			name:    "year must be four digits",
+			pattern: "%album% (%year%)/%title%",
+			path:    "/m/Album (03)/Song.mp3",
+			want:    nil,
+		},
+		{
+			name:    "must start at a path segment",
+			pattern: "%track%. %title%",
+			path:    "/m/Song.mp3",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := CompilePathPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompilePathPattern: %v", err)
+			}
+			if got := p.Match(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPathPatterns(t *testing.T) {
+	patterns, err := CompilePathPatterns([]string{"%artist%/%album%/%track% %title%", "%artist% - %title%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, values := MatchPathPatterns(patterns, "/m/Artist/Album/07 Song.mp3")
+	if p != patterns[0] || values["track"] != "07" {
+		t.Errorf("got pattern %v, values %v", p, values)
+	}
+	p, values = MatchPathPatterns(patterns, "/m/Artist - Song.mp3")
+	if p != patterns[1] || values["title"] != "Song" {
+		t.Errorf("got pattern %v, values %v", p, values)
+	}
+	if p, values = MatchPathPatterns(patterns, "/m/Song.mp3"); p != nil || values != nil {
+		t.Errorf("no match: got %v, %v", p, values)
+	}
+}
+
+func TestApplyPathValues(t *testing.T) {
+	values := map[string]string{"title": "Song", "artist": "Artist", "year": "2001", "track": "07", "disc": "x"}
+	tests := []struct {
+		name      string
+		music     models.Music
+		overwrite bool
+		want      models.Music
+		changed   []string
+	}{
+		{
+			name:    "fill empty fields",
+			music:   models.Music{},
+			want:    models.Music{Title: "Song", Artist: "Artist", Year: 2001, TrackNumber: 7},
+			changed: []string{"title", "artist", "year", "track"},
+		},
+		{
+			name:    "keep existing without overwrite",
+			music:   models.Music{Title: "Old", Year: 1999},
+			want:    models.Music{Title: "Old", Artist: "Artist", Year: 1999, TrackNumber: 7},
+			changed: []string{"artist", "track"},
+		},
+		{
+			name:      "overwrite skips equal values",
+			music:     models.Music{Title: "Song", Artist: "Other", TrackNumber: 7, DiscNumber: 2},
+			overwrite: true,
+			want:      models.Music{Title: "Song", Artist: "Artist", Year: 2001, TrackNumber: 7, DiscNumber: 2},
+			changed:   []string{"artist", "year"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.music
+			changed := ApplyPathValues(&m, values, tt.overwrite)
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if m.Title != tt.want.Title || m.Artist != tt.want.Artist || m.Year != tt.want.Year ||
+				m.TrackNumber != tt.want.TrackNumber || m.DiscNumber != tt.want.DiscNumber {
+				t.Errorf("music = %+v, want %+v", m, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"go-music-tag/models"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// musicBrainzUFIDProvider Picard 写入录音 ID 时使用的 UFID provider
+const musicBrainzUFIDProvider = "http://musicbrainz.org"
+
+// id3TextFrames ID3v2 文本帧到统一键名 (Vorbis 注释风格) 的映射
+var id3TextFrames = map[string]string{
+	"TSRC": "isrc",
+	"TRC":  "isrc",
+	"TPUB": "label",
+	"TPB":  "label",
+	"TBPM": "bpm",
+	"TBP":  "bpm",
+	"TKEY": "initialkey",
+	"TKE":  "initialkey",
+	"TSOP": "artistsort",
+	"TSOA": "albumsort",
+	"TSOT": "titlesort",
+	"TSO2": "albumartistsort",
+	"TSOC": "composersort",
+	"TDOR": "originaldate",
+	"TORY": "originalyear",
+	"TOR":  "originalyear",
+}
+
+// txxxAliases Picard TXXX 描述 (小写) 到统一键名的映射，未列出的描述直接使用小写形式
+var txxxAliases = map[string]string{
+	"musicbrainz track id":         "musicbrainz_trackid",
+	"musicbrainz release track id": "musicbrainz_releasetrackid",
+	"musicbrainz album id":         "musicbrainz_albumid",
+	"musicbrainz release group id": "musicbrainz_releasegroupid",
+	"musicbrainz artist id":        "musicbrainz_artistid",
+	"musicbrainz album artist id":  "musicbrainz_albumartistid",
+	"musicbrainz album type":       "releasetype",
+	"key":                          "initialkey",
+	"featured artists":             "featured_artists",
+}
+
+// vorbisAliases Vorbis 注释中同义键的映射
+var vorbisAliases = map[string]string{
+	"organization":          "label",
+	"publisher":             "label",
+	"musicbrainz_albumtype": "releasetype",
+	"key":                   "initialkey",
+	"tempo":                 "bpm",
+}
+
+// readExtendedTags 从 ID3v2 (TXXX/UFID/文本帧) 或 Vorbis 注释中读取扩展标签，
+// 返回统一的小写键名，例如 musicbrainz_albumid、isrc、replaygain_track_gain
+func readExtendedTags(md tag.Metadata) map[string]string {
+	result := make(map[string]string)
+
+	set := func(key, value string) {
+		value = strings.TrimSpace(strings.Trim(value, "\x00"))
+		if key == "" || value == "" {
+			return
+		}
+		if _, ok := result[key]; !ok {
+			result[key] = value
+		}
+	}
+
+	switch md.Format() {
+	case tag.ID3v2_2, tag.ID3v2_3, tag.ID3v2_4:
+		for name, value := range md.Raw() {
+			frame := name
+			if i := strings.Index(frame, "_"); i > 0 {
+				frame = frame[:i] // 重复帧会被命名为 TXXX_0、TXXX_1
+			}
+
+			switch v := value.(type) {
+			case *tag.Comm:
+				if frame != "TXXX" && frame != "TXX" {
+					continue
+				}
+				desc := strings.ToLower(strings.TrimSpace(v.Description))
+				if alias, ok := txxxAliases[desc]; ok {
+					desc = alias
+				}
+				set(desc, v.Text)
+			case *tag.UFID:
+				if v.Provider == musicBrainzUFIDProvider {
+					set("musicbrainz_trackid", string(v.Identifier))
+				}
+			case string:
+				if key, ok := id3TextFrames[frame]; ok {
+					set(key, v)
+				}
+			}
+		}
+	default:
+		for name, value := range md.Raw() {
+			v, ok := value.(string)
+			if !ok {
+				continue
+			}
+			key := strings.ToLower(name)
+			if alias, ok := vorbisAliases[key]; ok {
+				key = alias
+			}
+			set(key, v)
+		}
+	}
+
+	return result
+}
+
+// applyExtendedTags 将扩展标签写入 models.Music
+func applyExtendedTags(music *models.Music, tags map[string]string) {
+	music.MBRecordingID = tags["musicbrainz_trackid"]
+	music.MBTrackID = tags["musicbrainz_releasetrackid"]
+	music.MBReleaseID = tags["musicbrainz_albumid"]
+	music.MBReleaseGroupID = tags["musicbrainz_releasegroupid"]
+	music.MBArtistID = tags["musicbrainz_artistid"]
+	music.MBAlbumArtistID = tags["musicbrainz_albumartistid"]
+
+	music.FeaturedArtists = tags["featured_artists"]
+	music.ISRC = tags["isrc"]
+	music.Label = tags["label"]
+	music.CatalogNumber = tags["catalognumber"]
+	music.InitialKey = tags["initialkey"]
+	music.ReleaseType = tags["releasetype"]
+
+	music.ArtistSort = tags["artistsort"]
+	music.AlbumSort = tags["albumsort"]
+	music.TitleSort = tags["titlesort"]
+	music.AlbumArtistSort = tags["albumartistsort"]
+	music.ComposerSort = tags["composersort"]
+
+	if bpm, err := strconv.ParseFloat(tags["bpm"], 64); err == nil && bpm > 0 {
+		music.BPM = int(bpm + 0.5)
+	}
+
+	// TDOR/ORIGINALDATE 为完整日期，取前 4 位作为年份
+	for _, key := range []string{"originalyear", "originaldate"} {
+		if year := parseYear(tags[key]); year > 0 {
+			music.OriginalYear = year
+			break
+		}
+	}
+
+	music.ReplayGainTrackGain = parseReplayGain(tags["replaygain_track_gain"])
+	music.ReplayGainTrackPeak = parseReplayGain(tags["replaygain_track_peak"])
+	music.ReplayGainAlbumGain = parseReplayGain(tags["replaygain_album_gain"])
+	music.ReplayGainAlbumPeak = parseReplayGain(tags["replaygain_album_peak"])
+}
+
+// parseReplayGain 解析 "-6.54 dB" 或 "0.988547" 形式的值，无法解析时返回 nil
+func parseReplayGain(value string) *float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(value, "dB"), "db"))
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// 码率模式
+const (
+	BitRateCBR = "CBR"
+	BitRateVBR = "VBR"
+	BitRateABR = "ABR"
+)
+
+// vbrHeader 第一帧中的 Xing/Info 或 VBRI 头，以及紧随 Xing 头的 LAME 扩展信息
+type vbrHeader struct {
+	tag     string // Xing | Info | VBRI
+	frames  int    // 音频帧数，不含头所在的帧
+	bytes   int    // 音频数据字节数，0 表示未知
+	quality int    // -1 表示未知
+	encoder string
+	mode    string
+	preset  string
+	delay   int // 编码器在开头插入的采样数
+	padding int // 编码器在末尾补齐的采样数
+}
+
+// lamePresets LAME 标签中 preset 字段的命名值
+var lamePresets = map[int]string{
+	1000: "r3mix",
+	1001: "standard",
+	1002: "extreme",
+	1003: "insane",
+	1004: "fast standard",
+	1005: "fast extreme",
+	1006: "medium",
+	1007: "fast medium",
+}
+
+// parseVBRHeader 解析位于 pos 的首帧，没有 Xing/Info/VBRI 头时返回 nil
+func parseVBRHeader(data []byte, pos int, h frameHeader) *vbrHeader {
+	if pos+h.size > len(data) {
+		return nil
+	}
+	frame := data[pos : pos+h.size]
+
+	// Xing/Info 位于 side information 之后
+	offset := 4 + h.sideInfoSize()
+	if h.protected {
+		offset += 2
+	}
+	if h.layer == 3 && len(frame) >= offset+8 {
+		if tag := string(frame[offset : offset+4]); tag == "Xing" || tag == "Info" {
+			return parseXing(frame[offset:], tag)
+		}
+	}
+
+	// VBRI 固定位于帧头后 32 字节
+	if len(frame) >= 36+26 && bytes.Equal(frame[36:40], []byte("VBRI")) {
+		v := frame[36:]
+		return &vbrHeader{
+			tag:     "VBRI",
+			delay:   int(binary.BigEndian.Uint16(v[6:8])),
+			quality: int(binary.BigEndian.Uint16(v[8:10])),
+			bytes:   int(binary.BigEndian.Uint32(v[10:14])),
+			frames:  int(binary.BigEndian.Uint32(v[14:18])),
+			encoder: "Fraunhofer",
+			mode:    BitRateVBR,
+		}
+	}
+	return nil
+}
+
+func parseXing(x []byte, tag string) *vbrHeader {
+	v := &vbrHeader{tag: tag, quality: -1, mode: BitRateVBR}
+	if tag == "Info" {
+		v.mode = BitRateCBR
+	}
+
+	flags := binary.BigEndian.Uint32(x[4:8])
+	p := 8
+	read := func() (int, bool) {
+		if len(x) < p+4 {
+			return 0, false
+		}
+		n := int(binary.BigEndian.Uint32(x[p : p+4]))
+		p += 4
+		return n, true
+	}
+	if flags&0x01 != 0 {
+		if n, ok := read(); ok {
+			v.frames = n
+		}
+	}
+	if flags&0x02 != 0 {
+		if n, ok := read(); ok {
+			v.bytes = n
+		}
+	}
+	if flags&0x04 != 0 {
+		p += 100 // TOC
+	}
+	if flags&0x08 != 0 {
+		if n, ok := read(); ok {
+			v.quality = n
+		}
+	}
+
+	// LAME 扩展标签：9 字节编码器版本 + 27 字节信息
+	if len(x) < p+9 {
+		return v
+	}
+	v.encoder = printable(x[p : p+9])
+	if !strings.HasPrefix(v.encoder, "LAME") || len(x) < p+36 {
+		return v
+	}
+	lame := x[p:]
+
+	switch lame[9] & 0x0F {
+	case 1, 8:
+		v.mode = BitRateCBR
+	case 2, 9:
+		v.mode = BitRateABR
+	case 3, 4, 5, 6:
+		v.mode = BitRateVBR
+	}
+	v.delay = int(lame[21])<<4 | int(lame[22])>>4
+	v.padding = int(lame[22]&0x0F)<<8 | int(lame[23])
+
+	preset := int(binary.BigEndian.Uint16(lame[26:28]) & 0x07FF)
+	switch {
+	case lamePresets[preset] != "":
+		v.preset = lamePresets[preset]
+	case preset >= 410 && preset <= 500 && preset%10 == 0:
+		v.preset = fmt.Sprintf("V%d", (500-preset)/10)
+	case preset >= 8 && preset <= 320:
+		v.preset = fmt.Sprintf("%s %d", v.mode, preset)
+	case v.mode == BitRateVBR && v.quality >= 0 && v.quality <= 100:
+		// 旧版 LAME 不写 preset，按 Xing 质量值推算 -V 等级
+		v.preset = fmt.Sprintf("V%d", (100-v.quality)/10)
+	}
+	return v
+}
+
+// printable 去掉编码器版本字符串中的填充和不可见字符
+func printable(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c >= 0x20 && c < 0x7F {
+			sb.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// streamInfo 由 VBR 头或逐帧统计得到的音频流信息
+type streamInfo struct {
+	duration   float64 // 秒
+	bitRate    int     // kbps
+	sampleRate int
+	frames     int
+	mode       string
+	encoder    string
+	preset     string
+	delay      int
+	padding    int
+	exact      bool // 时长来自帧数而不是估算
+}
+
+// readStreamInfo 优先使用 Xing/Info/VBRI 头中的帧数计算时长，没有时使用逐帧统计的结果
+func readStreamInfo(data []byte, scan frameScan) streamInfo {
+	var info streamInfo
+	if scan.frames == 0 {
+		return info
+	}
+	first := scan.first
+	info.sampleRate = first.sampleRate
+
+	if v := parseVBRHeader(data, scan.firstPos, first); v != nil && v.frames > 0 {
+		samples := v.frames*first.samples - v.delay - v.padding
+		if samples <= 0 {
+			samples = v.frames * first.samples
+		}
+		info.duration = float64(samples) / float64(first.sampleRate)
+		info.frames = v.frames
+		info.mode = v.mode
+		info.encoder = v.encoder
+		info.preset = v.preset
+		info.delay = v.delay
+		info.padding = v.padding
+		info.exact = true
+
+		audioBytes := v.bytes
+		if audioBytes <= 0 {
+			audioBytes = int(scan.audioBytes) - first.size
+		}
+		switch {
+		case v.mode == BitRateCBR:
+			// Info 帧本身使用与音频帧相同的比特率
+			info.bitRate = first.bitRate
+		case audioBytes > 0 && info.duration > 0:
+			info.bitRate = int(float64(audioBytes) * 8 / info.duration / 1000)
+		}
+		return info
+	}
+
+	// 没有 VBR 头：逐帧累加
+	info.frames = scan.frames
+	info.duration = float64(scan.samples) / float64(first.sampleRate)
+	info.exact = true
+	if scan.bitRateVaries {
+		info.mode = BitRateVBR
+	} else {
+		info.mode = BitRateCBR
+	}
+	if info.duration > 0 {
+		info.bitRate = int(float64(scan.audioBytes) * 8 / info.duration / 1000)
+	}
+	return info
+}
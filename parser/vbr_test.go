@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// MPEG-1 Layer III，128 kbps，44100 Hz，联合立体声，无 CRC；整帧 417 字节
+var testFrameHeader = []byte{0xFF, 0xFB, 0x90, 0x64}
+
+// xingOffset 立体声 MPEG-1 帧中 Xing/Info 头的位置 (帧头 4 + side information 32)
+const xingOffset = 36
+
+// lameTag LAME 扩展标签的取值
+type lameTag struct {
+	version string
+	method  byte
+	delay   int
+	padding int
+	preset  int
+}
+
+func (l lameTag) bytes() []byte {
+	b := make([]byte, 36)
+	copy(b, l.version)
+	b[9] = 0x10 | l.method
+	b[21] = byte(l.delay >> 4)
+	b[22] = byte(l.delay&0x0F)<<4 | byte(l.padding>>8)
+	b[23] = byte(l.padding)
+	binary.BigEndian.PutUint16(b[26:28], uint16(l.preset))
+	return b
+}
+
+// xingHeader 构造 Xing/Info 头，负值表示不写对应字段
+func xingHeader(tag string, frames, size, quality int, toc bool, lame *lameTag) []byte {
+	var flags uint32
+	var body []byte
+	put := func(flag uint32, v int) {
+		if v < 0 {
+			return
+		}
+		flags |= flag
+		body = binary.BigEndian.AppendUint32(body, uint32(v))
+	}
+	put(0x01, frames)
+	put(0x02, size)
+	if toc {
+		flags |= 0x04
+		body = append(body, make([]byte, 100)...)
+	}
+	put(0x08, quality)
+
+	b := append([]byte(tag), binary.BigEndian.AppendUint32(nil, flags)...)
+	b = append(b, body...)
+	if lame != nil {
+		b = append(b, lame.bytes()...)
+	}
+	return b
+}
+
+// mp3Frame 返回一整帧，payload 写在 side information 之后
+func mp3Frame(header []byte, payload []byte) []byte {
+	h, ok := parseFrameHeader(header)
+	if !ok {
+		panic("invalid test frame header")
+	}
+	frame := make([]byte, h.size)
+	copy(frame, header)
+	copy(frame[xingOffset:], payload)
+	return frame
+}
+
+func parseTestVBRHeader(t *testing.T, frame []byte) *vbrHeader {
+	t.Helper()
+	h, ok := parseFrameHeader(frame)
+	if !ok {
+		t.Fatal("invalid frame header")
+	}
+	return parseVBRHeader(frame, 0, h)
+}
+
+func TestParseVBRHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		xing  []byte
+		want  vbrHeader
+		isNil bool
+	}{
+		{
+			name: "xing without lame",
+			xing: xingHeader("Xing", 1000, 400000, -1, false, nil),
+			want: vbrHeader{tag: "Xing", frames: 1000, bytes: 400000, quality: -1, mode: BitRateVBR},
+		},
+		{
+			name: "xing with toc and quality",
+			xing: xingHeader("Xing", 500, -1, 78, true, nil),
+			want: vbrHeader{tag: "Xing", frames: 500, quality: 78, mode: BitRateVBR},
+		},
+		{
+			name: "info is cbr",
+			xing: xingHeader("Info", 200, -1, -1, false, nil),
+			want: vbrHeader{tag: "Info", frames: 200, quality: -1, mode: BitRateCBR},
+		},
+		{
+			name: "lame vbr preset V2",
+			xing: xingHeader("Xing", 1000, 400000, 78, true, &lameTag{version: "LAME3.100", method: 4, delay: 576, padding: 1200, preset: 480}),
+			want: vbrHeader{tag: "Xing", frames: 1000, bytes: 400000, quality: 78, encoder: "LAME3.100", mode: BitRateVBR, preset: "V2", delay: 576, padding: 1200},
+		},
+		{
+			name: "lame named preset",
+			xing: xingHeader("Xing", 10, -1, -1, false, &lameTag{version: "LAME3.99r", method: 3, preset: 1001}),
+			want: vbrHeader{tag: "Xing", frames: 10, quality: -1, encoder: "LAME3.99r", mode: BitRateVBR, preset: "standard"},
+		},
+		{
+			name: "lame cbr bitrate",
+			xing: xingHeader("Info", 10, -1, -1, false, &lameTag{version: "LAME3.100", method: 1, preset: 320}),
+			want: vbrHeader{tag: "Info", frames: 10, quality: -1, encoder: "LAME3.100", mode: BitRateCBR, preset: "CBR 320"},
+		},
+		{
+			name: "lame abr overrides xing tag",
+			xing: xingHeader("Xing", 10, -1, -1, false, &lameTag{version: "LAME3.98", method: 2, preset: 192}),
+			want: vbrHeader{tag: "Xing", frames: 10, quality: -1, encoder: "LAME3.98", mode: BitRateABR, preset: "ABR 192"},
+		},
+		{
+			name: "old lame preset from quality",
+			xing: xingHeader("Xing", 10, -1, 57, false, &lameTag{version: "LAME3.90", method: 4}),
+			want: vbrHeader{tag: "Xing", frames: 10, quality: 57, encoder: "LAME3.90", mode: BitRateVBR, preset: "V4"},
+		},
+		{
+			name: "other encoder keeps name only",
+			xing: xingHeader("Xing", 10, -1, -1, false, &lameTag{version: "Lavc58.91", method: 1, preset: 320}),
+			want: vbrHeader{tag: "Xing", frames: 10, quality: -1, encoder: "Lavc58.91", mode: BitRateVBR},
+		},
+		{
+			name:  "no header",
+			xing:  []byte("\x00\x00\x00\x00\x00\x00\x00\x00"),
+			isNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTestVBRHeader(t, mp3Frame(testFrameHeader, tt.xing))
+			if tt.isNil {
+				if got != nil {
+					t.Fatalf("got %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil header")
+			}
+			if *got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVBRHeaderVBRI(t *testing.T) {
+	frame := mp3Frame(testFrameHeader, nil)
+	v := frame[36:]
+	copy(v, "VBRI")
+	binary.BigEndian.PutUint16(v[4:6], 1)
+	binary.BigEndian.PutUint16(v[6:8], 1105)
+	binary.BigEndian.PutUint16(v[8:10], 75)
+	binary.BigEndian.PutUint32(v[10:14], 3000000)
+	binary.BigEndian.PutUint32(v[14:18], 7000)
+
+	got := parseTestVBRHeader(t, frame)
+	want := vbrHeader{tag: "VBRI", frames: 7000, bytes: 3000000, quality: 75, encoder: "Fraunhofer", mode: BitRateVBR, delay: 1105}
+	if got == nil || *got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVBRHeaderTruncated(t *testing.T) {
+	frame := mp3Frame(testFrameHeader, xingHeader("Xing", 10, -1, -1, false, nil))
+	h, _ := parseFrameHeader(frame)
+	if got := parseVBRHeader(frame[:100], 0, h); got != nil {
+		t.Errorf("truncated frame: got %+v, want nil", got)
+	}
+
+	// 声明了 TOC 和质量值但数据不够时不越界
+	short := xingHeader("Xing", 10, -1, -1, false, nil)
+	binary.BigEndian.PutUint32(short[4:8], 0x0F)
+	if got := parseXing(short, "Xing"); got.frames != 10 || got.quality != -1 {
+		t.Errorf("short xing = %+v", got)
+	}
+}
+
+func TestReadStreamInfo(t *testing.T) {
+	audio := func(header []byte, n int) []byte {
+		var b []byte
+		for i := 0; i < n; i++ {
+			b = append(b, mp3Frame(header, nil)...)
+		}
+		return b
+	}
+	frame160 := []byte{0xFF, 0xFB, 0xA0, 0x64} // 160 kbps，522 字节
+
+	tests := []struct {
+		name     string
+		data     []byte
+		duration float64
+		bitRate  int
+		frames   int
+		mode     string
+		preset   string
+	}{
+		{
+			name:     "cbr without header",
+			data:     audio(testFrameHeader, 20),
+			duration: 20 * 1152 / 44100.0,
+			bitRate:  127, // 20×417 字节 / 0.522 秒
+			frames:   20,
+			mode:     BitRateCBR,
+		},
+		{
+			name:     "varying bitrate without header",
+			data:     append(audio(testFrameHeader, 10), audio(frame160, 10)...),
+			duration: 20 * 1152 / 44100.0,
+			bitRate:  143, // 10×(417+522) 字节 / 0.522 秒
+			frames:   20,
+			mode:     BitRateVBR,
+		},
+		{
+			name: "xing frame count with gapless trim",
+			data: append(mp3Frame(testFrameHeader, xingHeader("Xing", 1000, 500000, 78, false,
+				&lameTag{version: "LAME3.100", method: 4, delay: 576, padding: 1152, preset: 480})), audio(testFrameHeader, 5)...),
+			duration: (1000*1152 - 576 - 1152) / 44100.0,
+			bitRate:  153, // Xing 头中的 500000 字节 / 26.08 秒
+			frames:   1000,
+			mode:     BitRateVBR,
+			preset:   "V2",
+		},
+		{
+			name:     "info frame uses frame bitrate",
+			data:     append(mp3Frame(testFrameHeader, xingHeader("Info", 100, -1, -1, false, nil)), audio(testFrameHeader, 5)...),
+			duration: 100 * 1152 / 44100.0,
+			bitRate:  128,
+			frames:   100,
+			mode:     BitRateCBR,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := readStreamInfo(tt.data, scanFrames(tt.data))
+			if math.Abs(info.duration-tt.duration) > 1e-9 {
+				t.Errorf("duration = %v, want %v", info.duration, tt.duration)
+			}
+			if info.bitRate != tt.bitRate || info.frames != tt.frames || info.mode != tt.mode || info.preset != tt.preset {
+				t.Errorf("info = %+v, want bitrate %d, frames %d, mode %s, preset %q", info, tt.bitRate, tt.frames, tt.mode, tt.preset)
+			}
+			if !info.exact || info.sampleRate != 44100 {
+				t.Errorf("exact = %v, sample rate = %d", info.exact, info.sampleRate)
+			}
+		})
+	}
+
+	if info := readStreamInfo(nil, scanFrames(bytes.Repeat([]byte{0}, 100))); info != (streamInfo{}) {
+		t.Errorf("no frames: got %+v", info)
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	if got := printable([]byte("LAME3.100\x00")); got != "LAME3.100" {
+		t.Errorf("printable = %q", got)
+	}
+	if got := printable([]byte(" L\x01AME \xff")); got != "LAME" {
+		t.Errorf("printable = %q", got)
+	}
+}
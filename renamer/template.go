@@ -0,0 +1,198 @@
+package renamer
+
+import (
+	"fmt"
+	"go-music-tag/models"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxComponentBytes 单个路径段的最大长度，多数文件系统限制为 255 字节
+const maxComponentBytes = 200
+
+// placeholderRe 匹配 {field} 或 {field:02}
+var placeholderRe = regexp.MustCompile(`\{([a-z_]+)(?::(0?\d+))?\}`)
+
+// Fields 模板支持的字段
+var Fields = []string{
+	"title", "artist", "album", "album_artist", "composer", "genre",
+	"year", "track", "disc", "ext", "filename",
+}
+
+// illegalChars Windows/Samba 和大多数 WebDAV 服务端不接受的字符
+var illegalChars = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_", "?", "_",
+	"\"", "'", "<", "_", ">", "_", "|", "_",
+)
+
+type placeholder struct {
+	start, end int
+	field      string
+	width      int
+}
+
+// Template 解析后的重命名模板
+type Template struct {
+	raw          string
+	placeholders []placeholder
+}
+
+// Parse 解析模板并校验字段名，模板必须以 .{ext} 结尾以保留扩展名
+func Parse(tmpl string) (*Template, error) {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+	if !strings.HasSuffix(tmpl, ".{ext}") {
+		return nil, fmt.Errorf("template must end with .{ext}")
+	}
+
+	t := &Template{raw: tmpl}
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(tmpl, -1) {
+		p := placeholder{start: m[0], end: m[1], field: tmpl[m[2]:m[3]]}
+		if !isField(p.field) {
+			return nil, fmt.Errorf("unknown field {%s}", p.field)
+		}
+		if m[4] >= 0 {
+			p.width, _ = strconv.Atoi(tmpl[m[4]:m[5]])
+		}
+		t.placeholders = append(t.placeholders, p)
+	}
+	return t, nil
+}
+
+// String 返回原始模板
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Render 根据曲目标签生成相对于音乐库根目录的路径
+func (t *Template) Render(m *models.Music) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, p := range t.placeholders {
+		b.WriteString(t.raw[last:p.start])
+		b.WriteString(fieldValue(m, p.field, p.width))
+		last = p.end
+	}
+	b.WriteString(t.raw[last:])
+
+	var parts []string
+	for _, part := range strings.Split(b.String(), "/") {
+		if part = cleanComponent(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("template produced an empty path")
+	}
+	return path.Join(parts...), nil
+}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue 取字段值，缺失时使用合理的占位，并清理非法字符
+func fieldValue(m *models.Music, field string, width int) string {
+	switch field {
+	case "year":
+		return number(m.Year, width, false)
+	case "track":
+		return number(m.TrackNumber, width, true)
+	case "disc":
+		disc := m.DiscNumber
+		if disc <= 0 {
+			disc = 1
+		}
+		return number(disc, width, true)
+	case "ext":
+		return strings.ToLower(strings.TrimPrefix(path.Ext(m.FilePath), "."))
+	}
+
+	var value string
+	switch field {
+	case "title":
+		value = m.Title
+		if strings.TrimSpace(value) == "" {
+			value = baseName(m)
+		}
+	case "artist":
+		value = fallback(m.Artist, "Unknown Artist")
+	case "album_artist":
+		value = fallback(m.AlbumArtist, fallback(m.Artist, "Unknown Artist"))
+	case "album":
+		value = fallback(m.Album, "Unknown Album")
+	case "composer":
+		value = m.Composer
+	case "genre":
+		value = m.Genre
+	case "filename":
+		value = baseName(m)
+	}
+	return Sanitize(value)
+}
+
+func number(n, width int, keepZero bool) string {
+	if n <= 0 && !keepZero {
+		return ""
+	}
+	if width > 0 {
+		return fmt.Sprintf("%0*d", width, n)
+	}
+	return strconv.Itoa(n)
+}
+
+func fallback(value, def string) string {
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	return value
+}
+
+func baseName(m *models.Music) string {
+	name := path.Base(m.FilePath)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// Sanitize 替换路径中的非法字符并去掉控制字符，结果不包含路径分隔符
+func Sanitize(s string) string {
+	s = illegalChars.Replace(s)
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cleanComponent 去掉空字段留下的首尾分隔符，避免生成 "." ".." 和隐藏文件
+func cleanComponent(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.Trim(s, " -_.")
+	if len(s) <= maxComponentBytes {
+		return s
+	}
+
+	// 超长时截断主文件名，保留扩展名
+	ext := path.Ext(s)
+	if len(ext) > 10 {
+		ext = ""
+	}
+	stem := s[:len(s)-len(ext)]
+	limit := maxComponentBytes - len(ext)
+	for limit > 0 && !utf8.RuneStart(stem[limit]) {
+		limit--
+	}
+	return strings.TrimRight(stem[:limit], " -_.") + ext
+}
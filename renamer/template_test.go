@@ -0,0 +1,90 @@
+package renamer
+
+import (
+	"go-music-tag/models"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		ok   bool
+	}{
+		{"{artist}/{album}/{track:02} - {title}.{ext}", true},
+		{"  {title}.{ext}  ", true},
+		{"", false},
+		{"{artist} - {title}", false},
+		{"{artist}/{bogus}.{ext}", false},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.tmpl)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) error = %v, want ok %v", tt.tmpl, err, tt.ok)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	full := &models.Music{
+		FilePath: "/music/old/01 song.MP3", Title: "晴天", Artist: "周杰伦", Album: "叶惠美",
+		AlbumArtist: "Jay Chou", Year: 2003, TrackNumber: 3, DiscNumber: 2, Genre: "Pop",
+	}
+	empty := &models.Music{FilePath: "/music/old/01 song.mp3"}
+
+	tests := []struct {
+		name  string
+		tmpl  string
+		music *models.Music
+		want  string
+	}{
+		{"full path", "{album_artist}/{album} ({year})/{disc}-{track:02} {title}.{ext}", full, "Jay Chou/叶惠美 (2003)/2-03 晴天.mp3"},
+		{"fallbacks", "{album_artist}/{album}/{track:02} {title}.{ext}", empty, "Unknown Artist/Unknown Album/00 01 song.mp3"},
+		{"empty year", "{artist} - {year} - {title}.{ext}", empty, "Unknown Artist - - 01 song.mp3"},
+		{"empty directory dropped", "{genre}/{title}.{ext}", empty, "01 song.mp3"},
+		{"filename", "{filename}.{ext}", full, "01 song.mp3"},
+		{"illegal characters", "{title}.{ext}", &models.Music{FilePath: "/a.mp3", Title: `AC/DC: "Live"?`}, "AC_DC_ 'Live'_.mp3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.tmpl)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got, err := tmpl.Render(tt.music)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLongComponent(t *testing.T) {
+	tmpl, _ := Parse("{title}.{ext}")
+	got, err := tmpl.Render(&models.Music{FilePath: "/a.flac", Title: strings.Repeat("歌", 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > maxComponentBytes || !strings.HasSuffix(got, "歌.flac") {
+		t.Errorf("Render = %q (%d bytes)", got, len(got))
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a/b\\c", "a_b_c"},
+		{"  many   spaces\t", "many spaces"},
+		{"bell\x07", "bell"},
+		{`<x>|"y"`, "_x__'y'"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"go-music-tag/config"
 	"go-music-tag/handlers"
+	"log"
 	"net/http"
 	"os"
 	"time"
@@ -25,7 +26,7 @@ func SetupRouter() *gin.Engine {
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, HEAD")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Range")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Range, X-User")
 		c.Header("Access-Control-Expose-Headers", "Content-Range, Content-Length, Content-Type")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -69,6 +70,9 @@ func SetupRouter() *gin.Engine {
 
 	// 初始化 Handler
 	musicHandler := handlers.NewMusicHandlerLazy()
+	if err := musicHandler.StartScheduler(); err != nil {
+		log.Printf("⚠️ Failed to start scheduler: %v", err)
+	}
 
 	// API 路由组
 	v1 := r.Group("/api/v1")
@@ -89,13 +93,25 @@ func SetupRouter() *gin.Engine {
 		v1.GET("/music/:id", musicHandler.Get)
 		v1.PUT("/music/:id", musicHandler.Update)
 		v1.POST("/music/batch", musicHandler.BatchUpdate)
+		v1.POST("/music/batch-edit", musicHandler.BatchEdit)
+		v1.GET("/music/batch-edit/fields", musicHandler.GetEditableFields)
+		v1.POST("/music/repair-encoding", musicHandler.RepairEncoding)
+		v1.GET("/music/repair-encoding/options", musicHandler.GetCharsetOptions)
 		v1.DELETE("/music/:id", musicHandler.Delete)
 		v1.DELETE("/music", musicHandler.DeleteAll)
 		v1.GET("/music/search", musicHandler.Search)
 		v1.GET("/music/playlist", musicHandler.GetPlaylist)
 		v1.GET("/music/:id/cover", musicHandler.GetCover)
+		v1.POST("/music/:id/cover", musicHandler.SetCover)
+		v1.DELETE("/music/:id/cover", musicHandler.DeleteCover)
+		v1.GET("/music/:id/cover/candidates", musicHandler.GetCoverCandidates)
 		v1.GET("/music/:id/play", musicHandler.Play)
 		v1.GET("/music/:id/lyrics", musicHandler.GetLyrics)
+		v1.GET("/music/:id/lyrics/export", musicHandler.ExportLyrics)
+		v1.PUT("/music/:id/lyrics", musicHandler.UpdateLyrics)
+		v1.POST("/music/:id/lyrics/adjust", musicHandler.AdjustLyricsTiming)
+		v1.GET("/music/:id/lyrics/versions", musicHandler.GetLyricsVersions)
+		v1.POST("/music/:id/lyrics/versions/:version/restore", musicHandler.RestoreLyricsVersion)
 
 		// 歌词和封面获取（确保这些只出现一次！）
 		v1.POST("/music/:id/fetch-lyrics", musicHandler.FetchLyrics)
@@ -103,6 +119,77 @@ func SetupRouter() *gin.Engine {
 		v1.POST("/music/batch-fetch-lyrics", musicHandler.BatchFetchLyrics)
 		v1.POST("/music/batch-fetch-covers", musicHandler.BatchFetchCovers)
 		v1.POST("/music/batch-fetch-all", musicHandler.BatchFetchAll)
+		v1.POST("/music/batch-fetch", musicHandler.BatchFetch)
+		v1.GET("/fetch/jobs", musicHandler.ListFetchJobs)
+		v1.GET("/fetch/jobs/:job_id", musicHandler.GetFetchJobResults)
+
+		// 定时任务
+		v1.GET("/schedules", musicHandler.ListSchedules)
+		v1.POST("/schedules", musicHandler.CreateSchedule)
+		v1.PUT("/schedules/:id", musicHandler.UpdateSchedule)
+		v1.DELETE("/schedules/:id", musicHandler.DeleteSchedule)
+		v1.POST("/schedules/:id/run", musicHandler.RunSchedule)
+
+		// 封面库 (按内容哈希去重)
+		v1.GET("/artwork/:id", musicHandler.GetArtwork)
+		v1.POST("/artwork/gc", musicHandler.CollectArtworkGarbage)
+
+		// MusicBrainz 匹配
+		v1.GET("/music/:id/musicbrainz", musicHandler.GetMusicBrainzCandidates)
+		v1.POST("/music/:id/musicbrainz/apply", musicHandler.ApplyMusicBrainzCandidate)
+		v1.POST("/music/:id/musicbrainz/refresh", musicHandler.RefreshTagsFromMusicBrainz)
+		v1.POST("/musicbrainz/batch", musicHandler.BatchMusicBrainzLookup)
+		v1.GET("/musicbrainz/matches", musicHandler.ListMusicBrainzMatches)
+		v1.POST("/musicbrainz/matches/:id/reject", musicHandler.RejectMusicBrainzMatch)
+
+		// 声学指纹识别
+		v1.GET("/music/:id/fingerprint", musicHandler.GetFingerprint)
+		v1.POST("/music/:id/fingerprint", musicHandler.FingerprintMusic)
+		v1.POST("/fingerprint/batch", musicHandler.BatchFingerprint)
+
+		// 重复曲目
+		v1.GET("/duplicates", musicHandler.FindDuplicates)
+		v1.POST("/duplicates/resolve", musicHandler.ResolveDuplicates)
+
+		// 标签修改记录
+		v1.GET("/music/:id/history", musicHandler.GetMusicHistory)
+		v1.GET("/history", musicHandler.ListTagHistory)
+		v1.POST("/history/:id/revert", musicHandler.RevertTagChange)
+		v1.POST("/history/batches/:batch_id/revert", musicHandler.RevertTagBatch)
+
+		// 标签规范化
+		v1.GET("/normalize/rules", musicHandler.GetNormalizeRules)
+		v1.POST("/normalize", musicHandler.NormalizeTags)
+
+		// 从路径提取标签
+		v1.POST("/path-patterns/test", musicHandler.TestPathPatterns)
+		v1.POST("/music/fill-from-path", musicHandler.FillTagsFromPath)
+
+		// 按模板重命名
+		v1.POST("/rename/preview", musicHandler.PreviewRename)
+		v1.POST("/rename/apply", musicHandler.ApplyRename)
+		v1.GET("/rename/batches", musicHandler.ListRenameBatches)
+		v1.GET("/rename/batches/:batch_id", musicHandler.GetRenameBatch)
+		v1.POST("/rename/batches/:batch_id/undo", musicHandler.UndoRename)
+
+		// 专辑一致性检查与编辑
+		v1.GET("/albums/check", musicHandler.CheckAlbums)
+		v1.GET("/albums/tracks", musicHandler.GetAlbumTracks)
+		v1.POST("/albums/fix", musicHandler.FixAlbum)
+		v1.PUT("/albums", musicHandler.EditAlbum)
+
+		// 音乐库健康报告
+		v1.GET("/library/health", musicHandler.HealthReport)
+		v1.GET("/library/health/tracks", musicHandler.HealthTracks)
+		v1.GET("/library/health/orphans", musicHandler.HealthOrphans)
+
+		// MP3 完整性检查
+		v1.GET("/integrity", musicHandler.ListIntegrity)
+		v1.POST("/integrity/validate", musicHandler.BatchValidate)
+		v1.POST("/music/:id/validate", musicHandler.ValidateMusic)
+
+		// 响度分析 (ReplayGain 2.0)
+		v1.POST("/loudness/analyze", musicHandler.AnalyzeLoudness)
 
 		// 统计信息
 		v1.GET("/statistics", musicHandler.Statistics)
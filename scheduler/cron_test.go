@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		expr, from, want string
+	}{
+		// 单个取值和 *
+		{"0 3 * * *", "2026-10-19 02:59", "2026-10-19 03:00"},
+		{"0 3 * * *", "2026-10-19 03:00", "2026-10-20 03:00"},
+		{"* * * * *", "2026-10-19 10:15", "2026-10-19 10:16"},
+		// 列表、范围和步长
+		{"15,45 * * * *", "2026-10-19 10:20", "2026-10-19 10:45"},
+		{"0 9-17 * * *", "2026-10-19 17:30", "2026-10-20 09:00"},
+		{"*/20 * * * *", "2026-10-19 10:41", "2026-10-19 11:00"},
+		{"5/15 * * * *", "2026-10-19 10:21", "2026-10-19 10:35"},
+		{"0 0-12/6 * * *", "2026-10-19 07:00", "2026-10-19 12:00"},
+		// 月份和星期的名称，周日可以写作 0 或 7
+		{"0 4 * * sun", "2026-10-19 00:00", "2026-10-25 04:00"},
+		{"0 4 * * 7", "2026-10-19 00:00", "2026-10-25 04:00"},
+		{"0 4 * * MON-FRI", "2026-10-24 00:00", "2026-10-26 04:00"},
+		{"0 0 1 jan,jul *", "2026-10-19 00:00", "2027-01-01 00:00"},
+		// 日和周都受限时满足其一即可
+		{"0 0 1 * 1", "2026-10-19 00:00", "2026-10-26 00:00"},
+		{"0 0 20 * 0", "2026-10-19 00:00", "2026-10-20 00:00"},
+		// 简写
+		{"@daily", "2026-10-19 10:00", "2026-10-20 00:00"},
+		{"@midnight", "2026-10-19 10:00", "2026-10-20 00:00"},
+		{"@weekly", "2026-10-19 10:00", "2026-10-25 00:00"},
+		{"@hourly", "2026-10-19 10:00", "2026-10-19 11:00"},
+		{"@monthly", "2026-10-19 10:00", "2026-11-01 00:00"},
+		{"@yearly", "2026-10-19 10:00", "2027-01-01 00:00"},
+		// 跨月、跨年和闰年
+		{"0 0 31 * *", "2026-10-31 12:00", "2026-12-31 00:00"},
+		{"30 23 * * *", "2026-12-31 23:45", "2027-01-01 23:30"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q from %s: got %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := c.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Feb 30 should never run, got %s", next)
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name, expr string
+		from, want time.Time
+	}{
+		// 2026-03-08 02:00 跳到 03:00，当天没有 02:30
+		{"spring forward skips missing hour", "30 2 * * *",
+			time.Date(2026, 3, 7, 12, 0, 0, 0, loc), time.Date(2026, 3, 9, 2, 30, 0, 0, loc)},
+		{"spring forward daily", "0 3 * * *",
+			time.Date(2026, 3, 7, 12, 0, 0, 0, loc), time.Date(2026, 3, 8, 3, 0, 0, 0, loc)},
+		// 2026-11-01 02:00 回到 01:00，01:30 只触发一次
+		{"fall back daily", "0 3 * * *",
+			time.Date(2026, 10, 31, 12, 0, 0, 0, loc), time.Date(2026, 11, 1, 3, 0, 0, 0, loc)},
+		{"fall back next day", "30 1 * * *",
+			time.Date(2026, 11, 1, 1, 45, 0, 0, loc), time.Date(2026, 11, 2, 1, 30, 0, 0, loc)},
+	}
+	// 圣保罗 2018-11-04 的 00:00 不存在，时钟直接跳到 01:00
+	if sp, err := time.LoadLocation("America/Sao_Paulo"); err == nil {
+		tests = append(tests, struct {
+			name, expr string
+			from, want time.Time
+		}{"missing midnight", "0 0 * * *",
+			time.Date(2018, 11, 3, 12, 0, 0, 0, sp), time.Date(2018, 11, 5, 0, 0, 0, 0, sp)})
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := c.Next(tt.from)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		if got.Location() != tt.from.Location() {
+			t.Errorf("%s: location %s, want %s", tt.name, got.Location(), tt.from.Location())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"go-music-tag/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tickInterval 检查到期任务的间隔，cron 精确到分钟
+const tickInterval = 20 * time.Second
+
+// ErrBusy 有冲突的任务 (手动扫描或批量任务) 正在运行，本次执行跳过
+var ErrBusy = errors.New("another task is running")
+
+// ErrAlreadyQueued 同一定时任务正在运行或等待运行
+var ErrAlreadyQueued = errors.New("schedule is already running or queued")
+
+// Task 定时任务的执行函数，同步执行完毕后返回结果摘要
+type Task func() (string, error)
+
+// Scheduler 按 cron 表达式触发数据库中的定时任务。所有任务由同一个 worker 依次执行，
+// 定时任务之间不会重叠；与手动任务的冲突由 Task 自己检查并返回 ErrBusy
+type Scheduler struct {
+	db    *gorm.DB
+	tasks map[string]Task
+
+	mu      sync.Mutex
+	pending map[uint]bool // 正在运行或排队中的任务
+	current uint          // 正在运行的任务
+	queue   chan uint
+	started bool
+}
+
+// New 创建调度器，tasks 为任务名到执行函数的映射
+func New(db *gorm.DB, tasks map[string]Task) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		tasks:   tasks,
+		pending: make(map[uint]bool),
+		queue:   make(chan uint, 64),
+	}
+}
+
+// HasTask 是否支持该任务
+func (s *Scheduler) HasTask(name string) bool {
+	_, ok := s.tasks[name]
+	return ok
+}
+
+// Start 重新计算所有任务的下次运行时间 (停机期间错过的不补跑)，然后开始调度，重复调用无效
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	var schedules []models.Schedule
+	if err := s.db.Find(&schedules).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range schedules {
+		if err := s.Reschedule(&schedules[i], now); err != nil {
+			log.Printf("[Scheduler] ⚠️ %s: %v", schedules[i].Name, err)
+		}
+	}
+
+	go s.loop()
+	go s.worker()
+	log.Printf("[Scheduler] Started with %d schedules", len(schedules))
+	return nil
+}
+
+// Reschedule 按当前的 cron 和启用状态更新下次运行时间，修改任务后调用
+func (s *Scheduler) Reschedule(schedule *models.Schedule, from time.Time) error {
+	var next *time.Time
+	if schedule.Enabled {
+		c, err := ParseCron(schedule.Cron)
+		if err != nil {
+			return err
+		}
+		if t := c.Next(from); !t.IsZero() {
+			next = &t
+		}
+	}
+	schedule.NextRunAt = next
+	return s.db.Model(schedule).Update("next_run_at", next).Error
+}
+
+// Trigger 立即把任务加入执行队列，不影响下次定时运行的时间
+func (s *Scheduler) Trigger(id uint) error {
+	var schedule models.Schedule
+	if err := s.db.First(&schedule, id).Error; err != nil {
+		return err
+	}
+	if !s.HasTask(schedule.Task) {
+		return fmt.Errorf("unknown task: %s", schedule.Task)
+	}
+	return s.enqueue(id)
+}
+
+// State 任务当前是否正在运行或排队
+func (s *Scheduler) State(id uint) (running, queued bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running = s.current == id
+	return running, s.pending[id] && !running
+}
+
+func (s *Scheduler) enqueue(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[id] {
+		return ErrAlreadyQueued
+	}
+	select {
+	case s.queue <- id:
+		s.pending[id] = true
+		return nil
+	default:
+		return errors.New("schedule queue is full")
+	}
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.dispatchDue(now)
+	}
+}
+
+// dispatchDue 把到期的任务加入队列并推进下次运行时间；上一次还没结束的任务本次跳过
+func (s *Scheduler) dispatchDue(now time.Time) {
+	var due []models.Schedule
+	if err := s.db.Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Printf("[Scheduler] ⚠️ Failed to load schedules: %v", err)
+		return
+	}
+	for i := range due {
+		schedule := &due[i]
+		if err := s.Reschedule(schedule, now); err != nil {
+			log.Printf("[Scheduler] ⚠️ %s: %v", schedule.Name, err)
+			continue
+		}
+		if err := s.enqueue(schedule.ID); errors.Is(err, ErrAlreadyQueued) {
+			log.Printf("[Scheduler] %s is still running, skipping this run", schedule.Name)
+		} else if err != nil {
+			log.Printf("[Scheduler] ⚠️ %s: %v", schedule.Name, err)
+		}
+	}
+}
+
+func (s *Scheduler) worker() {
+	for id := range s.queue {
+		s.mu.Lock()
+		s.current = id
+		s.mu.Unlock()
+
+		s.run(id)
+
+		s.mu.Lock()
+		s.current = 0
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}
+}
+
+// run 执行任务并记录结果
+func (s *Scheduler) run(id uint) {
+	var schedule models.Schedule
+	if err := s.db.First(&schedule, id).Error; err != nil {
+		return
+	}
+	task, ok := s.tasks[schedule.Task]
+	if !ok {
+		log.Printf("[Scheduler] ⚠️ %s: unknown task %s", schedule.Name, schedule.Task)
+		return
+	}
+
+	log.Printf("[Scheduler] ▶️ Running %s (%s)", schedule.Name, schedule.Task)
+	start := time.Now()
+	message, err := runTask(task)
+	status := models.ScheduleStatusSuccess
+	switch {
+	case errors.Is(err, ErrBusy):
+		status, message = models.ScheduleStatusSkipped, err.Error()
+	case err != nil:
+		status, message = models.ScheduleStatusFailed, err.Error()
+	}
+	if len(message) > 500 {
+		message = message[:500]
+	}
+	log.Printf("[Scheduler] %s finished: %s %s", schedule.Name, status, message)
+
+	s.db.Model(&schedule).Updates(map[string]interface{}{
+		"last_run_at":   start,
+		"last_status":   status,
+		"last_message":  message,
+		"last_duration": time.Since(start).Milliseconds(),
+	})
+}
+
+// runTask 执行任务，panic 时记为失败，避免 worker 退出
+func runTask(task Task) (message string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task()
+}
@@ -0,0 +1,210 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeyEnv 存放 base64 编码主密钥的环境变量，优先级高于密钥文件
+	KeyEnv = "MUSIC_TAG_SECRET_KEY"
+	// KeySize AES-256 密钥长度
+	KeySize = 32
+
+	// 密文前缀，用于区分旧版明文数据
+	encryptedPrefix = "enc:v1:"
+)
+
+var ErrNotInitialized = errors.New("secret: cipher not initialized")
+
+// Cipher 使用 AES-GCM 加解密敏感字段
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+var (
+	defaultCipher *Cipher
+	defaultMutex  sync.RWMutex
+)
+
+// NewCipher 根据 32 字节密钥创建加密器
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secret: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文，空字符串保持为空
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("secret: failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文；没有前缀的旧版明文原样返回
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if value == "" || !IsEncrypted(value) {
+		return value, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("secret: invalid ciphertext encoding: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", fmt.Errorf("secret: ciphertext too short")
+	}
+	plain, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("secret: decryption failed (wrong key?): %w", err)
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted 判断值是否为本包生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// GenerateKey 生成随机主密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("secret: failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// EncodeKey 将密钥编码为 base64 文本
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodeKey 解析 base64 文本密钥
+func DecodeKey(text string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("secret: invalid key encoding: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secret: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// LoadKey 读取主密钥：优先环境变量，其次密钥文件；文件不存在时自动生成
+func LoadKey(keyFile string) ([]byte, error) {
+	if env := os.Getenv(KeyEnv); env != "" {
+		return DecodeKey(env)
+	}
+
+	if keyFile == "" {
+		return nil, fmt.Errorf("secret: no key in $%s and no key file configured", KeyEnv)
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		return DecodeKey(string(data))
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("secret: failed to read key file: %w", err)
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteKeyFile(keyFile, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// WriteKeyFile 以 0600 权限原子写入密钥文件
+func WriteKeyFile(keyFile string, key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return fmt.Errorf("secret: failed to create key directory: %w", err)
+	}
+	tmp := keyFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(EncodeKey(key)+"\n"), 0600); err != nil {
+		return fmt.Errorf("secret: failed to write key file: %w", err)
+	}
+	if err := os.Rename(tmp, keyFile); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("secret: failed to replace key file: %w", err)
+	}
+	return nil
+}
+
+// Init 加载主密钥并设置全局加密器
+func Init(keyFile string) error {
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		return err
+	}
+	SetDefault(c)
+	return nil
+}
+
+// SetDefault 替换全局加密器 (密钥轮换后使用)
+func SetDefault(c *Cipher) {
+	defaultMutex.Lock()
+	defaultCipher = c
+	defaultMutex.Unlock()
+}
+
+// Default 返回全局加密器
+func Default() (*Cipher, error) {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	if defaultCipher == nil {
+		return nil, ErrNotInitialized
+	}
+	return defaultCipher, nil
+}
+
+// Encrypt 使用全局加密器加密
+func Encrypt(plain string) (string, error) {
+	c, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return c.Encrypt(plain)
+}
+
+// Decrypt 使用全局加密器解密
+func Decrypt(value string) (string, error) {
+	c, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return c.Decrypt(value)
+}
@@ -0,0 +1,672 @@
+// Package tagwriter 提供最小化的 ID3v2 标签写入：保留原有帧，只替换需要修改的帧。
+package tagwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go-music-tag/parser"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrUnsupportedVersion ID3v2.2 使用 3 字节帧 ID，不支持原地修改
+var ErrUnsupportedVersion = errors.New("unsupported ID3v2 version")
+
+// ErrUnsupportedFrame 压缩或加密的帧无法解码，重写标签会丢失这些帧
+var ErrUnsupportedFrame = errors.New("compressed or encrypted frame")
+
+// defaultPadding 写入时预留的填充字节，便于后续原地修改
+const defaultPadding = 1024
+
+// 文本编码
+const (
+	encodingISO88591 = 0x00
+	encodingUTF16    = 0x01
+	encodingUTF8     = 0x03
+)
+
+// Frame 一个 ID3v2 帧，Body 为去除帧头后的原始内容
+type Frame struct {
+	ID   string
+	Body []byte
+}
+
+// Tag ID3v2.3 或 ID3v2.4 标签
+type Tag struct {
+	Version byte // 3 或 4
+	Frames  []Frame
+}
+
+// standardFrames 统一字段名到 ID3v2 文本帧的映射，year 按版本区分
+var standardFrames = map[string]string{
+	"title":        "TIT2",
+	"artist":       "TPE1",
+	"album":        "TALB",
+	"album_artist": "TPE2",
+	"composer":     "TCOM",
+	"genre":        "TCON",
+	"track":        "TRCK",
+	"disc":         "TPOS",
+	"bpm":          "TBPM",
+	"isrc":         "TSRC",
+	"label":        "TPUB",
+	"initial_key":  "TKEY",
+}
+
+// Read 解析文件中的 ID3v2 标签，没有标签时返回空的 ID3v2.4 标签。
+// 以开头的第一个标签为准，其后的 ID3v2 标签 (含追加在末尾的) 与 ID3v1 中该标签没有的字段
+// 合并进来，Apply 去掉这些标签时不会丢失字段
+func Read(data []byte) (*Tag, error) {
+	layout := parser.LocateTags(data)
+	if len(layout.Leading) == 0 && len(data) >= 10 && string(data[:3]) == "ID3" {
+		return nil, fmt.Errorf("invalid ID3v2 tag header")
+	}
+
+	var tag *Tag
+	spans := append(append([]parser.TagSpan(nil), layout.Leading...), layout.Trailing...)
+	for _, span := range spans {
+		if span.Kind != parser.TagID3v2 {
+			continue
+		}
+		t, err := parseTag(data[span.Start:span.End])
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			tag = t
+			continue
+		}
+		if err := tag.merge(t); err != nil {
+			return nil, err
+		}
+	}
+	if tag == nil {
+		tag = &Tag{Version: 4}
+	}
+
+	for _, span := range layout.Trailing {
+		if span.Kind == parser.TagID3v1 {
+			tag.mergeID3v1(data[span.Start:span.End])
+		}
+	}
+	return tag, nil
+}
+
+// parseTag 解析以 ID3v2 头部开始的一个标签
+func parseTag(data []byte) (*Tag, error) {
+	version := data[3]
+	if version == 2 {
+		return nil, ErrUnsupportedVersion
+	}
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("%w: 2.%d", ErrUnsupportedVersion, version)
+	}
+
+	flags := data[5]
+	size := int(syncsafe(data[6:10]))
+	if 10+size > len(data) {
+		return nil, fmt.Errorf("ID3v2 tag size %d exceeds file size", size)
+	}
+	body := data[10 : 10+size]
+
+	// ID3v2.3 的非同步化作用于整个标签
+	if version == 3 && flags&0x80 != 0 {
+		body = removeUnsync(body)
+	}
+
+	// 跳过扩展头
+	if flags&0x40 != 0 && len(body) >= 4 {
+		var extSize int
+		if version == 4 {
+			extSize = int(syncsafe(body[:4]))
+		} else {
+			extSize = int(binary.BigEndian.Uint32(body[:4])) + 4
+		}
+		if extSize > len(body) {
+			return nil, fmt.Errorf("invalid extended header size")
+		}
+		body = body[extSize:]
+	}
+
+	tag := &Tag{Version: version}
+	for len(body) >= 10 {
+		if body[0] == 0 {
+			break // 填充区
+		}
+		id := string(body[:4])
+		var frameSize int
+		if version == 4 {
+			frameSize = int(syncsafe(body[4:8]))
+		} else {
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		formatFlags := body[9]
+		if frameSize < 0 || 10+frameSize > len(body) {
+			return nil, fmt.Errorf("frame %s size %d exceeds tag size", id, frameSize)
+		}
+		frameBody := body[10 : 10+frameSize]
+		body = body[10+frameSize:]
+
+		frameBody, ok := decodeFrameFormat(version, formatFlags, frameBody)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFrame, id)
+		}
+		tag.Frames = append(tag.Frames, Frame{ID: id, Body: append([]byte(nil), frameBody...)})
+	}
+	return tag, nil
+}
+
+// decodeFrameFormat 处理帧格式标志，返回可直接重写的帧内容
+func decodeFrameFormat(version, flags byte, body []byte) ([]byte, bool) {
+	if version == 3 {
+		if flags&0xC0 != 0 { // 压缩、加密
+			return nil, false
+		}
+		if flags&0x20 != 0 && len(body) > 0 { // 分组标识
+			body = body[1:]
+		}
+		return body, true
+	}
+
+	if flags&0x0C != 0 { // 压缩、加密
+		return nil, false
+	}
+	if flags&0x40 != 0 && len(body) > 0 {
+		body = body[1:]
+	}
+	if flags&0x01 != 0 && len(body) >= 4 { // 数据长度指示
+		body = body[4:]
+	}
+	if flags&0x02 != 0 {
+		body = removeUnsync(body)
+	}
+	return body, true
+}
+
+// Bytes 序列化标签，帧标志全部清零，末尾附加填充
+func (t *Tag) Bytes() []byte {
+	var frames bytes.Buffer
+	for _, f := range t.Frames {
+		frames.WriteString(f.ID)
+		if t.Version == 4 {
+			frames.Write(encodeSyncsafe(uint32(len(f.Body))))
+		} else {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(len(f.Body)))
+			frames.Write(size[:])
+		}
+		frames.Write([]byte{0, 0})
+		frames.Write(f.Body)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("ID3")
+	out.Write([]byte{t.Version, 0, 0})
+	out.Write(encodeSyncsafe(uint32(frames.Len() + defaultPadding)))
+	out.Write(frames.Bytes())
+	out.Write(make([]byte, defaultPadding))
+	return out.Bytes()
+}
+
+// Apply 用 Read 得到的标签替换文件中的全部 ID3v2 标签 (包括追加在末尾的)，并去掉 ID3v1 标签，
+// 它们的字段已由 Read 合并。APEv2 与 Lyrics3v2 标签保留；Lyrics3v2 依赖紧随其后的 ID3v1，此时 ID3v1 也保留
+func Apply(data []byte, t *Tag) []byte {
+	layout := parser.LocateTags(data)
+	tag := t.Bytes()
+	out := make([]byte, 0, len(tag)+len(data)-layout.AudioStart)
+	out = append(out, tag...)
+	out = append(out, data[layout.AudioStart:layout.AudioEnd]...)
+
+	lyrics3 := false
+	for _, span := range layout.Trailing {
+		switch span.Kind {
+		case parser.TagAPE:
+		case parser.TagLyrics3:
+			lyrics3 = true
+		case parser.TagID3v1:
+			if !lyrics3 {
+				continue
+			}
+		default:
+			continue
+		}
+		out = append(out, data[span.Start:span.End]...)
+	}
+	return out
+}
+
+// merge 加入 other 中有而 t 中没有的帧，版本不同时先转换帧格式
+func (t *Tag) merge(other *Tag) error {
+	for _, f := range other.Frames {
+		f, ok := convertFrame(f, other.Version, t.Version)
+		if !ok {
+			return fmt.Errorf("cannot convert frame %s from ID3v2.%d to ID3v2.%d", f.ID, other.Version, t.Version)
+		}
+		key := frameKey(f)
+		found := false
+		for i := range t.Frames {
+			if frameKey(t.Frames[i]) == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Frames = append(t.Frames, f)
+		}
+	}
+	return nil
+}
+
+// frameKey 判断两个帧是否描述同一项：TXXX/WXXX 按描述，COMM/USLT 按语言和描述，
+// APIC 按图片类型，其余文本和链接帧按 ID，未知的帧按完整内容
+func frameKey(f Frame) string {
+	switch {
+	case len(f.Body) == 0:
+		return f.ID
+	case f.ID == "TXXX" || f.ID == "WXXX":
+		desc, _ := splitText(f.Body[0], f.Body[1:])
+		return f.ID + "\x00" + strings.ToLower(desc)
+	case (f.ID == "COMM" || f.ID == "USLT") && len(f.Body) >= 4:
+		desc, _ := splitText(f.Body[0], f.Body[4:])
+		return f.ID + "\x00" + string(f.Body[1:4]) + "\x00" + desc
+	case f.ID == "APIC":
+		return f.ID + "\x00" + string(pictureTypeOf(f.Body))
+	case f.ID[0] == 'T' || f.ID[0] == 'W':
+		return f.ID
+	}
+	return f.ID + "\x00" + string(f.Body)
+}
+
+// convertFrame 转换不同版本之间的帧：年份帧在 TYER 与 TDRC 之间转换，
+// 写入 ID3v2.3 时把 ID3v2.4 的 UTF-8 和 UTF-16BE 文本改为带 BOM 的 UTF-16；无法转换时 ok 为 false
+func convertFrame(f Frame, from, to byte) (Frame, bool) {
+	if from == to || len(f.Body) == 0 {
+		return f, true
+	}
+	switch {
+	case to == 4 && f.ID == "TYER":
+		f.ID = "TDRC"
+		return f, true
+	case to == 3 && f.ID == "TDRC":
+		year, _ := splitText(f.Body[0], f.Body[1:])
+		if len(year) > 4 {
+			year = year[:4]
+		}
+		return Frame{ID: "TYER", Body: append([]byte{encodingISO88591}, year...)}, true
+	}
+
+	enc := f.Body[0]
+	if to != 3 || (enc != encodingUTF8 && enc != 0x02) {
+		return f, true
+	}
+
+	// prefix 为编码字节之后、第一段文本之前的内容，tail 为最后一段文本之后的二进制数据
+	var prefix, tail []byte
+	var texts []string
+	rest := f.Body[1:]
+	switch {
+	case f.ID == "COMM" || f.ID == "USLT":
+		if len(rest) < 3 {
+			return f, false
+		}
+		prefix = rest[:3]
+		desc, text := splitText(enc, rest[3:])
+		texts = []string{desc, decodeText(enc, text)}
+	case f.ID == "APIC":
+		i := bytes.IndexByte(rest, 0)
+		if i < 0 || i+2 > len(rest) {
+			return f, false
+		}
+		prefix = rest[:i+2] // MIME、结束符和图片类型
+		var desc string
+		desc, tail = splitText(enc, rest[i+2:])
+		texts = []string{desc}
+	case f.ID == "TXXX" || f.ID == "WXXX":
+		desc, value := splitText(enc, rest)
+		if f.ID == "WXXX" {
+			texts, tail = []string{desc}, value // 链接为 ISO-8859-1
+		} else {
+			texts = []string{desc, decodeText(enc, value)}
+		}
+	case f.ID[0] == 'T':
+		// ID3v2.4 的多值以结束符分隔，ID3v2.3 使用 "/"
+		var values []string
+		for len(rest) > 0 {
+			var v string
+			v, rest = splitText(enc, rest)
+			values = append(values, v)
+		}
+		texts = []string{strings.Join(values, "/")}
+	default:
+		return f, false
+	}
+
+	body := append([]byte{encodingUTF16}, prefix...)
+	for i, text := range texts {
+		if i > 0 {
+			body = append(body, terminator(encodingUTF16)...)
+		}
+		body = append(body, encodeText(encodingUTF16, text)...)
+	}
+	if tail != nil {
+		body = append(body, terminator(encodingUTF16)...)
+		body = append(body, tail...)
+	}
+	return Frame{ID: f.ID, Body: body}, true
+}
+
+// mergeID3v1 加入 ID3v1 中有而 t 中没有的字段。文本按原始字节写入 ISO-8859-1 帧，
+// 本地编码 (如 GBK) 与读取 ID3v1 时一样可以由编码修复识别
+func (t *Tag) mergeID3v1(v1 []byte) {
+	field := func(b []byte) []byte {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return bytes.TrimRight(b, " ")
+	}
+	add := func(id string, value []byte) {
+		if len(value) == 0 || t.Frame(id) != nil {
+			return
+		}
+		t.Frames = append(t.Frames, Frame{ID: id, Body: append([]byte{encodingISO88591}, value...)})
+	}
+
+	add("TIT2", field(v1[3:33]))
+	add("TPE1", field(v1[33:63]))
+	add("TALB", field(v1[63:93]))
+	if t.Version == 4 {
+		add("TDRC", field(v1[93:97]))
+	} else {
+		add("TYER", field(v1[93:97]))
+	}
+
+	// ID3v1.1 在注释的最后两个字节中存放音轨号
+	comment := v1[97:127]
+	if v1[125] == 0 && v1[126] != 0 {
+		comment = v1[97:125]
+		add("TRCK", []byte(strconv.Itoa(int(v1[126]))))
+	}
+	if c := field(comment); len(c) > 0 && !t.hasComment() {
+		body := append([]byte{encodingISO88591}, "XXX"...)
+		body = append(body, 0) // 空描述
+		t.Frames = append(t.Frames, Frame{ID: "COMM", Body: append(body, c...)})
+	}
+
+	// 流派写为 ID3v1 编号的引用形式，两个版本的读取器都能识别
+	if genre := v1[127]; genre != 0xFF {
+		add("TCON", []byte(fmt.Sprintf("(%d)", genre)))
+	}
+}
+
+// hasComment 是否已有空描述的 COMM 帧
+func (t *Tag) hasComment() bool {
+	for _, f := range t.Frames {
+		if f.ID != "COMM" {
+			continue
+		}
+		if len(f.Body) < 4 {
+			return true
+		}
+		if desc, _ := splitText(f.Body[0], f.Body[4:]); desc == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Frame 返回指定 ID 的第一个帧
+func (t *Tag) Frame(id string) *Frame {
+	for i := range t.Frames {
+		if t.Frames[i].ID == id {
+			return &t.Frames[i]
+		}
+	}
+	return nil
+}
+
+// RemoveFrames 删除所有满足条件的帧
+func (t *Tag) RemoveFrames(match func(f *Frame) bool) {
+	kept := t.Frames[:0]
+	for i := range t.Frames {
+		if !match(&t.Frames[i]) {
+			kept = append(kept, t.Frames[i])
+		}
+	}
+	t.Frames = kept
+}
+
+// setFrame 替换第一个满足条件的帧并删除其余同类帧，body 为 nil 时全部删除
+func (t *Tag) setFrame(id string, match func(f *Frame) bool, body []byte) {
+	replaced := false
+	kept := t.Frames[:0]
+	for _, f := range t.Frames {
+		if f.ID == id && match(&f) {
+			if replaced || body == nil {
+				continue
+			}
+			f.Body = body
+			replaced = true
+		}
+		kept = append(kept, f)
+	}
+	t.Frames = kept
+	if !replaced && body != nil {
+		t.Frames = append(t.Frames, Frame{ID: id, Body: body})
+	}
+}
+
+// SetText 设置文本帧，value 为空时删除该帧
+func (t *Tag) SetText(id, value string) {
+	var body []byte
+	if value != "" {
+		enc := t.textEncoding(value)
+		body = append([]byte{enc}, encodeText(enc, value)...)
+	}
+	t.setFrame(id, func(*Frame) bool { return true }, body)
+}
+
+// Text 读取文本帧内容
+func (t *Tag) Text(id string) string {
+	f := t.Frame(id)
+	if f == nil || len(f.Body) == 0 {
+		return ""
+	}
+	text, _ := splitText(f.Body[0], f.Body[1:])
+	return text
+}
+
+// SetField 按统一字段名设置标签，支持 standardFrames 中的字段和 year
+func (t *Tag) SetField(field, value string) error {
+	if field == "year" {
+		if t.Version == 4 {
+			t.SetText("TDRC", value)
+		} else {
+			t.SetText("TYER", value)
+		}
+		return nil
+	}
+	id, ok := standardFrames[field]
+	if !ok {
+		return fmt.Errorf("unsupported field: %s", field)
+	}
+	t.SetText(id, value)
+	return nil
+}
+
+// SetUserText 设置 TXXX 自定义文本帧，描述不区分大小写
+func (t *Tag) SetUserText(desc, value string) {
+	var body []byte
+	if value != "" {
+		enc := t.textEncoding(desc + value)
+		body = append([]byte{enc}, encodeText(enc, desc)...)
+		body = append(body, terminator(enc)...)
+		body = append(body, encodeText(enc, value)...)
+	}
+	t.setFrame("TXXX", func(f *Frame) bool {
+		if len(f.Body) == 0 {
+			return false
+		}
+		d, _ := splitText(f.Body[0], f.Body[1:])
+		return bytes.EqualFold([]byte(d), []byte(desc))
+	}, body)
+}
+
+// SetLyrics 设置 USLT 非同步歌词，text 为空时删除
+func (t *Tag) SetLyrics(lang, text string) {
+	t.setLangText("USLT", lang, text)
+}
+
+// SetComment 设置 COMM 注释，text 为空时删除
+func (t *Tag) SetComment(lang, text string) {
+	t.setLangText("COMM", lang, text)
+}
+
+func (t *Tag) setLangText(id, lang, text string) {
+	if len(lang) != 3 {
+		lang = "XXX"
+	}
+	var body []byte
+	if text != "" {
+		enc := t.textEncoding(text)
+		body = append([]byte{enc}, lang...)
+		body = append(body, terminator(enc)...) // 空描述
+		body = append(body, encodeText(enc, text)...)
+	}
+	// 只替换空描述的帧，保留 iTunes 等工具写入的带描述帧
+	t.setFrame(id, func(f *Frame) bool {
+		if len(f.Body) < 4 {
+			return true
+		}
+		desc, _ := splitText(f.Body[0], f.Body[4:])
+		return desc == ""
+	}, body)
+}
+
+// SetPicture 设置 APIC 图片，同一图片类型只保留一张，data 为空时删除
+func (t *Tag) SetPicture(mime string, pictureType byte, data []byte) {
+	var body []byte
+	if len(data) > 0 {
+		body = append([]byte{encodingISO88591}, mime...)
+		body = append(body, 0, pictureType, 0) // MIME 结束符、图片类型、空描述
+		body = append(body, data...)
+	}
+	t.setFrame("APIC", func(f *Frame) bool {
+		return pictureTypeOf(f.Body) == pictureType
+	}, body)
+}
+
+// pictureTypeOf 解析 APIC 帧的图片类型
+func pictureTypeOf(body []byte) byte {
+	if len(body) < 2 {
+		return 0xFF
+	}
+	i := bytes.IndexByte(body[1:], 0)
+	if i < 0 || 1+i+1 >= len(body) {
+		return 0xFF
+	}
+	return body[1+i+1]
+}
+
+// textEncoding ID3v2.4 使用 UTF-8，ID3v2.3 非 ASCII 文本使用带 BOM 的 UTF-16
+func (t *Tag) textEncoding(s string) byte {
+	if t.Version == 4 {
+		return encodingUTF8
+	}
+	for _, r := range s {
+		if r > 0x7F {
+			return encodingUTF16
+		}
+	}
+	return encodingISO88591
+}
+
+func encodeText(enc byte, s string) []byte {
+	if enc != encodingUTF16 {
+		return []byte(s)
+	}
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 2, 2+len(units)*2)
+	out[0], out[1] = 0xFF, 0xFE
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func terminator(enc byte) []byte {
+	if enc == encodingUTF16 || enc == 0x02 {
+		return []byte{0, 0}
+	}
+	return []byte{0}
+}
+
+// splitText 解码到第一个结束符为止的文本，返回文本和剩余字节
+func splitText(enc byte, b []byte) (string, []byte) {
+	var raw, rest []byte
+	if enc == encodingUTF16 || enc == 0x02 {
+		raw, rest = b, nil
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				raw, rest = b[:i], b[i+2:]
+				break
+			}
+		}
+	} else if i := bytes.IndexByte(b, 0); i >= 0 {
+		raw, rest = b[:i], b[i+1:]
+	} else {
+		raw = b
+	}
+	return decodeText(enc, raw), rest
+}
+
+func decodeText(enc byte, b []byte) string {
+	switch enc {
+	case encodingUTF16, 0x02:
+		bigEndian := enc == 0x02
+		if len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE {
+			b, bigEndian = b[2:], false
+		} else if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+			b, bigEndian = b[2:], true
+		}
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+			} else {
+				units = append(units, uint16(b[i+1])<<8|uint16(b[i]))
+			}
+		}
+		return string(utf16.Decode(units))
+	case encodingUTF8:
+		return string(b)
+	default:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7F)<<21 | uint32(b[1]&0x7F)<<14 | uint32(b[2]&0x7F)<<7 | uint32(b[3]&0x7F)
+}
+
+func encodeSyncsafe(n uint32) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// removeUnsync 还原非同步化：去掉 0xFF 之后插入的 0x00
+func removeUnsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,339 @@
+package tagwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"go-music-tag/parser"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// testAudio 20 个 128 kbps 的 MPEG-1 Layer III 帧
+var testAudio = func() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x64})
+	for i := 4; i < len(frame); i++ {
+		frame[i] = 0x55
+	}
+	return bytes.Repeat(frame, 20)
+}()
+
+// rawFrame 构造帧头和内容，flags 为两字节帧标志
+func rawFrame(version byte, id string, flags uint16, body []byte) []byte {
+	b := []byte(id)
+	if version == 4 {
+		b = append(b, encodeSyncsafe(uint32(len(body)))...)
+	} else {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	}
+	b = binary.BigEndian.AppendUint16(b, flags)
+	return append(b, body...)
+}
+
+// textFrame ISO-8859-1 文本帧
+func textFrame(version byte, id, text string) []byte {
+	return rawFrame(version, id, 0, append([]byte{encodingISO88591}, text...))
+}
+
+// rawTag 构造 ID3v2 标签，body 为扩展头和帧
+func rawTag(version, flags byte, body []byte) []byte {
+	b := []byte{'I', 'D', '3', version, 0, flags}
+	b = append(b, encodeSyncsafe(uint32(len(body)))...)
+	return append(b, body...)
+}
+
+// addUnsync 非同步化：在每个 0xFF 之后插入 0x00
+func addUnsync(b []byte) []byte {
+	var out []byte
+	for _, c := range b {
+		out = append(out, c)
+		if c == 0xFF {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// id3v1 构造 ID3v1.1 标签
+func id3v1(title, artist, album, year, comment string, track, genre byte) []byte {
+	b := make([]byte, 128)
+	copy(b, "TAG")
+	copy(b[3:33], title)
+	copy(b[33:63], artist)
+	copy(b[63:93], album)
+	copy(b[93:97], year)
+	copy(b[97:125], comment)
+	b[126] = track
+	b[127] = genre
+	return b
+}
+
+// apeTag 带头部的最小 APEv2 标签
+func apeTag() []byte {
+	item := append(binary.LittleEndian.AppendUint32(nil, 3), 0, 0, 0, 0)
+	item = append(item, "Key\x00abc"...)
+	block := func(flags uint32) []byte {
+		b := []byte("APETAGEX")
+		b = binary.LittleEndian.AppendUint32(b, 2000)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(item)+32))
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = binary.LittleEndian.AppendUint32(b, flags)
+		return append(b, make([]byte, 8)...)
+	}
+	out := block(1<<31 | 1<<29)
+	out = append(out, item...)
+	return append(out, block(1<<31)...)
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// rewrite 读取标签，应用 edit 后写回并重新读取
+func rewrite(t *testing.T, data []byte, edit func(*Tag)) ([]byte, *Tag) {
+	t.Helper()
+	tg, err := Read(data)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if edit != nil {
+		edit(tg)
+	}
+	out := Apply(data, tg)
+	again, err := Read(out)
+	if err != nil {
+		t.Fatalf("Read after Apply: %v", err)
+	}
+
+	layout := parser.LocateTags(out)
+	if !bytes.Equal(out[layout.AudioStart:layout.AudioEnd], testAudio) {
+		t.Fatal("audio data changed")
+	}
+	return out, again
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		version byte
+		want    map[string]string
+	}{
+		{
+			name: "v2.3",
+			data: join(rawTag(3, 0, join(
+				textFrame(3, "TIT2", "Old"),
+				textFrame(3, "TPE1", "Artist"),
+				textFrame(3, "TYER", "1999"),
+			)), testAudio),
+			version: 3,
+			want:    map[string]string{"TIT2": "新标题", "TPE1": "Artist", "TYER": "1999"},
+		},
+		{
+			name: "v2.4",
+			data: join(rawTag(4, 0, join(
+				textFrame(4, "TIT2", "Old"),
+				textFrame(4, "TDRC", "2001-05-01"),
+			)), testAudio),
+			version: 4,
+			want:    map[string]string{"TIT2": "新标题", "TDRC": "2001-05-01"},
+		},
+		{
+			name: "v2.3 unsynchronised tag",
+			data: join(rawTag(3, 0x80, addUnsync(join(
+				textFrame(3, "TIT2", "Old"),
+				textFrame(3, "TALB", "\xFFlbum"),
+			))), testAudio),
+			version: 3,
+			want:    map[string]string{"TIT2": "新标题", "TALB": "ÿlbum"},
+		},
+		{
+			name: "v2.4 unsynchronised frame with data length",
+			data: join(rawTag(4, 0, join(
+				textFrame(4, "TIT2", "Old"),
+				rawFrame(4, "TALB", 0x0003, join(encodeSyncsafe(6), addUnsync([]byte("\x00\xFFlbum")))),
+			)), testAudio),
+			version: 4,
+			want:    map[string]string{"TIT2": "新标题", "TALB": "ÿlbum"},
+		},
+		{
+			name: "v2.3 extended header",
+			data: join(rawTag(3, 0x40, join(
+				[]byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 0},
+				textFrame(3, "TIT2", "Old"),
+				textFrame(3, "TPE1", "Artist"),
+			)), testAudio),
+			version: 3,
+			want:    map[string]string{"TIT2": "新标题", "TPE1": "Artist"},
+		},
+		{
+			name: "v2.4 extended header",
+			data: join(rawTag(4, 0x40, join(
+				[]byte{0, 0, 0, 6, 1, 0},
+				textFrame(4, "TIT2", "Old"),
+				textFrame(4, "TPE1", "Artist"),
+			)), testAudio),
+			version: 4,
+			want:    map[string]string{"TIT2": "新标题", "TPE1": "Artist"},
+		},
+		{
+			name:    "ID3v1 only",
+			data:    join(testAudio, id3v1("Old", "V1 Artist", "V1 Album", "1987", "note", 7, 17)),
+			version: 4,
+			want:    map[string]string{"TIT2": "新标题", "TPE1": "V1 Artist", "TALB": "V1 Album", "TDRC": "1987", "TRCK": "7", "TCON": "(17)"},
+		},
+		{
+			name: "ID3v1 and v2 prefers v2",
+			data: join(rawTag(3, 0, join(
+				textFrame(3, "TIT2", "Old"),
+				textFrame(3, "TPE1", "V2 Artist"),
+			)), testAudio, id3v1("V1 Title", "V1 Artist", "", "1987", "", 3, 255)),
+			version: 3,
+			want:    map[string]string{"TIT2": "新标题", "TPE1": "V2 Artist", "TYER": "1987", "TRCK": "3", "TCON": ""},
+		},
+		{
+			name: "second leading tag",
+			data: join(
+				rawTag(3, 0, textFrame(3, "TIT2", "Old")),
+				rawTag(3, 0, join(textFrame(3, "TIT2", "Ignored"), textFrame(3, "TALB", "Second"))),
+				testAudio,
+			),
+			version: 3,
+			want:    map[string]string{"TIT2": "新标题", "TALB": "Second"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, got := rewrite(t, tt.data, func(tg *Tag) {
+				if err := tg.SetField("title", "新标题"); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if got.Version != tt.version {
+				t.Errorf("version = %d, want %d", got.Version, tt.version)
+			}
+			for id, want := range tt.want {
+				if text := got.Text(id); text != want {
+					t.Errorf("%s = %q, want %q", id, text, want)
+				}
+			}
+
+			layout := parser.LocateTags(out)
+			if len(layout.Leading) != 1 || len(layout.Trailing) != 0 {
+				t.Errorf("layout = %+v, want a single leading tag", layout)
+			}
+			if out[5] != 0 {
+				t.Errorf("header flags = %#x, want 0", out[5])
+			}
+		})
+	}
+}
+
+func TestID3v1CommentAndReadability(t *testing.T) {
+	data := join(testAudio, id3v1("Title", "Artist", "Album", "1987", "A comment", 7, 17))
+	out, got := rewrite(t, data, nil)
+	if !got.hasComment() {
+		t.Error("ID3v1 comment was not carried over")
+	}
+
+	m, err := tag.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("tag.ReadFrom: %v", err)
+	}
+	track, _ := m.Track()
+	if m.Format() != tag.ID3v2_4 || m.Title() != "Title" || m.Artist() != "Artist" || m.Album() != "Album" ||
+		m.Year() != 1987 || track != 7 || m.Genre() != "Rock" || m.Comment() != "A comment" {
+		t.Errorf("read back %s: %q %q %q %d %d %q %q", m.Format(), m.Title(), m.Artist(), m.Album(), m.Year(), track, m.Genre(), m.Comment())
+	}
+}
+
+func TestID3v1RawBytesKept(t *testing.T) {
+	// GBK 编码的 "晴天" 按原始字节写入 ISO-8859-1 帧，不被转换为其他编码
+	gbk := "\xC7\xE7\xCC\xEC"
+	_, got := rewrite(t, join(testAudio, id3v1(gbk, "", "", "", "", 0, 255)), nil)
+	f := got.Frame("TIT2")
+	if f == nil || !bytes.Equal(f.Body, append([]byte{encodingISO88591}, gbk...)) {
+		t.Errorf("TIT2 = %+v, want raw ISO-8859-1 bytes", f)
+	}
+}
+
+func TestAppendedTagMerged(t *testing.T) {
+	// 追加在末尾的 ID3v2.4 标签以 footer 结束
+	appended := rawTag(4, 0x10, join(
+		rawFrame(4, "TALB", 0, append([]byte{encodingUTF8}, "专辑"...)),
+		textFrame(4, "TDRC", "2005-03-01"),
+		textFrame(4, "TIT2", "Ignored"),
+	))
+	footer := append([]byte("3DI"), appended[3:10]...)
+	appended = append(appended, footer...)
+
+	data := join(rawTag(3, 0, textFrame(3, "TIT2", "Title")), testAudio, appended)
+	out, got := rewrite(t, data, nil)
+
+	if got.Text("TIT2") != "Title" || got.Text("TALB") != "专辑" || got.Text("TYER") != "2005" {
+		t.Errorf("merged tag: TIT2 %q, TALB %q, TYER %q", got.Text("TIT2"), got.Text("TALB"), got.Text("TYER"))
+	}
+	if f := got.Frame("TALB"); f == nil || f.Body[0] != encodingUTF16 {
+		t.Errorf("TALB should be converted to UTF-16 for ID3v2.3, got %+v", f)
+	}
+	if bytes.Contains(out, []byte("3DI")) {
+		t.Error("appended tag was not removed")
+	}
+}
+
+func TestTrailingTagsKept(t *testing.T) {
+	ape := apeTag()
+	v1 := id3v1("Title", "", "", "", "", 0, 255)
+
+	out, _ := rewrite(t, join(testAudio, ape, v1), nil)
+	if !bytes.HasSuffix(out, ape) {
+		t.Error("APEv2 tag was not kept at the end")
+	}
+
+	// Lyrics3v2 需要其后的 ID3v1
+	lyrics3 := []byte("LYRICSBEGININD00003110" + "000022LYRICS200")
+	out, _ = rewrite(t, join(testAudio, ape, lyrics3, v1), nil)
+	if !bytes.HasSuffix(out, join(ape, lyrics3, v1)) {
+		t.Error("Lyrics3v2 and the ID3v1 tag it depends on were not kept")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"v2.2", join(rawTag(2, 0, []byte("TT2\x00\x00\x04\x00Old")), testAudio), ErrUnsupportedVersion},
+		{"v2.3 compressed frame", join(rawTag(3, 0, rawFrame(3, "TIT2", 0x0080, []byte("xxxx"))), testAudio), ErrUnsupportedFrame},
+		{"v2.4 encrypted frame", join(rawTag(4, 0, rawFrame(4, "TIT2", 0x0004, []byte("xxxx"))), testAudio), ErrUnsupportedFrame},
+		{"compressed frame in second tag", join(
+			rawTag(3, 0, textFrame(3, "TIT2", "Title")),
+			rawTag(3, 0, rawFrame(3, "TALB", 0x0080, []byte("xxxx"))),
+			testAudio,
+		), ErrUnsupportedFrame},
+	}
+	for _, tt := range tests {
+		if _, err := Read(tt.data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	if _, err := Read(join([]byte("ID3\x03\x00\x00\x7F\x7F\x7F\x7F"), testAudio)); err == nil {
+		t.Error("expected error for a tag larger than the file")
+	}
+}
+
+func TestNoTag(t *testing.T) {
+	out, got := rewrite(t, testAudio, func(tg *Tag) {
+		tg.SetComment("eng", "hello")
+	})
+	if got.Version != 4 || !got.hasComment() {
+		t.Errorf("tag = %+v", got)
+	}
+	if !bytes.HasSuffix(out, testAudio) {
+		t.Error("audio should follow the new tag")
+	}
+}
@@ -215,6 +215,43 @@ func (c *Client) buildURL(filePath string) (string, error) {
 	return fmt.Sprintf("%s/%s", strings.TrimSuffix(c.baseURL, "/"), encodedPath), nil
 }
 
+// RootPath 返回音乐库根目录
+func (c *Client) RootPath() string {
+	return c.rootPath
+}
+
+// Exists 判断远程文件或目录是否存在
+func (c *Client) Exists(filePath string) bool {
+	_, err := c.listClient.Stat(filePath)
+	return err == nil
+}
+
+// MkdirAll 递归创建远程目录 (MKCOL)
+func (c *Client) MkdirAll(dirPath string) error {
+	return c.listClient.MkdirAll(dirPath, 0755)
+}
+
+// Move 移动或重命名远程文件 (MOVE)，目标目录不存在时自动创建，目标已存在时返回错误
+func (c *Client) Move(oldPath, newPath string) error {
+	if err := c.MkdirAll(path.Dir(newPath)); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", path.Dir(newPath), err)
+	}
+	if err := c.listClient.Rename(oldPath, newPath, false); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", oldPath, newPath, err)
+	}
+	return nil
+}
+
+// PutFile 上传并覆盖远程文件 (PUT)
+func (c *Client) PutFile(filePath string, data []byte) error {
+	return c.listClient.Write(filePath, data, 0644)
+}
+
+// Remove 删除远程文件 (DELETE)
+func (c *Client) Remove(filePath string) error {
+	return c.listClient.Remove(filePath)
+}
+
 func (c *Client) ListMP3Files() ([]FileInfo, error) {
 	files, err := c.listClient.ReadDir(c.rootPath)
 	if err != nil {
@@ -248,6 +285,10 @@ func (c *Client) walkDir(dirPath string, extensions []string) ([]FileInfo, error
 	for _, file := range files {
 		fullPath := path.Join(dirPath, file.Name())
 		if file.IsDir() {
+			// 跳过隐藏目录 (包括重复文件隔离目录)
+			if strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
 			subFiles, err := c.walkDir(fullPath, extensions)
 			if err != nil {
 				continue